@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/metrics"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/storage"
+)
+
+// daemon runs the pipeline every interval and serves /metrics until interrupted,
+// days since the last fetched one are fetched by every run.
+func daemon(ctx context.Context, db storage.Storage) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mcfg := metrics.ParseMetricsConfig()
+	served := make(chan error, 1)
+	go func() {
+		served <- metrics.Serve(ctx, mcfg.Addr)
+	}()
+
+	t := time.NewTicker(run.ParseRunConfig().Interval)
+	defer t.Stop()
+
+	for {
+		if err := once(ctx, db, false); err != nil {
+			slog.ErrorContext(ctx, "run failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return <-served
+		case err := <-served:
+			return err
+		case <-t.C:
+		}
+	}
+}
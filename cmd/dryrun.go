@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+)
+
+// printTransactions prints transactions which would be inserted.
+func printTransactions(w io.Writer, tr insider.Transactions) error {
+	fmt.Fprintf(w, "--- %d transactions would be inserted ---\n", len(tr))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TICKER\tOWNER\tRELATIONSHIP\tDATE\tTYPE\tCOST\tSHARES\tVALUE\tTOTAL\tNOTIFIED\tFLAGS")
+	for _, t := range tr {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.2f\t%d\t%d\t%d\t%s\t%s\n",
+			t.Ticker, t.Owner, t.Relationship, t.TransactionDate.Format(time.DateOnly), t.Transaction,
+			t.Cost, t.Shares, t.Value, t.SharesTotal, t.NotificationDate.Format("2006-01-02 15:04"),
+			strings.Join(t.Flags, "; "))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
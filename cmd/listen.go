@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/RyabovNick/finviz_parser/internal/storage"
+)
+
+// listen prints ingest notifications as JSON lines until interrupted.
+func listen(ctx context.Context, db storage.Storage) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ch, err := db.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for in := range ch {
+		if err := enc.Encode(in); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,50 +2,332 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/edgar"
+	"github.com/RyabovNick/finviz_parser/internal/event"
+	"github.com/RyabovNick/finviz_parser/internal/ingest"
 	"github.com/RyabovNick/finviz_parser/internal/insider"
-	"github.com/RyabovNick/finviz_parser/internal/store"
+	"github.com/RyabovNick/finviz_parser/internal/logging"
+	"github.com/RyabovNick/finviz_parser/internal/metrics"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/quote"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+	"github.com/RyabovNick/finviz_parser/internal/storage"
 	"github.com/RyabovNick/finviz_parser/internal/telegram"
+	"github.com/RyabovNick/finviz_parser/internal/tracing"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func main() {
+	if err := execute(); err != nil {
+		slog.Error("failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func execute() error {
 	err := godotenv.Load(".env")
-	if err != nil {
-		panic(err)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
 	}
 
+	// the standard logger writes to the same handler
+	slog.SetDefault(logging.New(logging.ParseLoggingConfig(), os.Stderr))
+
+	dryRun := flag.Bool("dry-run", false, "print transactions and messages instead of saving and sending them")
+	flag.Parse()
+	args := flag.Args()
+
 	ctx := context.Background()
 
-	db, err := store.New(ctx, store.Options{
-		Host:     "localhost:5432",
-		Database: "finviz",
-		Username: "finviz",
-		Password: "finviz",
-		MaxPool:  10,
-		MinPool:  2,
-	})
+	// responses of all HTTP clients are counted
+	http.DefaultTransport = metrics.NewTransport(http.DefaultTransport)
+
+	shutdown, err := tracing.Setup(ctx, tracing.ParseTracingConfig(), os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			slog.Error("flush spans", "err", err)
+		}
+	}()
+
+	cfg := storage.ParseStorageConfig()
+	// the dry run keeps everything in memory, so reports are built
+	// only from the scraped transactions
+	if *dryRun {
+		cfg = storage.Config{DSN: "memory://"}
+	}
+
+	db, err := storage.Open(ctx, cfg.DSN)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer db.Close()
 
-	telegram, err := telegram.New(telegram.ParseTelegramConfig(), db)
+	if len(args) > 0 && args[0] == "migrate" {
+		return migrate(ctx, db, args[1:])
+	}
+
+	if cfg.AutoMigrate {
+		if err := migrate(ctx, db, []string{"up"}); err != nil {
+			return err
+		}
+	}
+
+	if len(args) > 0 && args[0] == "status" {
+		return status(ctx, db, args[1:])
+	}
+
+	if len(args) > 0 && args[0] == "symbols" {
+		return symbols(ctx, db, args[1:])
+	}
+
+	if len(args) > 0 && args[0] == "listen" {
+		return listen(ctx, db)
+	}
+
+	metrics.WatchNewest(db)
+
+	if len(args) > 0 && args[0] == "daemon" {
+		return daemon(ctx, db)
+	}
+
+	err = once(ctx, db, *dryRun)
+	if mcfg := metrics.ParseMetricsConfig(); mcfg.Pushgateway != "" && !*dryRun {
+		if err := metrics.Push(ctx, mcfg); err != nil {
+			slog.ErrorContext(ctx, "push metrics", "err", err)
+		}
+	}
+
+	return err
+}
+
+// once runs the pipeline for every day since the last fetched one,
+// the failed day doesn't stop the later ones.
+func once(ctx context.Context, db storage.Storage, dryRun bool) error {
+	days, err := run.Days(ctx, db, time.Now(), run.ParseRunConfig().Backfill)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, day := range days {
+		errs = append(errs, runDay(ctx, db, day, dryRun))
+	}
+
+	return errors.Join(errs...)
+}
+
+// runDay records the run of the day and runs the pipeline.
+func runDay(ctx context.Context, db storage.Storage, day time.Time, dryRun bool) error {
+	l, err := run.Start(ctx, db, day)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	b := insider.New(db)
-	txs, err := b.LastDayTransaction()
+	// every line of the run has its ID
+	ctx = logging.WithRunID(ctx, l.Run().ID)
+	slog.InfoContext(ctx, "run started", "day", l.Run().Day.Format(time.DateOnly))
+
+	// spans of all stages are children of the run
+	ctx, span := tracing.Start(ctx, "run",
+		attribute.Int64("run.id", l.Run().ID),
+		attribute.String("run.day", l.Run().Day.Format(time.DateOnly)))
+	err = pipeline(ctx, db, l, dryRun)
+	tracing.End(span, err)
+	if err := l.Finish(ctx, err); err != nil {
+		slog.ErrorContext(ctx, "finish run", "err", err)
+	}
+
+	if err != nil {
+		// the run ID ties the error to the lines of the run
+		return fmt.Errorf("run %d: %w", l.Run().ID, err)
+	}
+	slog.InfoContext(ctx, "run finished")
+
+	return nil
+}
+
+// pipeline fetches transactions of the day of the run, saves them
+// and publishes pending digests. The dry run prints transactions
+// and messages, prices and events are skipped.
+func pipeline(ctx context.Context, db storage.Storage, l *run.Ledger, dryRun bool) error {
+	day := l.Run().Day
+
+	sources, err := registry(insider.ParseInsiderConfig())
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	if err := b.Save(ctx, txs); err != nil {
-		panic(err)
+	bot, err := connect(db, dryRun)
+	if err != nil {
+		return err
 	}
 
-	if err := telegram.Publish(ctx); err != nil {
-		panic(err)
+	priceCfg := price.ParsePriceConfig()
+	prices := priceCfg.Enabled() && !dryRun
+
+	publisher := publish.New(publish.ParsePublishConfig(), db)
+	publisher.Register(run.SinkDigest, bot.Publish)
+	digests := []publish.Digest{publish.NewDigest(day, run.SinkDigest)}
+	if prices {
+		publisher.Register(run.SinkPerformance, bot.PublishPerformance)
+		digests = append(digests, publish.NewDigest(day, run.SinkPerformance))
 	}
+	runner := ingest.New(sources, db, publisher)
+
+	fetched, err := l.Fetched(ctx)
+	if err != nil {
+		return err
+	}
+
+	// digests left after the failed publishing are still delivered
+	if fetched {
+		slog.InfoContext(ctx, "day has already been fetched, skip", "day", day.Format(time.DateOnly))
+		if err := errors.Join(runner.Publish(ctx, l), relay(ctx, db, l)); err != nil {
+			return err
+		}
+		return l.Skip(ctx)
+	}
+
+	txs, err := runner.Ingest(ctx, l, insider.DayWindow(day), digests...)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		if err := printTransactions(os.Stdout, txs); err != nil {
+			return err
+		}
+	}
+
+	alerts := alert.New(db, bot)
+	if cfg := alert.ParseAlertConfig(); cfg.RulesFile != "" {
+		rules, err := alert.LoadRules(cfg.RulesFile)
+		if err != nil {
+			return err
+		}
+
+		if err := alerts.Sync(ctx, rules); err != nil {
+			return err
+		}
+	}
+
+	err = alerts.Evaluate(ctx, txs)
+	if lerr := l.Published(ctx, run.SinkAlerts, err); lerr != nil {
+		return lerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", run.SinkAlerts, err)
+	}
+
+	if cfg := edgar.ParseEdgarConfig(); cfg.Enabled() {
+		if err := edgar.NewEnricher(cfg, db).Enrich(ctx); err != nil {
+			return err
+		}
+	} else {
+		slog.InfoContext(ctx, "EDGAR_USER_AGENT is not set, skip Form 4 enrichment")
+	}
+
+	if cfg := company.ParseCompanyConfig(); cfg.Enabled {
+		n, err := company.NewUpdater(cfg, quote.New(), db).Update(ctx)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "saved companies", "companies", n)
+	}
+
+	if prices {
+		provider, err := price.NewProvider(priceCfg)
+		if err != nil {
+			return err
+		}
+
+		if err := price.NewUpdater(priceCfg, provider, db).Update(ctx); err != nil {
+			return err
+		}
+
+		if err := score.New(score.ParseScoreConfig(priceCfg.Benchmark), db).Update(ctx); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		return runner.Publish(ctx, l)
+	}
+
+	return errors.Join(runner.Publish(ctx, l), relay(ctx, db, l))
+}
+
+// registry returns sources of transactions in the configured order,
+// EDGAR requires EDGAR_USER_AGENT.
+func registry(cfg insider.Config) (*ingest.Registry, error) {
+	r := ingest.NewRegistry()
+	for _, name := range cfg.Sources {
+		switch name {
+		case insider.SourceFinviz:
+			r.Register(name, insider.New())
+		case insider.SourceEDGAR:
+			ecfg := edgar.ParseEdgarConfig()
+			if !ecfg.Enabled() {
+				return nil, fmt.Errorf("edgar source requires EDGAR_USER_AGENT")
+			}
+			r.Register(name, edgar.NewSource(ecfg))
+		default:
+			return nil, fmt.Errorf("unknown insider source %q", name)
+		}
+	}
+
+	if r.Len() == 0 {
+		return nil, fmt.Errorf("no insider sources")
+	}
+
+	return r, nil
+}
+
+// connect returns the bot, the dry run prints messages to stdout.
+func connect(db storage.Storage, dryRun bool) (*telegram.Connection, error) {
+	if dryRun {
+		return telegram.NewDryRun(telegram.ParseDryRunConfig(), db, os.Stdout)
+	}
+
+	return telegram.New(telegram.ParseTelegramConfig(), db)
+}
+
+// relay publishes pending outbox events if the broker is set.
+func relay(ctx context.Context, db storage.Storage, l *run.Ledger) error {
+	cfg := event.ParseEventConfig()
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	broker, err := event.NewBroker(cfg)
+	if err != nil {
+		return err
+	}
+	defer broker.Close()
+
+	n, err := event.NewRelay(cfg, db, broker).Relay(ctx)
+	slog.InfoContext(ctx, "relayed events", "events", n, "broker", cfg.Broker)
+
+	if lerr := l.Published(ctx, run.SinkEvents, err); lerr != nil {
+		return lerr
+	}
+
+	return err
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/RyabovNick/finviz_parser/internal/storage"
+)
+
+const migrateUsage = "usage: finviz migrate up | down [N] | force VERSION | status | version"
+
+// migrate runs the migrate subcommand with the embedded migrations.
+func migrate(ctx context.Context, db storage.Storage, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	m, err := db.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("steps: %w", err)
+			}
+		}
+		return m.Down(ctx, steps)
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		v, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("version: %w", err)
+		}
+		return m.Force(ctx, uint(v))
+	case "status":
+		st, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range st {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%d_%s\t%s\n", s.Version, s.Name, state)
+		}
+		return nil
+	case "version":
+		v, dirty, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", v)
+			return nil
+		}
+		fmt.Println(v)
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/storage"
+)
+
+// status prints the latest runs, the number is set by the first argument.
+func status(ctx context.Context, db storage.Storage, args []string) error {
+	limit := 10
+	if len(args) > 0 {
+		var err error
+		limit, err = strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("limit: %w", err)
+		}
+	}
+
+	runs, err := db.Runs(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tDAY\tSTATUS\tSOURCES\tSCRAPED\tKEPT\tINSERTED\tDUPLICATES\tPUBLISH\tERROR")
+	for _, r := range runs {
+		sources := make([]string, 0, len(r.Sources))
+		for name, h := range r.Sources {
+			sources = append(sources, fmt.Sprintf("%s=%s(%d)", name, h.Status, h.Rows))
+		}
+		sort.Strings(sources)
+
+		sinks := make([]string, 0, len(r.Publish))
+		for sink, st := range r.Publish {
+			sinks = append(sinks, fmt.Sprintf("%s=%s", sink, st))
+		}
+		sort.Strings(sinks)
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			r.ID, r.StartedAt.Format(time.DateTime), r.Duration().Round(time.Second),
+			r.Day.Format(time.DateOnly), r.Status, strings.Join(sources, ","),
+			r.Scraped, r.Kept, r.Inserted, r.Duplicates, strings.Join(sinks, ","), r.Error)
+	}
+
+	return w.Flush()
+}
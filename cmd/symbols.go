@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/storage"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+// symbols prints symbols of the ticker: symbols TICKER,
+// or maps the symbol of the source to the ticker: symbols add SOURCE SYMBOL TICKER.
+func symbols(ctx context.Context, db storage.Storage, args []string) error {
+	if len(args) == 4 && args[0] == "add" {
+		source, err := symbol.ParseSource(args[1])
+		if err != nil {
+			return err
+		}
+
+		return db.SaveSymbols(ctx, []symbol.Symbol{{
+			Source:    source,
+			Symbol:    args[2],
+			Ticker:    symbol.Normalize(args[3]),
+			UpdatedAt: time.Now(),
+		}})
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("usage: symbols TICKER | symbols add SOURCE SYMBOL TICKER")
+	}
+
+	ticker := symbol.Normalize(args[0])
+	m := symbol.NewMapper(db)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tSYMBOL")
+	for _, source := range symbol.Sources {
+		s, err := m.Symbol(ctx, ticker, source)
+		if err != nil {
+			return err
+		}
+		if s == "" {
+			s = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", source, s)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,234 @@
+// Package alert evaluates user defined rules against
+// newly saved transactions and notifies when they fire.
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+)
+
+// Rule is the named expression, see Expr for the syntax.
+type Rule struct {
+	Name      string    `json:"name" db:"name"`
+	Expr      string    `json:"expr" db:"expr"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Firing is the history record of the fired rule.
+type Firing struct {
+	RuleName      string    `json:"rule_name" db:"rule_name"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	FiredAt       time.Time `json:"fired_at" db:"fired_at"`
+	// DeliveredAt is nil until the notification is sent.
+	DeliveredAt *time.Time `json:"delivered_at" db:"delivered_at"`
+}
+
+type Storer interface {
+	Rules(ctx context.Context) ([]Rule, error)
+	SaveRule(ctx context.Context, r Rule) error
+	// Fire records the firing, it returns false if the rule
+	// has already fired for the transaction.
+	Fire(ctx context.Context, rule, transactionID string) (bool, error)
+	// Deliver marks the firing delivered.
+	Deliver(ctx context.Context, rule, transactionID string) error
+	// Undelivered returns transactions of firings of the rule
+	// which notifications aren't sent, the oldest firing first.
+	Undelivered(ctx context.Context, rule string) (insider.Transactions, error)
+}
+
+type Notifier interface {
+	Alert(ctx context.Context, rule Rule, t insider.Transaction) error
+}
+
+type Engine struct {
+	store    Storer
+	notifier Notifier
+}
+
+func New(store Storer, notifier Notifier) *Engine {
+	return &Engine{
+		store:    store,
+		notifier: notifier,
+	}
+}
+
+// LoadRules reads rules from the JSON file:
+//
+//	[{"name": "CEO buy over $1M", "expr": "transaction == \"Buy\" && relationship ~ \"CEO\" && value > 1000000"}]
+//
+// rules are enabled unless "enabled": false is set.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	var raw []struct {
+		Name    string `json:"name"`
+		Expr    string `json:"expr"`
+		Enabled *bool  `json:"enabled"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(raw))
+	for _, r := range raw {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule without name: %s", r.Expr)
+		}
+
+		if _, err := Compile(r.Expr); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+
+		rules = append(rules, Rule{
+			Name:    r.Name,
+			Expr:    r.Expr,
+			Enabled: r.Enabled == nil || *r.Enabled,
+		})
+	}
+
+	return rules, nil
+}
+
+// Sync saves rules from the config file to the store, stored rules
+// missing in the file are disabled, their firing history is kept.
+func (e *Engine) Sync(ctx context.Context, rules []Rule) error {
+	stored, err := e.store.Rules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed get rules: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		names[r.Name] = struct{}{}
+		if err := e.store.SaveRule(ctx, r); err != nil {
+			return fmt.Errorf("failed save rule %q: %w", r.Name, err)
+		}
+	}
+
+	for _, r := range stored {
+		if _, ok := names[r.Name]; ok || !r.Enabled {
+			continue
+		}
+
+		r.Enabled = false
+		if err := e.store.SaveRule(ctx, r); err != nil {
+			return fmt.Errorf("failed disable rule %q: %w", r.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate checks all enabled rules against the saved transactions
+// and notifies about the matched ones. A rule fires only once
+// for the transaction: firing is recorded before the notification,
+// so concurrent runs don't notify twice, and stays pending until
+// the notification is sent. Pending firings of earlier runs are retried
+// first, transactions of failed notifications aren't lost.
+func (e *Engine) Evaluate(ctx context.Context, tr insider.Transactions) error {
+	rules, err := e.store.Rules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed get rules: %w", err)
+	}
+
+	var errs []error
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+
+		expr, err := Compile(r.Expr)
+		if err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+
+		pending, err := e.store.Undelivered(ctx, r.Name)
+		if err != nil {
+			return fmt.Errorf("failed get undelivered firings of rule %q: %w", r.Name, err)
+		}
+
+		for _, t := range pending {
+			if err := e.notify(ctx, r, t); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		for _, t := range tr {
+			ok, err := expr.Match(Fields(t))
+			if err != nil {
+				return fmt.Errorf("rule %q: %w", r.Name, err)
+			}
+
+			if !ok {
+				continue
+			}
+
+			fired, err := e.store.Fire(ctx, r.Name, t.ID)
+			if err != nil {
+				return fmt.Errorf("failed fire rule %q: %w", r.Name, err)
+			}
+
+			if !fired {
+				continue
+			}
+
+			if err := e.notify(ctx, r, t); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// notify sends the alert of the fired rule and marks the firing delivered,
+// the failed firing stays pending.
+func (e *Engine) notify(ctx context.Context, r Rule, t insider.Transaction) error {
+	if err := e.notifier.Alert(ctx, r, t); err != nil {
+		return fmt.Errorf("failed notify rule %q: %w", r.Name, err)
+	}
+
+	if err := e.store.Deliver(ctx, r.Name, t.ID); err != nil {
+		return fmt.Errorf("failed deliver rule %q: %w", r.Name, err)
+	}
+
+	return nil
+}
+
+// Fields returns transaction fields available in expressions.
+func Fields(t insider.Transaction) map[string]any {
+	holdingsChange := 0.0
+	if t.HoldingsChange != nil {
+		holdingsChange = *t.HoldingsChange
+	}
+
+	valueToMedian := 0.0
+	if t.ValueToMedian != nil {
+		valueToMedian = *t.ValueToMedian
+	}
+
+	return map[string]any{
+		"ticker":          t.Ticker,
+		"owner":           t.Owner,
+		"relationship":    t.Relationship,
+		"transaction":     string(t.Transaction),
+		"cost":            t.Cost,
+		"shares":          float64(t.Shares),
+		"value":           float64(t.Value),
+		"shares_total":    float64(t.SharesTotal),
+		"holdings_change": holdingsChange,
+		"value_to_median": valueToMedian,
+		"first_buy":       t.FirstBuy,
+		"unusual":         len(t.Flags) > 0,
+	}
+}
@@ -0,0 +1,88 @@
+package alert_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type notifier struct {
+	err    error
+	alerts []string
+}
+
+func (n *notifier) Alert(_ context.Context, rule alert.Rule, t insider.Transaction) error {
+	if n.err != nil {
+		return n.err
+	}
+
+	n.alerts = append(n.alerts, rule.Name+"/"+t.Ticker)
+	return nil
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	tr, err := store.InsertTransactions(ctx, insider.Transactions{{
+		Ticker:      "AAA",
+		Owner:       "Smith John",
+		Transaction: insider.Sale,
+		Shares:      1000,
+		Value:       10000,
+		SEC: insider.SEC{
+			NotificationDate: time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC),
+			URL:              "https://www.sec.gov/Archives/edgar/data/1/1.txt",
+		},
+	}})
+	require.NoError(t, err)
+	require.Len(t, tr, 1)
+
+	n := &notifier{err: errors.New("chat not found")}
+	e := alert.New(store, n)
+	require.NoError(t, e.Sync(ctx, []alert.Rule{{Name: "sold out", Expr: "shares / shares_total > 0.5", Enabled: true}}))
+
+	require.ErrorIs(t, e.Evaluate(ctx, tr), n.err)
+	firings, err := store.Firings(ctx, "sold out", 10)
+	require.NoError(t, err)
+	require.Len(t, firings, 1)
+	assert.Nil(t, firings[0].DeliveredAt, "the firing is pending")
+
+	// the next run has no new transactions, the pending firing is retried
+	n.err = nil
+	require.NoError(t, e.Evaluate(ctx, nil))
+	require.NoError(t, e.Evaluate(ctx, tr))
+	assert.Equal(t, []string{"sold out/AAA"}, n.alerts, "notified once after the failure")
+
+	firings, err = store.Firings(ctx, "sold out", 10)
+	require.NoError(t, err)
+	require.Len(t, firings, 1)
+	assert.NotNil(t, firings[0].DeliveredAt)
+}
+
+func TestEngine_Sync(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	e := alert.New(store, &notifier{})
+
+	require.NoError(t, e.Sync(ctx, []alert.Rule{
+		{Name: "large", Expr: "value > 100", Enabled: true},
+		{Name: "ceo", Expr: `relationship ~ "CEO"`, Enabled: true},
+	}))
+	require.NoError(t, e.Sync(ctx, []alert.Rule{{Name: "ceo", Expr: `relationship ~ "CEO"`, Enabled: true}}))
+
+	rules, err := store.Rules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "ceo", rules[0].Name)
+	assert.True(t, rules[0].Enabled)
+	assert.Equal(t, "large", rules[1].Name)
+	assert.False(t, rules[1].Enabled, "the rule removed from the file is disabled")
+}
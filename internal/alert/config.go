@@ -0,0 +1,16 @@
+package alert
+
+import (
+	"os"
+)
+
+type Config struct {
+	// RulesFile is the JSON file with rules synced to the store on start.
+	RulesFile string
+}
+
+func ParseAlertConfig() Config {
+	return Config{
+		RulesFile: os.Getenv("ALERT_RULES_FILE"),
+	}
+}
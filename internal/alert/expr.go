@@ -0,0 +1,574 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expressions are evaluated against transaction fields:
+//
+//	transaction == "Buy" && relationship ~ "CEO" && value > 1000000
+//	ticker in ["AAPL", "MSFT"]
+//	transaction == "Sale" && shares / shares_total > 0.5
+//
+// Operators by precedence (lowest first):
+//
+//	|| or
+//	&& and
+//	!  not
+//	== != > >= < <= ~ (contains, case insensitive) in (list membership)
+//	+ -
+//	* /
+//	- (unary)
+//
+// Values are numbers, strings in double quotes, true/false and lists in [].
+// Division by zero gives +Inf or -Inf, 0 / 0 isn't equal to anything.
+
+// Expr is the compiled expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses the expression.
+func Compile(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q at %d", p.peek().text, p.peek().pos)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Match evaluates the expression, it must result in bool.
+func (e *Expr) Match(env map[string]any) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression result is %T, not bool", v)
+	}
+
+	return b, nil
+}
+
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokString
+	tokIdent
+	tokOp
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	rs := []rune(src)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			start := i
+			for i < len(rs) && (unicode.IsDigit(rs[i]) || rs[i] == '.' || rs[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: string(rs[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(rs) && (unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i]) || rs[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(rs[start:i]), pos: start})
+		case r == '"':
+			start := i
+			var sb strings.Builder
+			for i++; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				sb.WriteRune(rs[i])
+			}
+			if i == len(rs) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		default:
+			op := string(r)
+			if i+1 < len(rs) {
+				switch two := string(rs[i : i+2]); two {
+				case "&&", "||", "==", "!=", ">=", "<=":
+					op = two
+				}
+			}
+
+			switch op {
+			case "&&", "||", "==", "!=", ">=", "<=", ">", "<", "!", "~", "+", "-", "*", "/", "(", ")", "[", "]", ",":
+			default:
+				return nil, fmt.Errorf("unexpected %q at %d", op, i)
+			}
+
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(rs)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) done() bool {
+	return p.peek().kind == tokEOF
+}
+
+// accept consumes the operator or keyword if it's next.
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", false
+	}
+
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return fmt.Errorf("expected %q at %d", text, p.peek().pos)
+	}
+
+	return nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) not() (node, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return negation{n}, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept("==", "!=", ">=", "<=", ">", "<", "~", "in")
+	if !ok {
+		return left, nil
+	}
+
+	right, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	return comparison{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) sum() (node, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmetic{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) product() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmetic{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic{op: "-", left: literal{0.0}, right: n}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tokNumber:
+		p.pos++
+		f, err := strconv.ParseFloat(strings.ReplaceAll(t.text, "_", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("number %q at %d: %w", t.text, t.pos, err)
+		}
+		return literal{f}, nil
+	case tokString:
+		p.pos++
+		return literal{t.text}, nil
+	case tokIdent:
+		p.pos++
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		}
+		return field{t.text}, nil
+	}
+
+	if _, ok := p.accept("("); ok {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+
+	if _, ok := p.accept("["); ok {
+		var items list
+		for {
+			if _, ok := p.accept("]"); ok {
+				return items, nil
+			}
+			if len(items) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+
+			n, err := p.sum()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, n)
+		}
+	}
+
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+type node interface {
+	eval(env map[string]any) (any, error)
+}
+
+type literal struct {
+	v any
+}
+
+func (n literal) eval(map[string]any) (any, error) {
+	return n.v, nil
+}
+
+type field struct {
+	name string
+}
+
+func (n field) eval(env map[string]any) (any, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", n.name)
+	}
+
+	return v, nil
+}
+
+type list []node
+
+func (n list) eval(env map[string]any) (any, error) {
+	vs := make([]any, 0, len(n))
+	for _, item := range n {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+
+	return vs, nil
+}
+
+type negation struct {
+	n node
+}
+
+func (n negation) eval(env map[string]any) (any, error) {
+	v, err := n.n.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! expects bool, got %T", v)
+	}
+
+	return !b, nil
+}
+
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (n logical) eval(env map[string]any) (any, error) {
+	l, err := evalBool(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// short circuit
+	if n.op == "&&" && !l || n.op == "||" && l {
+		return l, nil
+	}
+
+	return evalBool(n.right, env)
+}
+
+func evalBool(n node, env map[string]any) (bool, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("&& and || expect bool, got %T", v)
+	}
+
+	return b, nil
+}
+
+type arithmetic struct {
+	op          string
+	left, right node
+}
+
+func (n arithmetic) eval(env map[string]any) (any, error) {
+	l, r, err := evalNumbers(n.left, n.right, env)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.op, err)
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	default:
+		// division by zero is infinite, the sale of all shares
+		// (shares_total is 0) is over any share of holdings
+		return l / r, nil
+	}
+}
+
+type comparison struct {
+	op          string
+	left, right node
+}
+
+func (n comparison) eval(env map[string]any) (any, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "~":
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if !lok || !rok {
+			return nil, fmt.Errorf("~ expects strings, got %T and %T", l, r)
+		}
+		return strings.Contains(strings.ToLower(ls), strings.ToLower(rs)), nil
+	case "in":
+		items, ok := r.([]any)
+		if !ok {
+			return nil, fmt.Errorf("in expects list, got %T", r)
+		}
+		for _, item := range items {
+			if equal(l, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s expects numbers, got %T and %T", n.op, l, r)
+	}
+
+	switch n.op {
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	case "<":
+		return lf < rf, nil
+	default:
+		return lf <= rf, nil
+	}
+}
+
+func evalNumbers(left, right node, env map[string]any) (float64, float64, error) {
+	l, err := left.eval(env)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r, err := right.eval(env)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return 0, 0, fmt.Errorf("expects numbers, got %T and %T", l, r)
+	}
+
+	return lf, rf, nil
+}
+
+// equal compares values, strings are compared case insensitive.
+func equal(l, r any) bool {
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return strings.EqualFold(ls, rs)
+	}
+
+	return l == r
+}
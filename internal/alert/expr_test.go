@@ -0,0 +1,135 @@
+package alert
+
+import (
+	"testing"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpr_Match(t *testing.T) {
+	ceoBuy := insider.Transaction{
+		Ticker:       "EXTX",
+		Owner:        "Doe John",
+		Relationship: "Chief Executive Officer, CEO",
+		Transaction:  insider.Buy,
+		Cost:         24.3,
+		Shares:       50000,
+		Value:        1215000,
+		SharesTotal:  150000,
+	}
+
+	bigSale := insider.Transaction{
+		Ticker:      "SMPL",
+		Transaction: insider.Sale,
+		Shares:      6000,
+		SharesTotal: 4000,
+		Value:       60000,
+	}
+
+	soldOut := insider.Transaction{
+		Ticker:      "SMPL",
+		Transaction: insider.Sale,
+		Shares:      6000,
+		SharesTotal: 0,
+		Value:       60000,
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		tr      insider.Transaction
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "CEO buy over $1M",
+			expr: `transaction == "Buy" && relationship ~ "ceo" && value > 1_000_000`,
+			tr:   ceoBuy,
+			want: true,
+		},
+		{
+			name: "watched tickers",
+			expr: `ticker in ["AAPL", "extx"]`,
+			tr:   ceoBuy,
+			want: true,
+		},
+		{
+			name: "sale over 50% of shares total",
+			expr: `transaction == "Sale" and shares / shares_total > 0.5`,
+			tr:   bigSale,
+			want: true,
+		},
+		{
+			name: "sale of all shares",
+			expr: `transaction == "Sale" and shares / shares_total > 0.5`,
+			tr:   soldOut,
+			want: true,
+		},
+		{
+			name: "zero by zero",
+			expr: `shares_total / shares_total == 0`,
+			tr:   soldOut,
+			want: false,
+		},
+		{
+			name: "not matched",
+			expr: `!(transaction == "Sale") || value * 2 >= 1000000000`,
+			tr:   bigSale,
+			want: false,
+		},
+		{
+			name: "precedence",
+			expr: `value - 10000 * 6 == 0 && -cost <= 0`,
+			tr:   bigSale,
+			want: true,
+		},
+		{
+			name:    "unknown field",
+			expr:    `price > 10`,
+			tr:      ceoBuy,
+			wantErr: true,
+		},
+		{
+			name:    "type mismatch",
+			expr:    `ticker > 10`,
+			tr:      ceoBuy,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Compile(tt.expr)
+			assert.NoError(t, err)
+
+			got, err := e.Match(Fields(tt.tr))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "unterminated string", expr: `ticker == "AAPL`},
+		{name: "unknown operator", expr: `value % 2`},
+		{name: "missing paren", expr: `(value > 1`},
+		{name: "trailing tokens", expr: `value > 1 value`},
+		{name: "empty", expr: ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			assert.Error(t, err)
+		})
+	}
+}
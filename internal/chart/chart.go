@@ -0,0 +1,255 @@
+// Package chart renders PNG charts of the digest in pure Go.
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"time"
+)
+
+const (
+	width  = 800
+	margin = 20
+	// scale of the font
+	scale = 2
+
+	barHeight = 20
+	barGap    = 6
+
+	plotHeight = 320
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	foreground = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	gridColor  = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	// BuyColor is the color of buys and positive values.
+	BuyColor = color.RGBA{0x2e, 0x9d, 0x4f, 0xff}
+	// SaleColor is the color of sales and negative values.
+	SaleColor = color.RGBA{0xd6, 0x3b, 0x3b, 0xff}
+)
+
+// ErrNoData is returned when there is nothing to draw.
+var ErrNoData = errors.New("no data")
+
+// Bar is the labeled value of the bar chart.
+type Bar struct {
+	Label string
+	Value float64
+}
+
+// Bars renders the horizontal bar chart from the zero axis,
+// positive bars are green and negative are red.
+func Bars(title string, bars []Bar) ([]byte, error) {
+	if len(bars) == 0 {
+		return nil, ErrNoData
+	}
+
+	labelWidth, valueWidth := 0, 0
+	lo, hi := 0.0, 0.0
+	for _, b := range bars {
+		labelWidth = max(labelWidth, textWidth(b.Label, scale))
+		valueWidth = max(valueWidth, textWidth(Compact(b.Value), scale))
+		lo, hi = math.Min(lo, b.Value), math.Max(hi, b.Value)
+	}
+
+	top := margin + textHeight(scale) + margin
+	height := top + len(bars)*(barHeight+barGap) + margin
+	img := canvas(width, height)
+	drawText(img, margin, margin, title, foreground, scale)
+
+	// the value is drawn after the positive bar and before the negative one
+	left := margin + labelWidth + margin + valueWidth + barGap
+	right := width - margin - valueWidth - barGap
+	x := axis(lo, hi, left, right)
+	zero := x(0)
+	fill(img, image.Rect(zero, top-barGap, zero+1, height-margin), foreground)
+
+	for i, b := range bars {
+		y := top + i*(barHeight+barGap)
+		ty := y + (barHeight-textHeight(scale))/2
+		drawText(img, margin+labelWidth-textWidth(b.Label, scale), ty, b.Label, foreground, scale)
+
+		v := x(b.Value)
+		value := Compact(b.Value)
+		if b.Value >= 0 {
+			fill(img, image.Rect(zero, y, v, y+barHeight), BuyColor)
+			drawText(img, v+barGap, ty, value, foreground, scale)
+		} else {
+			fill(img, image.Rect(v, y, zero, y+barHeight), SaleColor)
+			drawText(img, v-barGap-textWidth(value, scale), ty, value, foreground, scale)
+		}
+	}
+
+	return encode(img)
+}
+
+// Series is the line of the trend chart.
+type Series struct {
+	Label  string
+	Color  color.Color
+	Values []float64
+}
+
+// Trend renders lines of values by day, every series has the value of every day.
+func Trend(title string, days []time.Time, series ...Series) ([]byte, error) {
+	if len(days) < 2 || len(series) == 0 {
+		return nil, ErrNoData
+	}
+
+	hi := 0.0
+	for _, s := range series {
+		if len(s.Values) != len(days) {
+			return nil, fmt.Errorf("series %s has %d values of %d days", s.Label, len(s.Values), len(days))
+		}
+
+		for _, v := range s.Values {
+			hi = math.Max(hi, v)
+		}
+	}
+
+	if hi == 0 {
+		return nil, ErrNoData
+	}
+
+	lineHeight := textHeight(scale) + margin
+	top := margin + 2*lineHeight
+	height := top + plotHeight + lineHeight + margin
+	img := canvas(width, height)
+
+	drawText(img, margin, margin, title, foreground, scale)
+
+	// legend
+	lx := margin
+	for _, s := range series {
+		fill(img, image.Rect(lx, margin+lineHeight, lx+textHeight(scale), margin+lineHeight+textHeight(scale)), s.Color)
+		lx += textHeight(scale) + barGap
+		drawText(img, lx, margin+lineHeight, s.Label, foreground, scale)
+		lx += textWidth(s.Label, scale) + margin
+	}
+
+	label := Compact(hi)
+	left := margin + textWidth(label, scale) + barGap
+	right := width - margin
+	bottom := top + plotHeight
+
+	// the grid of the maximum, the middle and zero
+	for i := 0; i <= 2; i++ {
+		y := top + i*plotHeight/2
+		fill(img, image.Rect(left, y, right, y+1), gridColor)
+	}
+	drawText(img, margin, top-textHeight(scale)/2, label, foreground, scale)
+	drawText(img, left-barGap-textWidth("0", scale), bottom-textHeight(scale)/2, "0", foreground, scale)
+
+	x := func(i int) int {
+		return left + i*(right-left)/(len(days)-1)
+	}
+	y := axis(0, hi, bottom, top)
+
+	for _, s := range series {
+		for i := 1; i < len(days); i++ {
+			line(img, x(i-1), y(s.Values[i-1]), x(i), y(s.Values[i]), s.Color)
+		}
+	}
+
+	first, last := days[0].Format("01-02"), days[len(days)-1].Format("01-02")
+	drawText(img, left, bottom+margin, first, foreground, scale)
+	drawText(img, right-textWidth(last, scale), bottom+margin, last, foreground, scale)
+
+	return encode(img)
+}
+
+// Compact formats the value with K, M and B suffixes.
+func Compact(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e9:
+		return fmt.Sprintf("%.1fB", v/1e9)
+	case abs >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case abs >= 1e3:
+		return fmt.Sprintf("%.0fK", v/1e3)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
+
+// axis maps values from lo..hi to pixels from..to.
+func axis(lo, hi float64, from, to int) func(float64) int {
+	if hi == lo {
+		return func(float64) int { return from }
+	}
+
+	return func(v float64) int {
+		return from + int(math.Round((v-lo)/(hi-lo)*float64(to-from)))
+	}
+}
+
+func canvas(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	return img
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r.Canon(), image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// line draws the 2px line by Bresenham's algorithm.
+func line(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	e := dx + dy
+
+	for {
+		fill(img, image.Rect(x0, y0, x0+2, y0+2), c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
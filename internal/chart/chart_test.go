@@ -0,0 +1,107 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBars(t *testing.T) {
+	b, err := Bars("Net buy/sell, $", []Bar{
+		{Label: "AAPL", Value: 1_200_000},
+		{Label: "TSLA", Value: -50_000},
+		{Label: "NVDA", Value: -2_500_000},
+	})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, width, img.Bounds().Dx())
+
+	// the largest bar ends before the widest value
+	y := margin + textHeight(scale) + margin + barHeight/2
+	assert.Equal(t, BuyColor, img.At(width-margin-textWidth("-2.5M", scale)-barGap-1, y))
+	assert.Equal(t, background, img.At(width-margin-textWidth("-2.5M", scale)-barGap+1, y))
+
+	_, err = Bars("empty", nil)
+	assert.ErrorIs(t, err, ErrNoData)
+}
+
+func TestTrend(t *testing.T) {
+	days := []time.Time{
+		time.Date(2024, 6, 23, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name    string
+		series  []Series
+		wantErr bool
+	}{
+		{
+			name: "success",
+			series: []Series{
+				{Label: "Buy", Color: BuyColor, Values: []float64{100, 0, 300}},
+				{Label: "Sale", Color: SaleColor, Values: []float64{200, 50, 0}},
+			},
+		},
+		{
+			name:    "no values",
+			series:  []Series{{Label: "Buy", Color: BuyColor, Values: []float64{0, 0, 0}}},
+			wantErr: true,
+		},
+		{
+			name:    "missing days",
+			series:  []Series{{Label: "Buy", Color: BuyColor, Values: []float64{100}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Trend("Buy vs sale", days, tt.series...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			_, err = png.Decode(bytes.NewReader(b))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{v: 0, want: "0"},
+		{v: 999, want: "999"},
+		{v: -50_000, want: "-50K"},
+		{v: 1_250_000, want: "1.2M"},
+		{v: 3_400_000_000, want: "3.4B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Compact(tt.v))
+		})
+	}
+}
+
+func TestDrawText(t *testing.T) {
+	img := canvas(100, 20)
+	drawText(img, 0, 0, "a", foreground, 1)
+
+	// the top of 'A' is ".###."
+	assert.Equal(t, background, img.At(0, 0))
+	assert.Equal(t, foreground, img.At(1, 0))
+	assert.Equal(t, 11, textWidth("ab", 1))
+}
@@ -0,0 +1,210 @@
+// Package company keeps metadata of traded tickers: the name, sector,
+// industry, country and market cap from the finviz quote page.
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/quote"
+)
+
+// lookbackDays is the period of traded tickers which companies are kept fresh.
+const lookbackDays = 90
+
+type Company struct {
+	Ticker   string `json:"ticker" db:"ticker"`
+	Name     string `json:"name" db:"name"`
+	Sector   string `json:"sector" db:"sector"`
+	Industry string `json:"industry" db:"industry"`
+	Country  string `json:"country" db:"country"`
+	// MarketCap, SharesOutstanding and SharesFloat are nil
+	// if the quote page has no value.
+	MarketCap         *float64  `json:"market_cap" db:"market_cap"`
+	SharesOutstanding *float64  `json:"shares_outstanding" db:"shares_outstanding"`
+	SharesFloat       *float64  `json:"shares_float" db:"shares_float"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Significance is the net transaction of the ticker relative
+// to the size of the company.
+type Significance struct {
+	Ticker string `json:"ticker" db:"ticker"`
+	// TotalValue and TotalShares are buys minus sales.
+	TotalValue  float64 `json:"total_value" db:"total_value"`
+	TotalShares int64   `json:"total_shares" db:"total_shares"`
+	MarketCap   float64 `json:"market_cap" db:"market_cap"`
+	// ValueBps is the value in basis points of the market cap.
+	ValueBps float64 `json:"value_bps" db:"value_bps"`
+	// FloatShare is the share of the float, or of shares outstanding
+	// if the float is unknown, nil if both are unknown.
+	FloatShare *float64 `json:"float_share" db:"float_share"`
+}
+
+// SectorTotal is the total of the transaction type in the sector,
+// transactions of tickers without the company are not counted.
+type SectorTotal struct {
+	Sector string `json:"sector" db:"sector"`
+	insider.TransactionTypeCount
+}
+
+// Sector is buys and sales of the sector.
+type Sector struct {
+	Sector    string  `json:"sector"`
+	BuyCount  int     `json:"buy_count"`
+	BuyValue  float64 `json:"buy_value"`
+	SaleCount int     `json:"sale_count"`
+	SaleValue float64 `json:"sale_value"`
+}
+
+// Net is buys minus sales.
+func (s Sector) Net() float64 {
+	return s.BuyValue - s.SaleValue
+}
+
+// Sectors groups totals by sector, sectors with the largest
+// value of transactions go first.
+func Sectors(totals []SectorTotal) []Sector {
+	index := make(map[string]int)
+
+	var sectors []Sector
+	for _, t := range totals {
+		i, ok := index[t.Sector]
+		if !ok {
+			i = len(sectors)
+			index[t.Sector] = i
+			sectors = append(sectors, Sector{Sector: t.Sector})
+		}
+
+		switch t.Transaction {
+		case insider.Buy:
+			sectors[i].BuyCount += t.TransactionCount
+			sectors[i].BuyValue += t.TotalValue
+		case insider.Sale:
+			sectors[i].SaleCount += t.TransactionCount
+			sectors[i].SaleValue += t.TotalValue
+		}
+	}
+
+	sort.SliceStable(sectors, func(i, j int) bool {
+		return sectors[i].BuyValue+sectors[i].SaleValue > sectors[j].BuyValue+sectors[j].SaleValue
+	})
+
+	return sectors
+}
+
+// Browser returns the quote page of the ticker.
+type Browser interface {
+	Page(ticker string) (*quote.Page, error)
+}
+
+type Storer interface {
+	TradedTickers(ctx context.Context, since time.Time) (insider.Tickers, error)
+	Companies(ctx context.Context, tickers []string) ([]Company, error)
+	SaveCompany(ctx context.Context, c Company) error
+}
+
+type Updater struct {
+	cfg     Config
+	browser Browser
+	store   Storer
+	now     func() time.Time
+	sleep   func(time.Duration)
+}
+
+func NewUpdater(cfg Config, browser Browser, store Storer) *Updater {
+	return &Updater{
+		cfg:     cfg,
+		browser: browser,
+		store:   store,
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// Update scrapes companies of recently traded tickers which are not
+// stored yet or older than MaxAge. Tickers without the quote page are
+// skipped, it returns the number of saved companies.
+func (u *Updater) Update(ctx context.Context) (int, error) {
+	now := u.now().UTC()
+
+	tickers, err := u.store.TradedTickers(ctx, now.AddDate(0, 0, -lookbackDays))
+	if err != nil {
+		return 0, fmt.Errorf("failed get traded tickers: %w", err)
+	}
+
+	cached, err := u.store.Companies(ctx, tickers)
+	if err != nil {
+		return 0, fmt.Errorf("failed get companies: %w", err)
+	}
+
+	fresh := make(map[string]struct{}, len(cached))
+	for _, c := range cached {
+		if now.Sub(c.UpdatedAt) < u.cfg.MaxAge {
+			fresh[c.Ticker] = struct{}{}
+		}
+	}
+
+	saved, requested := 0, 0
+	for _, t := range tickers {
+		if _, ok := fresh[t]; ok {
+			continue
+		}
+
+		// finviz limits the rate of requests, failed ones count too
+		if requested > 0 {
+			u.sleep(u.cfg.Delay)
+		}
+		requested++
+
+		page, err := u.browser.Page(t)
+		if err != nil {
+			slog.WarnContext(ctx, "skip quote page", "ticker", t, "err", err)
+			continue
+		}
+
+		if err := u.store.SaveCompany(ctx, FromPage(ctx, page, now)); err != nil {
+			return saved, fmt.Errorf("failed save company: %w", err)
+		}
+		saved++
+	}
+
+	return saved, nil
+}
+
+// FromPage returns the company of the quote page.
+func FromPage(ctx context.Context, p *quote.Page, now time.Time) Company {
+	c := Company{
+		Ticker:    p.Ticker,
+		Name:      p.Company,
+		Sector:    p.Sector,
+		Industry:  p.Industry,
+		Country:   p.Country,
+		UpdatedAt: now,
+	}
+
+	c.MarketCap = optional(ctx, p, "Market Cap")
+	c.SharesOutstanding = optional(ctx, p, "Shs Outstand")
+	c.SharesFloat = optional(ctx, p, "Shs Float")
+
+	return c
+}
+
+// optional returns the snapshot value, nil if it's missing.
+func optional(ctx context.Context, p *quote.Page, key string) *float64 {
+	v, err := p.Float(key)
+	switch {
+	case errors.Is(err, quote.ErrNoValue):
+		return nil
+	case err != nil:
+		slog.WarnContext(ctx, "invalid quote value", "ticker", p.Ticker, "key", key, "err", err)
+		return nil
+	default:
+		return &v
+	}
+}
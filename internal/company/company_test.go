@@ -0,0 +1,147 @@
+package company_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/quote"
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// browser returns pages of the map and records requested tickers.
+type browser struct {
+	pages     map[string]*quote.Page
+	requested []string
+}
+
+func (b *browser) Page(ticker string) (*quote.Page, error) {
+	b.requested = append(b.requested, ticker)
+
+	p, ok := b.pages[ticker]
+	if !ok {
+		return nil, errors.New("snapshot not found")
+	}
+
+	return p, nil
+}
+
+func page(ticker, sector, marketCap string) *quote.Page {
+	return &quote.Page{
+		Ticker:   ticker,
+		Company:  ticker + " Inc.",
+		Sector:   sector,
+		Industry: "Software",
+		Country:  "USA",
+		Snapshot: map[string]string{"Market Cap": marketCap, "Shs Outstand": "10.00M", "Shs Float": "-"},
+	}
+}
+
+func TestUpdater_Update(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	store := memory.New()
+
+	var tr insider.Transactions
+	for _, ticker := range []string{"AAA", "BBB", "CCC", "DDD"} {
+		tr = append(tr, insider.Transaction{
+			Ticker:          ticker,
+			Owner:           "Smith John",
+			TransactionDate: now.AddDate(0, 0, -3),
+			Transaction:     insider.Buy,
+			Value:           1000,
+			SEC:             insider.SEC{NotificationDate: now.AddDate(0, 0, -1)},
+		})
+	}
+	_, err := store.InsertTransactions(ctx, tr)
+	require.NoError(t, err)
+
+	// AAA is fresh, BBB is stale
+	require.NoError(t, store.SaveCompany(ctx, company.Company{Ticker: "AAA", Sector: "Technology", UpdatedAt: now.Add(-time.Hour)}))
+	require.NoError(t, store.SaveCompany(ctx, company.Company{Ticker: "BBB", Sector: "Old", UpdatedAt: now.AddDate(0, 0, -31)}))
+
+	b := &browser{pages: map[string]*quote.Page{
+		"AAA": page("AAA", "Technology", "1.5B"),
+		"BBB": page("BBB", "Healthcare", "-"),
+		"CCC": page("CCC", "Energy", "200M"),
+	}}
+
+	u := company.NewUpdater(company.Config{MaxAge: 30 * 24 * time.Hour}, b, store)
+	n, err := u.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"BBB", "CCC", "DDD"}, b.requested, "fresh companies are not scraped")
+
+	c, err := store.Companies(ctx, []string{"AAA", "BBB", "CCC", "DDD"})
+	require.NoError(t, err)
+	require.Len(t, c, 3, "tickers without the page are skipped")
+
+	assert.Equal(t, "Healthcare", c[1].Sector, "stale company is refreshed")
+	assert.Nil(t, c[1].MarketCap)
+	assert.Equal(t, "CCC Inc.", c[2].Name)
+	require.NotNil(t, c[2].MarketCap)
+	assert.InDelta(t, 200e6, *c[2].MarketCap, 1e-6)
+	require.NotNil(t, c[2].SharesOutstanding)
+	assert.InDelta(t, 10e6, *c[2].SharesOutstanding, 1e-6)
+	assert.Nil(t, c[2].SharesFloat)
+}
+
+func TestUpdater_UpdateDelay(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	store := memory.New()
+
+	var tr insider.Transactions
+	for _, ticker := range []string{"AAA", "BBB", "CCC"} {
+		tr = append(tr, insider.Transaction{
+			Ticker:          ticker,
+			Owner:           "Smith John",
+			TransactionDate: now.AddDate(0, 0, -3),
+			Transaction:     insider.Buy,
+			Value:           1000,
+			SEC:             insider.SEC{NotificationDate: now.AddDate(0, 0, -1)},
+		})
+	}
+	_, err := store.InsertTransactions(ctx, tr)
+	require.NoError(t, err)
+
+	b := &browser{pages: map[string]*quote.Page{"CCC": page("CCC", "Energy", "200M")}}
+
+	delay := 50 * time.Millisecond
+	u := company.NewUpdater(company.Config{MaxAge: time.Hour, Delay: delay}, b, store)
+
+	start := time.Now()
+	n, err := u.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, b.requested, 3)
+	assert.GreaterOrEqual(t, time.Since(start), 2*delay, "failed requests are delayed too")
+}
+
+func TestSectors(t *testing.T) {
+	total := func(sector string, typ insider.TransactionType, count int, value float64) company.SectorTotal {
+		return company.SectorTotal{
+			Sector:               sector,
+			TransactionTypeCount: insider.TransactionTypeCount{Transaction: typ, TransactionCount: count, TotalValue: value},
+		}
+	}
+
+	got := company.Sectors([]company.SectorTotal{
+		total("Energy", insider.Sale, 1, 100),
+		total("Healthcare", insider.Buy, 2, 500),
+		total("Healthcare", insider.Sale, 1, 300),
+		total("Technology", insider.Buy, 1, 700),
+	})
+
+	assert.Equal(t, []company.Sector{
+		{Sector: "Healthcare", BuyCount: 2, BuyValue: 500, SaleCount: 1, SaleValue: 300},
+		{Sector: "Technology", BuyCount: 1, BuyValue: 700},
+		{Sector: "Energy", SaleCount: 1, SaleValue: 100},
+	}, got)
+	assert.InDelta(t, 200, got[0].Net(), 1e-9)
+}
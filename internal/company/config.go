@@ -0,0 +1,50 @@
+package company
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	// Enabled scrapes companies of traded tickers.
+	Enabled bool
+	// MaxAge is the age after which the company is scraped again.
+	MaxAge time.Duration
+	// Delay is the pause between quote pages.
+	Delay time.Duration
+}
+
+func ParseCompanyConfig() Config {
+	cfg := Config{
+		MaxAge: 30 * 24 * time.Hour,
+		Delay:  time.Second,
+	}
+
+	if v := os.Getenv("COMPANY_ENABLED"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatal("env: COMPANY_ENABLED cannot convert")
+		}
+		cfg.Enabled = enabled
+	}
+
+	if v := os.Getenv("COMPANY_MAX_AGE"); v != "" {
+		maxAge, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatal("env: COMPANY_MAX_AGE cannot convert")
+		}
+		cfg.MaxAge = maxAge
+	}
+
+	if v := os.Getenv("COMPANY_DELAY"); v != "" {
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatal("env: COMPANY_DELAY cannot convert")
+		}
+		cfg.Delay = delay
+	}
+
+	return cfg
+}
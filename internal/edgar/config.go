@@ -0,0 +1,22 @@
+package edgar
+
+import (
+	"os"
+)
+
+type Config struct {
+	// UserAgent is required by SEC: "Sample Company Name AdminContact@<sample company domain>.com"
+	UserAgent string
+}
+
+// ParseEdgarConfig returns config from the environment.
+// Enrichment is disabled when EDGAR_USER_AGENT is empty.
+func ParseEdgarConfig() Config {
+	return Config{
+		UserAgent: os.Getenv("EDGAR_USER_AGENT"),
+	}
+}
+
+func (c Config) Enabled() bool {
+	return c.UserAgent != ""
+}
@@ -0,0 +1,232 @@
+package edgar
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+// SEC allows up to 10 requests per second.
+const requestDelay = 100 * time.Millisecond
+
+type Storer interface {
+	UnenrichedTransactions(ctx context.Context) (insider.Transactions, error)
+	SaveFiling(ctx context.Context, transactionID string, f insider.Filing) error
+	SaveSymbols(ctx context.Context, s []symbol.Symbol) error
+}
+
+// Enricher follows SEC.URL of the saved transactions
+// and stores the details from the Form 4 filing.
+type Enricher struct {
+	store     Storer
+	client    *http.Client
+	userAgent string
+	delay     time.Duration
+}
+
+func NewEnricher(cfg Config, store Storer) *Enricher {
+	return &Enricher{
+		store:     store,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		userAgent: cfg.UserAgent,
+		delay:     requestDelay,
+	}
+}
+
+// Enrich fetches Form 4 for every not yet enriched transaction.
+// Filings that can't be fetched or parsed are skipped
+// and will be retried on the next run.
+func (e *Enricher) Enrich(ctx context.Context) error {
+	txs, err := e.store.UnenrichedTransactions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed get unenriched transactions: %w", err)
+	}
+
+	// one filing may contain several transactions
+	var urls []string
+	byURL := make(map[string]insider.Transactions)
+	for _, t := range txs {
+		if _, ok := byURL[t.URL]; !ok {
+			urls = append(urls, t.URL)
+		}
+		byURL[t.URL] = append(byURL[t.URL], t)
+	}
+
+	for i, u := range urls {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(e.delay):
+			}
+		}
+
+		form, err := e.Form4(ctx, u)
+		if err != nil {
+			slog.WarnContext(ctx, "skip form4 enrichment", "url", u, "err", err)
+			continue
+		}
+
+		for _, t := range byURL[u] {
+			filing, ok := form.Filing(t)
+			if !ok {
+				slog.WarnContext(ctx, "skip form4 enrichment without the row of the transaction",
+					"url", u, "transaction", t.ID)
+				continue
+			}
+
+			if err := e.store.SaveFiling(ctx, t.ID, filing); err != nil {
+				return fmt.Errorf("failed save filing: %w", err)
+			}
+		}
+
+		// the issuer's CIK is mapped to the ticker of the transaction,
+		// the filing's trading symbol may be in another format
+		t := byURL[u][0]
+		if filing, _ := form.Filing(t); filing.IssuerCIK != "" {
+			s := symbol.Symbol{
+				Source:    symbol.EDGAR,
+				Symbol:    filing.IssuerCIK,
+				Ticker:    t.Ticker,
+				UpdatedAt: time.Now(),
+			}
+			if err := e.store.SaveSymbols(ctx, []symbol.Symbol{s}); err != nil {
+				return fmt.Errorf("failed save symbols: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Form4 downloads and parses Form 4 by the link from finviz.
+func (e *Enricher) Form4(ctx context.Context, filingURL string) (*Form4, error) {
+	raw, err := RawXMLURL(filingURL)
+	if err != nil {
+		return nil, fmt.Errorf("raw url: %w", err)
+	}
+
+	acc, err := AccessionNumber(filingURL)
+	if err != nil {
+		return nil, fmt.Errorf("accession number: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("User-Agent", e.userAgent)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	form, err := ParseForm4(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	form.AccessionNumber = acc
+
+	return form, nil
+}
+
+// Filing returns the filing details for the finviz transaction,
+// it returns false if no row of the filing matches the transaction.
+// Details of the rows are unknown then: the filing has only CIKs
+// and the checkbox, the transaction isn't enriched and is retried.
+func (f *Form4) Filing(t insider.Transaction) (insider.Filing, bool) {
+	filing := insider.Filing{
+		IssuerCIK: f.Issuer.CIK,
+		OwnerCIK:  f.owner(t.Owner).CIK,
+		Plan10b51: f.Aff10b5One,
+	}
+
+	if filing.IssuerCIK == "" {
+		// the filing is in the directory of the issuer
+		filing.IssuerCIK, _ = FilerCIK(t.URL)
+	}
+
+	row, ok := f.match(t)
+	if !ok {
+		return filing, false
+	}
+
+	filing.AccessionNumber = f.AccessionNumber
+	filing.TransactionCode = insider.TransactionCode(row.Code)
+	filing.DirectOwnership = &row.DirectOwnership
+	filing.Footnotes = f.FootnotesOf(row)
+
+	return filing, true
+}
+
+// owner finds the reporting owner of the transaction by the name,
+// finviz shortens names: "Smith John" is "SMITH JOHN A" in the filing.
+// It returns the empty owner if no name matches, the CIK of another
+// owner of the joint filing would merge histories of different insiders.
+func (f *Form4) owner(name string) Owner {
+	words := strings.Fields(strings.ToUpper(name))
+	for _, o := range f.Owners {
+		owner := strings.Fields(strings.ToUpper(o.Name))
+		if len(words) > 0 && !slices.ContainsFunc(words, func(w string) bool { return !slices.Contains(owner, w) }) {
+			return o
+		}
+	}
+
+	return Owner{}
+}
+
+// match finds the Form 4 row finviz transaction was built from.
+//
+// finviz may merge several rows of the filing into one, so if there is
+// no exact match by date and shares it falls back to the first row
+// with the same date and code, then with the same code. Rows of other
+// codes never match, their details would be saved to the wrong transaction.
+func (f *Form4) match(t insider.Transaction) (Form4Transaction, bool) {
+	code := string(transactionCode(t.Transaction))
+	sameDay := func(row Form4Transaction) bool {
+		return row.Date.Format(form4DateFormat) == t.TransactionDate.Format(form4DateFormat)
+	}
+
+	matchers := []func(row Form4Transaction) bool{
+		func(row Form4Transaction) bool {
+			return row.Code == code && sameDay(row) && int(math.Round(row.Shares)) == t.Shares
+		},
+		func(row Form4Transaction) bool { return row.Code == code && sameDay(row) },
+		func(row Form4Transaction) bool { return row.Code == code },
+	}
+
+	for _, m := range matchers {
+		for _, row := range f.Transactions {
+			if m(row) {
+				return row, true
+			}
+		}
+	}
+
+	return Form4Transaction{}, false
+}
+
+func transactionCode(t insider.TransactionType) insider.TransactionCode {
+	switch t {
+	case insider.Buy:
+		return insider.CodePurchase
+	case insider.Sale:
+		return insider.CodeSale
+	default:
+		return ""
+	}
+}
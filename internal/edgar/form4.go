@@ -0,0 +1,318 @@
+// Package edgar works with SEC EDGAR filings
+// that finviz links to from every insider transaction.
+package edgar
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+// Form 4 dates are in the ISO format: 2024-06-26
+const form4DateFormat = "2006-01-02"
+
+// acceptedFormat is the acceptance time of the submission header
+// in New York time: 20240626163512
+const acceptedFormat = "20060102150405"
+
+var acceptedRe = regexp.MustCompile(`<ACCEPTANCE-DATETIME>(\d{14})`)
+
+// Form4 is the parsed Form 4 ownership document.
+type Form4 struct {
+	AccessionNumber string
+	// Accepted is the time EDGAR accepted the submission, New York time
+	// like finviz shows it. It's zero for the raw XML without the header.
+	Accepted time.Time
+	Issuer   Issuer
+	// Owners are reporting owners, joint filings have several.
+	Owners []Owner
+	// Aff10b5One is the checkbox (since 2023) that indicates the transactions
+	// were made pursuant to a Rule 10b5-1 trading plan.
+	Aff10b5One   bool
+	Transactions []Form4Transaction
+	// Footnotes by their id (F1, F2, ...).
+	Footnotes map[string]string
+}
+
+// Issuer is the company, CIK is without leading zeros.
+type Issuer struct {
+	CIK    string
+	Name   string
+	Ticker string
+}
+
+// Owner is the reporting owner, CIK is without leading zeros.
+type Owner struct {
+	CIK  string
+	Name string
+	// Relationship is the officer title and roles like finviz shows them:
+	// "CEO, Director", "10% Owner".
+	Relationship string
+}
+
+// Form4Transaction is a row from the non-derivative or derivative table.
+type Form4Transaction struct {
+	Derivative       bool
+	SecurityTitle    string
+	Date             time.Time
+	Code             string
+	Shares           float64
+	Price            float64
+	AcquiredDisposed string
+	SharesOwnedAfter float64
+	DirectOwnership  bool
+	// FootnoteIDs are the ids of all footnotes referenced by the row.
+	FootnoteIDs []string
+}
+
+// FootnotesOf returns the texts of footnotes referenced by the transaction.
+func (f *Form4) FootnotesOf(t Form4Transaction) []string {
+	notes := make([]string, 0, len(t.FootnoteIDs))
+	for _, id := range t.FootnoteIDs {
+		if n, ok := f.Footnotes[id]; ok {
+			notes = append(notes, n)
+		}
+	}
+
+	return notes
+}
+
+type xmlValue struct {
+	Value string `xml:"value"`
+}
+
+type xmlTransaction struct {
+	SecurityTitle   xmlValue `xml:"securityTitle"`
+	TransactionDate xmlValue `xml:"transactionDate"`
+	Coding          struct {
+		Code string `xml:"transactionCode"`
+	} `xml:"transactionCoding"`
+	Amounts struct {
+		Shares           xmlValue `xml:"transactionShares"`
+		Price            xmlValue `xml:"transactionPricePerShare"`
+		AcquiredDisposed xmlValue `xml:"transactionAcquiredDisposedCode"`
+	} `xml:"transactionAmounts"`
+	PostAmounts struct {
+		SharesOwned xmlValue `xml:"sharesOwnedFollowingTransaction"`
+	} `xml:"postTransactionAmounts"`
+	OwnershipNature struct {
+		DirectOrIndirect xmlValue `xml:"directOrIndirectOwnership"`
+	} `xml:"ownershipNature"`
+	Inner string `xml:",innerxml"`
+}
+
+type xmlOwnershipDocument struct {
+	XMLName    xml.Name `xml:"ownershipDocument"`
+	Aff10b5One string   `xml:"aff10b5One"`
+	Issuer     struct {
+		CIK    string `xml:"issuerCik"`
+		Name   string `xml:"issuerName"`
+		Ticker string `xml:"issuerTradingSymbol"`
+	} `xml:"issuer"`
+	Owners []struct {
+		CIK          string          `xml:"reportingOwnerId>rptOwnerCik"`
+		Name         string          `xml:"reportingOwnerId>rptOwnerName"`
+		Relationship xmlRelationship `xml:"reportingOwnerRelationship"`
+	} `xml:"reportingOwner"`
+	NonDerivative []xmlTransaction `xml:"nonDerivativeTable>nonDerivativeTransaction"`
+	Derivative    []xmlTransaction `xml:"derivativeTable>derivativeTransaction"`
+	Footnotes     []struct {
+		ID   string `xml:"id,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"footnotes>footnote"`
+}
+
+type xmlRelationship struct {
+	IsDirector        string `xml:"isDirector"`
+	IsOfficer         string `xml:"isOfficer"`
+	IsTenPercentOwner string `xml:"isTenPercentOwner"`
+	IsOther           string `xml:"isOther"`
+	OfficerTitle      string `xml:"officerTitle"`
+	OtherText         string `xml:"otherText"`
+}
+
+func (r xmlRelationship) String() string {
+	var roles []string
+	if parseBool(r.IsOfficer) && strings.TrimSpace(r.OfficerTitle) != "" {
+		roles = append(roles, strings.TrimSpace(r.OfficerTitle))
+	}
+	if parseBool(r.IsDirector) {
+		roles = append(roles, "Director")
+	}
+	if parseBool(r.IsTenPercentOwner) {
+		roles = append(roles, "10% Owner")
+	}
+	if parseBool(r.IsOther) && strings.TrimSpace(r.OtherText) != "" {
+		roles = append(roles, strings.TrimSpace(r.OtherText))
+	}
+
+	return strings.Join(roles, ", ")
+}
+
+// ParseForm4 parses Form 4 XML document, the raw XML
+// or the full submission text file with the XML inside.
+func ParseForm4(r io.Reader) (*Form4, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	var accepted time.Time
+	if m := acceptedRe.FindSubmatch(b); m != nil {
+		accepted, err = time.Parse(acceptedFormat, string(m[1]))
+		if err != nil {
+			return nil, fmt.Errorf("acceptance time: %w", err)
+		}
+	}
+
+	// the submission has SGML headers around the document
+	if start := bytes.Index(b, []byte("<ownershipDocument")); start > 0 {
+		b = b[start:]
+		if end := bytes.Index(b, []byte("</ownershipDocument>")); end > 0 {
+			b = b[:end+len("</ownershipDocument>")]
+		}
+	}
+
+	var doc xmlOwnershipDocument
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	f := &Form4{
+		Accepted: accepted,
+		Issuer: Issuer{
+			CIK:    CIK(doc.Issuer.CIK),
+			Name:   strings.TrimSpace(doc.Issuer.Name),
+			Ticker: symbol.Normalize(doc.Issuer.Ticker),
+		},
+		Aff10b5One: parseBool(doc.Aff10b5One),
+		Footnotes:  make(map[string]string, len(doc.Footnotes)),
+	}
+
+	for _, o := range doc.Owners {
+		f.Owners = append(f.Owners, Owner{
+			CIK:          CIK(o.CIK),
+			Name:         strings.TrimSpace(o.Name),
+			Relationship: o.Relationship.String(),
+		})
+	}
+
+	for _, n := range doc.Footnotes {
+		f.Footnotes[n.ID] = strings.Join(strings.Fields(n.Text), " ")
+	}
+
+	for _, t := range doc.NonDerivative {
+		tx, err := t.transaction(false)
+		if err != nil {
+			return nil, fmt.Errorf("non-derivative transaction: %w", err)
+		}
+		f.Transactions = append(f.Transactions, tx)
+	}
+
+	for _, t := range doc.Derivative {
+		tx, err := t.transaction(true)
+		if err != nil {
+			return nil, fmt.Errorf("derivative transaction: %w", err)
+		}
+		f.Transactions = append(f.Transactions, tx)
+	}
+
+	return f, nil
+}
+
+func (t xmlTransaction) transaction(derivative bool) (Form4Transaction, error) {
+	date, err := time.Parse(form4DateFormat, strings.TrimSpace(t.TransactionDate.Value))
+	if err != nil {
+		return Form4Transaction{}, fmt.Errorf("date: %w", err)
+	}
+
+	shares, err := parseFloat(t.Amounts.Shares.Value)
+	if err != nil {
+		return Form4Transaction{}, fmt.Errorf("shares: %w", err)
+	}
+
+	price, err := parseFloat(t.Amounts.Price.Value)
+	if err != nil {
+		return Form4Transaction{}, fmt.Errorf("price: %w", err)
+	}
+
+	owned, err := parseFloat(t.PostAmounts.SharesOwned.Value)
+	if err != nil {
+		return Form4Transaction{}, fmt.Errorf("shares owned: %w", err)
+	}
+
+	ids, err := footnoteIDs(t.Inner)
+	if err != nil {
+		return Form4Transaction{}, fmt.Errorf("footnotes: %w", err)
+	}
+
+	return Form4Transaction{
+		Derivative:       derivative,
+		SecurityTitle:    strings.TrimSpace(t.SecurityTitle.Value),
+		Date:             date,
+		Code:             strings.TrimSpace(t.Coding.Code),
+		Shares:           shares,
+		Price:            price,
+		AcquiredDisposed: strings.TrimSpace(t.Amounts.AcquiredDisposed.Value),
+		SharesOwnedAfter: owned,
+		DirectOwnership:  strings.TrimSpace(t.OwnershipNature.DirectOrIndirect.Value) != "I",
+		FootnoteIDs:      ids,
+	}, nil
+}
+
+// footnoteIDs returns ids of all <footnoteId> elements,
+// they can be placed at any level of the transaction.
+func footnoteIDs(inner string) ([]string, error) {
+	var ids []string
+	seen := make(map[string]bool)
+
+	d := xml.NewDecoder(strings.NewReader(inner))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		el, ok := tok.(xml.StartElement)
+		if !ok || el.Name.Local != "footnoteId" {
+			continue
+		}
+
+		for _, a := range el.Attr {
+			if a.Name.Local == "id" && !seen[a.Value] {
+				seen[a.Value] = true
+				ids = append(ids, a.Value)
+			}
+		}
+	}
+}
+
+// parseFloat parses optional numeric value, price may be omitted
+// for gifts and awards.
+func parseFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseBool(s string) bool {
+	switch strings.TrimSpace(strings.ToLower(s)) {
+	case "1", "true":
+		return true
+	default:
+		return false
+	}
+}
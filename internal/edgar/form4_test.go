@@ -0,0 +1,246 @@
+package edgar
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseForm4(t *testing.T) {
+	tests := []struct {
+		name         string
+		fileName     string
+		ticker       string
+		issuerCIK    string
+		owners       []Owner
+		aff10b5One   bool
+		codes        []string
+		derivative   int
+		footnotesLen int
+	}{
+		{
+			name:         "sale under 10b5-1 plan with option exercise",
+			fileName:     "testdata/form4_sale_10b5_1.xml",
+			ticker:       "EXTX",
+			issuerCIK:    "1397047",
+			owners:       []Owner{{CIK: "1805833", Name: "Doe John", Relationship: "Chief Executive Officer"}},
+			aff10b5One:   true,
+			codes:        []string{"M", "S", "M"},
+			derivative:   1,
+			footnotesLen: 3,
+		},
+		{
+			name:         "indirect purchase and gift",
+			fileName:     "testdata/form4_purchase_indirect.xml",
+			ticker:       "SMPL",
+			issuerCIK:    "320193",
+			owners:       []Owner{{CIK: "1234567", Name: "Smith Alice", Relationship: "Director"}},
+			aff10b5One:   false,
+			codes:        []string{"P", "G"},
+			derivative:   0,
+			footnotesLen: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.fileName)
+			require.NoError(t, err)
+			defer f.Close()
+
+			form, err := ParseForm4(f)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.ticker, form.Issuer.Ticker)
+			assert.Equal(t, tt.issuerCIK, form.Issuer.CIK)
+			assert.Equal(t, tt.owners, form.Owners)
+			assert.Equal(t, tt.aff10b5One, form.Aff10b5One)
+			assert.Len(t, form.Footnotes, tt.footnotesLen)
+
+			var codes []string
+			var derivative int
+			for _, tx := range form.Transactions {
+				codes = append(codes, tx.Code)
+				if tx.Derivative {
+					derivative++
+				}
+			}
+			assert.Equal(t, tt.codes, codes)
+			assert.Equal(t, tt.derivative, derivative)
+		})
+	}
+}
+
+func TestForm4_Filing(t *testing.T) {
+	tests := []struct {
+		name      string
+		fileName  string
+		tx        insider.Transaction
+		want      insider.Filing
+		matched   bool
+		footnotes int
+	}{
+		{
+			name:     "sale matched by date and shares",
+			fileName: "testdata/form4_sale_10b5_1.xml",
+			tx: insider.Transaction{
+				Owner:           "Doe John",
+				TransactionDate: time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC),
+				Transaction:     insider.Sale,
+				Shares:          10000,
+			},
+			want: insider.Filing{
+				IssuerCIK:       "1397047",
+				OwnerCIK:        "1805833",
+				TransactionCode: insider.CodeSale,
+				Plan10b51:       true,
+				DirectOwnership: ptr(true),
+			},
+			matched:   true,
+			footnotes: 2,
+		},
+		{
+			name:     "merged purchase matched by code",
+			fileName: "testdata/form4_purchase_indirect.xml",
+			tx: insider.Transaction{
+				Owner:           "Smith Alice",
+				TransactionDate: time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC),
+				Transaction:     insider.Buy,
+				Shares:          7000,
+			},
+			want: insider.Filing{
+				IssuerCIK:       "320193",
+				OwnerCIK:        "1234567",
+				TransactionCode: insider.CodePurchase,
+				Plan10b51:       false,
+				DirectOwnership: ptr(false),
+			},
+			matched:   true,
+			footnotes: 1,
+		},
+		{
+			name:     "sale without the row of the code",
+			fileName: "testdata/form4_purchase_indirect.xml",
+			tx: insider.Transaction{
+				Owner:           "Smith Alice",
+				TransactionDate: time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC),
+				Transaction:     insider.Sale,
+				Shares:          7000,
+			},
+			want: insider.Filing{
+				IssuerCIK: "320193",
+				OwnerCIK:  "1234567",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.fileName)
+			require.NoError(t, err)
+			defer f.Close()
+
+			form, err := ParseForm4(f)
+			require.NoError(t, err)
+			form.AccessionNumber = "0001213900-24-056822"
+
+			got, ok := form.Filing(tt.tx)
+			assert.Equal(t, tt.matched, ok)
+			assert.Equal(t, tt.matched, got.AccessionNumber != "", "the accession is set only for the matched row")
+			assert.Equal(t, tt.want.IssuerCIK, got.IssuerCIK)
+			assert.Equal(t, tt.want.OwnerCIK, got.OwnerCIK)
+			assert.Equal(t, tt.want.TransactionCode, got.TransactionCode)
+			assert.Equal(t, tt.want.Plan10b51, got.Plan10b51)
+			assert.Equal(t, tt.want.DirectOwnership, got.DirectOwnership)
+			assert.Len(t, got.Footnotes, tt.footnotes)
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestAccessionNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		raw     string
+		cik     string
+		wantErr bool
+	}{
+		{
+			name: "finviz link",
+			url:  "http://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/xslF345X05/ownership.xml",
+			want: "0001213900-24-056822",
+			raw:  "https://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/ownership.xml",
+			cik:  "1397047",
+		},
+		{
+			name:    "not a filing",
+			url:     "https://finviz.com/insidertrading.ashx",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AccessionNumber(tt.url)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			raw, err := RawXMLURL(tt.url)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.raw, raw)
+
+			cik, err := FilerCIK(tt.url)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.cik, cik)
+		})
+	}
+}
+
+func TestForm4_FilingOwner(t *testing.T) {
+	form := &Form4{
+		Issuer: Issuer{Ticker: "EXTX"},
+		Owners: []Owner{
+			{CIK: "1000001", Name: "EXAMPLE CAPITAL FUND LP"},
+			{CIK: "1805833", Name: "DOE JOHN A"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		tx     insider.Transaction
+		owner  string
+		issuer string
+	}{
+		{
+			name:   "shortened name of the joint filing",
+			tx:     insider.Transaction{Owner: "Doe John", SEC: insider.SEC{URL: "http://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/xslF345X05/ownership.xml"}},
+			owner:  "1805833",
+			issuer: "1397047",
+		},
+		{
+			name: "unknown name has no owner",
+			tx:   insider.Transaction{Owner: "Smith Alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := form.Filing(tt.tx)
+			assert.Equal(t, tt.owner, got.OwnerCIK)
+			assert.Equal(t, tt.issuer, got.IssuerCIK, "issuer cik from the url")
+		})
+	}
+}
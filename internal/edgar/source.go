@@ -0,0 +1,393 @@
+package edgar
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/metrics"
+	"github.com/RyabovNick/finviz_parser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Form 4 filings of the day are listed in the daily form index:
+// https://www.sec.gov/Archives/edgar/daily-index/2024/QTR2/form.20240624.idx
+const defaultBaseURL = "https://www.sec.gov"
+
+// Source fetches transactions from Form 4 filings of the EDGAR daily index,
+// it isn't limited by the latest rows finviz shows.
+type Source struct {
+	client    *http.Client
+	userAgent string
+	delay     time.Duration
+	baseURL   string
+}
+
+func NewSource(cfg Config) *Source {
+	return &Source{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		userAgent: cfg.UserAgent,
+		delay:     requestDelay,
+		baseURL:   defaultBaseURL,
+	}
+}
+
+// IndexEntry is a row of the daily form index.
+type IndexEntry struct {
+	FormType  string
+	Company   string
+	CIK       string
+	DateFiled time.Time
+	// FileName is the path of the submission text file:
+	// edgar/data/1397047/0001213900-24-056822.txt
+	FileName string
+}
+
+// Accession returns the dashed accession number from the file name.
+func (e IndexEntry) Accession() string {
+	name := e.FileName[strings.LastIndex(e.FileName, "/")+1:]
+	return strings.TrimSuffix(name, ".txt")
+}
+
+// Fetch returns purchases and sales of Form 4 filings filed in the window.
+// Weekends and federal holidays have no index and no transactions,
+// filings that can't be fetched or parsed are skipped. If the index
+// of the day fails or isn't published yet, transactions of the rest days
+// are returned with the error, so the day is fetched again by the next run.
+func (s *Source) Fetch(ctx context.Context, w insider.Window) (insider.Transactions, error) {
+	var (
+		txs  insider.Transactions
+		errs []error
+	)
+	for _, day := range w.Days() {
+		tx, err := s.fetchDay(ctx, day)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", day.Format(time.DateOnly), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		txs = append(txs, tx...)
+	}
+
+	return txs, errors.Join(errs...)
+}
+
+// URLs returns daily indexes of the window.
+func (s *Source) URLs(w insider.Window) []string {
+	var urls []string
+	for _, day := range w.Days() {
+		urls = append(urls, s.IndexURL(day))
+	}
+
+	return urls
+}
+
+func (s *Source) fetchDay(ctx context.Context, day time.Time) (insider.Transactions, error) {
+	if !BusinessDay(day) {
+		return nil, nil
+	}
+
+	body, err := s.get(ctx, s.IndexURL(day))
+	if err != nil {
+		return nil, fmt.Errorf("failed get daily index: %w", err)
+	}
+	if body == nil {
+		return nil, fmt.Errorf("daily index isn't published yet")
+	}
+
+	entries, err := ParseIndex(body)
+	body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed parse daily index: %w", err)
+	}
+
+	var txs insider.Transactions
+	for i, e := range Form4Entries(entries) {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(s.delay):
+			}
+		}
+
+		fctx, span := tracing.Start(ctx, "edgar.form4", attribute.String("file", e.FileName))
+		form, err := s.form4(fctx, e)
+		tracing.End(span, err)
+		if err != nil {
+			slog.WarnContext(ctx, "reject form4", "source", insider.SourceEDGAR, "file", e.FileName, "err", err)
+			metrics.RowsRejected.WithLabelValues(insider.SourceEDGAR, "form4").Inc()
+			continue
+		}
+		metrics.RowsParsed.WithLabelValues(insider.SourceEDGAR).Add(float64(len(form.Transactions)))
+
+		issuer := form.Issuer.CIK
+		if issuer == "" {
+			issuer = e.CIK
+		}
+		url := fmt.Sprintf("%s/Archives/edgar/data/%s/%s/%s.txt",
+			s.baseURL, issuer, strings.ReplaceAll(e.Accession(), "-", ""), e.Accession())
+
+		notified := form.Accepted
+		if notified.IsZero() {
+			notified = e.DateFiled
+		}
+
+		txs = append(txs, form.InsiderTransactions(url, notified)...)
+	}
+
+	return txs, nil
+}
+
+// BusinessDay reports whether EDGAR publishes the daily index of the day:
+// it's closed on weekends and federal holidays.
+func BusinessDay(day time.Time) bool {
+	switch day.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+
+	y, m, d := day.Date()
+	for _, h := range federalHolidays(y) {
+		if h.Month() == m && h.Day() == d {
+			return false
+		}
+	}
+
+	return true
+}
+
+// federalHolidays returns observed federal holidays of the year: fixed
+// holidays on Saturday are observed on Friday, on Sunday on Monday.
+func federalHolidays(year int) []time.Time {
+	date := func(m time.Month, d int) time.Time {
+		return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	// nth weekday of the month, the last one if n is negative
+	nth := func(m time.Month, wd time.Weekday, n int) time.Time {
+		if n < 0 {
+			d := date(m+1, 1).AddDate(0, 0, -1)
+			return d.AddDate(0, 0, -int((d.Weekday()-wd+7)%7))
+		}
+		d := date(m, 1)
+		return d.AddDate(0, 0, int((wd-d.Weekday()+7)%7)+7*(n-1))
+	}
+	observed := func(d time.Time) time.Time {
+		switch d.Weekday() {
+		case time.Saturday:
+			return d.AddDate(0, 0, -1)
+		case time.Sunday:
+			return d.AddDate(0, 0, 1)
+		}
+		return d
+	}
+
+	return []time.Time{
+		observed(date(time.January, 1)),
+		nth(time.January, time.Monday, 3),
+		nth(time.February, time.Monday, 3),
+		nth(time.May, time.Monday, -1),
+		observed(date(time.June, 19)),
+		observed(date(time.July, 4)),
+		nth(time.September, time.Monday, 1),
+		nth(time.October, time.Monday, 2),
+		observed(date(time.November, 11)),
+		nth(time.November, time.Thursday, 4),
+		observed(date(time.December, 25)),
+	}
+}
+
+// IndexURL returns the link to the daily form index of the day.
+func (s *Source) IndexURL(day time.Time) string {
+	return fmt.Sprintf("%s/Archives/edgar/daily-index/%d/QTR%d/form.%s.idx",
+		s.baseURL, day.Year(), (int(day.Month())-1)/3+1, day.Format("20060102"))
+}
+
+func (s *Source) form4(ctx context.Context, e IndexEntry) (*Form4, error) {
+	body, err := s.get(ctx, s.baseURL+"/Archives/"+e.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("not found")
+	}
+	defer body.Close()
+
+	form, err := ParseForm4(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	form.AccessionNumber = e.Accession()
+
+	return form, nil
+}
+
+// get returns the body of the response, it's nil if the document isn't found.
+// EDGAR answers 403 to requests without the declared User-Agent and over
+// the rate limit, it's the error, so the source is recorded as failed.
+func (s *Source) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+// ParseIndex parses the daily form index, rows follow the dashed line
+// after the header. Company names contain spaces, so the form type
+// is the first field and the CIK, the date and the file name are the last.
+func ParseIndex(r io.Reader) ([]IndexEntry, error) {
+	var entries []IndexEntry
+
+	header := true
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if header {
+			header = !strings.HasPrefix(line, "---")
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		n := len(fields)
+		date, err := time.Parse("20060102", fields[n-2])
+		if err != nil {
+			return nil, fmt.Errorf("date filed: %w", err)
+		}
+
+		entries = append(entries, IndexEntry{
+			FormType:  fields[0],
+			Company:   strings.Join(fields[1:n-3], " "),
+			CIK:       fields[n-3],
+			DateFiled: date,
+			FileName:  fields[n-1],
+		})
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Form4Entries returns Form 4 filings once, the index lists the filing
+// under the issuer and under every reporting owner.
+func Form4Entries(entries []IndexEntry) []IndexEntry {
+	var form4 []IndexEntry
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if e.FormType != "4" || seen[e.Accession()] {
+			continue
+		}
+		seen[e.Accession()] = true
+		form4 = append(form4, e)
+	}
+
+	return form4
+}
+
+// InsiderTransactions returns open market purchases and sales of the filing
+// like finviz shows them: rows of the same code and date are merged,
+// the cost is the average price. Joint filings have the rows
+// of every reporting owner.
+func (f *Form4) InsiderTransactions(url string, notified time.Time) insider.Transactions {
+	owners := f.Owners
+	if len(owners) == 0 {
+		owners = []Owner{{}}
+	}
+
+	type merged struct {
+		code   string
+		date   time.Time
+		shares float64
+		value  float64
+		after  float64
+	}
+
+	var rows []*merged
+	for _, t := range f.Transactions {
+		if t.Derivative || (t.Code != string(insider.CodePurchase) && t.Code != string(insider.CodeSale)) {
+			continue
+		}
+
+		var m *merged
+		for _, r := range rows {
+			if r.code == t.Code && r.date.Equal(t.Date) {
+				m = r
+				break
+			}
+		}
+		if m == nil {
+			m = &merged{code: t.Code, date: t.Date}
+			rows = append(rows, m)
+		}
+
+		m.shares += t.Shares
+		m.value += t.Shares * t.Price
+		m.after = t.SharesOwnedAfter
+	}
+
+	txs := make(insider.Transactions, 0, len(rows)*len(owners))
+	for _, owner := range owners {
+		for _, r := range rows {
+			tx := insider.Transaction{
+				Ticker:          f.Issuer.Ticker,
+				Owner:           owner.Name,
+				Relationship:    owner.Relationship,
+				TransactionDate: r.date,
+				Transaction:     insider.Buy,
+				Shares:          int(math.Round(r.shares)),
+				Value:           int(math.Round(r.value)),
+				SharesTotal:     int(math.Round(r.after)),
+				SEC: insider.SEC{
+					NotificationDate: notified,
+					URL:              url,
+					IssuerCIK:        f.Issuer.CIK,
+					OwnerCIK:         owner.CIK,
+				},
+			}
+			if r.code == string(insider.CodeSale) {
+				tx.Transaction = insider.Sale
+			}
+			if r.shares > 0 {
+				tx.Cost = math.Round(r.value/r.shares*100) / 100
+			}
+
+			txs = append(txs, tx)
+		}
+	}
+
+	return txs
+}
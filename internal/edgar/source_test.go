@@ -0,0 +1,206 @@
+package edgar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSource serves testdata as EDGAR archives.
+func newTestSource(t *testing.T) *Source {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(srv.Close)
+
+	return &Source{
+		client:    srv.Client(),
+		userAgent: "test test@example.com",
+		baseURL:   srv.URL,
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	f, err := os.Open("testdata/Archives/edgar/daily-index/2024/QTR2/form.20240626.idx")
+	require.NoError(t, err)
+	defer f.Close()
+
+	entries, err := ParseIndex(f)
+	require.NoError(t, err)
+	require.Len(t, entries, 6)
+
+	assert.Equal(t, IndexEntry{
+		FormType:  "4",
+		Company:   "Example Therapeutics, Inc.",
+		CIK:       "1397047",
+		DateFiled: time.Date(2024, 6, 26, 0, 0, 0, 0, time.UTC),
+		FileName:  "edgar/data/1397047/0001213900-24-056822.txt",
+	}, entries[2])
+	assert.Equal(t, "0001213900-24-056822", entries[2].Accession())
+
+	form4 := Form4Entries(entries)
+	require.Len(t, form4, 3, "Form 3, 4/A and the duplicate of the owner are skipped")
+	assert.Equal(t, "1805833", form4[0].CIK)
+	assert.Equal(t, "0000320193-24-000101", form4[1].Accession())
+}
+
+func TestSource_IndexURL(t *testing.T) {
+	s := NewSource(Config{})
+
+	assert.Equal(t, "https://www.sec.gov/Archives/edgar/daily-index/2024/QTR2/form.20240626.idx",
+		s.IndexURL(time.Date(2024, 6, 26, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "https://www.sec.gov/Archives/edgar/daily-index/2024/QTR4/form.20241001.idx",
+		s.IndexURL(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestSource_Fetch(t *testing.T) {
+	s := newTestSource(t)
+	day := time.Date(2024, 6, 26, 0, 0, 0, 0, time.UTC)
+	w := insider.DayWindow(day)
+
+	assert.Equal(t, []string{s.baseURL + "/Archives/edgar/daily-index/2024/QTR2/form.20240626.idx"}, s.URLs(w))
+
+	txs, err := s.Fetch(context.Background(), w)
+	require.NoError(t, err, "the missing filing is skipped")
+
+	require.Len(t, txs, 2)
+	assert.Equal(t, insider.Transaction{
+		Ticker:          "EXTX",
+		Owner:           "Doe John",
+		Relationship:    "Chief Executive Officer",
+		TransactionDate: time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC),
+		Transaction:     insider.Sale,
+		Cost:            24.31,
+		Shares:          10000,
+		Value:           243127,
+		SharesTotal:     150000,
+		SEC: insider.SEC{
+			NotificationDate: time.Date(2024, 6, 26, 16, 35, 12, 0, time.UTC),
+			URL:              s.baseURL + "/Archives/edgar/data/1397047/000121390024056822/0001213900-24-056822.txt",
+			IssuerCIK:        "1397047",
+			OwnerCIK:         "1805833",
+		},
+	}, txs[0])
+	assert.Equal(t, "000121390024056822", txs[0].Accession())
+
+	assert.Equal(t, "SMPL", txs[1].Ticker)
+	assert.Equal(t, insider.Buy, txs[1].Transaction)
+	assert.Equal(t, 157500, txs[1].Value)
+	assert.Equal(t, "Director", txs[1].Relationship)
+	assert.Equal(t, day, txs[1].NotificationDate, "the date filed without the acceptance time")
+
+	txs, err = s.Fetch(context.Background(), insider.Window{From: day.AddDate(0, 0, -1), To: day.AddDate(0, 0, 2)})
+	require.ErrorContains(t, err, "2024-06-25: daily index isn't published yet")
+	require.ErrorContains(t, err, "2024-06-27: daily index isn't published yet")
+	assert.Len(t, txs, 2, "transactions of fetched days are kept")
+}
+
+func TestSource_FetchForbidden(t *testing.T) {
+	files := http.FileServer(http.Dir("testdata"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/Archives/edgar/daily-index/2024/QTR2/form.20240627.idx" {
+			http.Error(w, "Request Rate Threshold Exceeded", http.StatusForbidden)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &Source{client: srv.Client(), userAgent: "test test@example.com", baseURL: srv.URL}
+	day := time.Date(2024, 6, 26, 0, 0, 0, 0, time.UTC)
+
+	txs, err := s.Fetch(context.Background(), insider.Window{From: day, To: day.AddDate(0, 0, 2)})
+	require.ErrorContains(t, err, "2024-06-27: failed get daily index: unexpected status: 403 Forbidden")
+	assert.Len(t, txs, 2, "transactions of fetched days are kept")
+}
+
+func TestSource_FetchNoIndex(t *testing.T) {
+	s := newTestSource(t)
+
+	txs, err := s.Fetch(context.Background(), insider.Window{
+		From: time.Date(2024, 6, 29, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err, "weekends have no index")
+	assert.Empty(t, txs)
+
+	txs, err = s.Fetch(context.Background(), insider.DayWindow(time.Date(2024, 7, 4, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, err, "holidays have no index")
+	assert.Empty(t, txs)
+}
+
+func TestBusinessDay(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		want bool
+	}{
+		{day: time.Date(2024, 6, 26, 0, 0, 0, 0, time.UTC), want: true},
+		{day: time.Date(2024, 6, 29, 0, 0, 0, 0, time.UTC), want: false},
+		{day: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), want: false},
+		{day: time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC), want: false},
+		{day: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), want: true},
+		{day: time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC), want: false},
+		// Juneteenth on Saturday is observed on Friday
+		{day: time.Date(2021, 6, 18, 0, 0, 0, 0, time.UTC), want: false},
+		// Christmas on Sunday is observed on Monday
+		{day: time.Date(2022, 12, 26, 0, 0, 0, 0, time.UTC), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.day.Format(time.DateOnly), func(t *testing.T) {
+			assert.Equal(t, tt.want, BusinessDay(tt.day))
+		})
+	}
+}
+
+func TestForm4_InsiderTransactions(t *testing.T) {
+	day := time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC)
+	f := &Form4{
+		Issuer: Issuer{CIK: "1397047", Ticker: "EXTX"},
+		Owners: []Owner{{CIK: "1805833", Name: "Doe John", Relationship: "CEO"}},
+		Transactions: []Form4Transaction{
+			{Date: day, Code: "S", Shares: 1000, Price: 10, SharesOwnedAfter: 9000},
+			{Date: day, Code: "M", Shares: 500, Price: 2, SharesOwnedAfter: 9500},
+			{Date: day, Code: "S", Shares: 500, Price: 11, SharesOwnedAfter: 9000},
+			{Date: day.AddDate(0, 0, 1), Code: "S", Shares: 100, Price: 12, SharesOwnedAfter: 8900},
+			{Derivative: true, Date: day, Code: "S", Shares: 500, Price: 2},
+		},
+	}
+
+	txs := f.InsiderTransactions("https://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/0001213900-24-056822.txt", day)
+	require.Len(t, txs, 2, "sales of the same day are merged, the exercise and derivatives are skipped")
+
+	assert.Equal(t, 1500, txs[0].Shares)
+	assert.Equal(t, 15500, txs[0].Value)
+	assert.Equal(t, 10.33, txs[0].Cost)
+	assert.Equal(t, 9000, txs[0].SharesTotal)
+	assert.Equal(t, "CEO", txs[0].Relationship)
+
+	assert.Equal(t, 100, txs[1].Shares)
+	assert.Equal(t, 8900, txs[1].SharesTotal)
+
+	f.Owners = append(f.Owners, Owner{CIK: "1805834", Name: "Doe Trust", Relationship: "10% Owner"})
+	txs = f.InsiderTransactions("https://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/0001213900-24-056822.txt", day)
+	require.Len(t, txs, 4, "rows of every owner of the joint filing")
+	assert.Equal(t, "Doe John", txs[1].Owner)
+	assert.Equal(t, "Doe Trust", txs[2].Owner)
+	assert.Equal(t, "1805834", txs[2].OwnerCIK)
+	assert.Equal(t, "10% Owner", txs[2].Relationship)
+	assert.Equal(t, 1500, txs[2].Shares)
+}
+
+func TestParseForm4_Submission(t *testing.T) {
+	f, err := os.Open("testdata/Archives/edgar/data/320193/0000320193-24-000101.txt")
+	require.NoError(t, err)
+	defer f.Close()
+
+	form, err := ParseForm4(f)
+	require.NoError(t, err, "the XML is inside the submission text file")
+	assert.Equal(t, "SMPL", form.Issuer.Ticker)
+	assert.Len(t, form.Transactions, 2)
+}
@@ -0,0 +1,82 @@
+package edgar
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// finviz links to the human readable version of the filing:
+// http://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/xslF345X05/ownership.xml
+// the raw XML is available at the same path without the xsl stylesheet part.
+
+// RawXMLURL returns the link to the raw Form 4 XML document.
+func RawXMLURL(filingURL string) (string, error) {
+	u, err := url.Parse(filingURL)
+	if err != nil {
+		return "", fmt.Errorf("parse url: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	raw := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if strings.HasPrefix(p, "xslF345") {
+			continue
+		}
+		raw = append(raw, p)
+	}
+
+	u.Scheme = "https"
+	u.Path = "/" + strings.Join(raw, "/")
+
+	return u.String(), nil
+}
+
+// AccessionNumber returns the accession number of the filing
+// in the dashed form: 0001213900-24-056822.
+func AccessionNumber(filingURL string) (string, error) {
+	parts, err := archivePath(filingURL)
+	if err != nil {
+		return "", err
+	}
+
+	acc := parts[4]
+	if len(acc) != 18 {
+		return "", fmt.Errorf("unexpected accession number: %s", acc)
+	}
+
+	return fmt.Sprintf("%s-%s-%s", acc[:10], acc[10:12], acc[12:]), nil
+}
+
+// FilerCIK returns the CIK of the filer directory of the filing,
+// finviz links to the directory of the issuer.
+func FilerCIK(filingURL string) (string, error) {
+	parts, err := archivePath(filingURL)
+	if err != nil {
+		return "", err
+	}
+
+	return CIK(parts[3]), nil
+}
+
+// CIK returns the CIK without leading zeros, as in EDGAR paths:
+// 0001397047 is 1397047.
+func CIK(s string) string {
+	return strings.TrimLeft(strings.TrimSpace(s), "0")
+}
+
+// archivePath returns parts of the filing path:
+// /Archives/edgar/data/{cik}/{accession}/...
+func archivePath(filingURL string) ([]string, error) {
+	u, err := url.Parse(filingURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 5 || parts[2] != "data" {
+		return nil, fmt.Errorf("unexpected filing url: %s", filingURL)
+	}
+
+	return parts, nil
+}
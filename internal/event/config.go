@@ -0,0 +1,78 @@
+package event
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+const (
+	BrokerNATS    = "nats"
+	BrokerKafka   = "kafka"
+	BrokerRedis   = "redis"
+	BrokerFile    = "file"
+	BrokerWebhook = "webhook"
+)
+
+type Config struct {
+	// Broker is one of nats, kafka, redis, file, webhook.
+	// Events are kept in the outbox when it's empty.
+	Broker string
+	// URL is the NATS server, the Kafka REST Proxy, the Redis address,
+	// the file path or the webhook URL.
+	URL string
+	// Topic is the NATS subject, the Kafka topic or the Redis stream.
+	Topic string
+	// Batch is the number of events read from the outbox at once.
+	Batch int
+}
+
+func (c Config) Enabled() bool {
+	return c.Broker != ""
+}
+
+func ParseEventConfig() Config {
+	cfg := Config{
+		Broker: os.Getenv("EVENT_BROKER"),
+		URL:    os.Getenv("EVENT_URL"),
+		Topic:  "insider.transactions",
+		Batch:  100,
+	}
+
+	if v := os.Getenv("EVENT_TOPIC"); v != "" {
+		cfg.Topic = v
+	}
+
+	if v := os.Getenv("EVENT_BATCH"); v != "" {
+		batch, err := strconv.Atoi(v)
+		if err != nil || batch < 1 {
+			log.Fatal("env: EVENT_BATCH cannot convert")
+		}
+		cfg.Batch = batch
+	}
+
+	if cfg.Enabled() && cfg.URL == "" {
+		log.Fatal("env: EVENT_URL not found")
+	}
+
+	return cfg
+}
+
+// NewBroker connects to the broker from the config.
+func NewBroker(cfg Config) (Broker, error) {
+	switch cfg.Broker {
+	case BrokerNATS:
+		return NewNATSBroker(cfg.URL, cfg.Topic)
+	case BrokerKafka:
+		return NewKafkaBroker(cfg.URL, cfg.Topic), nil
+	case BrokerRedis:
+		return NewRedisBroker(cfg.URL, cfg.Topic)
+	case BrokerFile:
+		return NewFileBroker(cfg.URL)
+	case BrokerWebhook:
+		return NewWebhookBroker(cfg.URL), nil
+	default:
+		return nil, fmt.Errorf("unknown event broker %q", cfg.Broker)
+	}
+}
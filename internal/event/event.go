@@ -0,0 +1,101 @@
+// Package event streams new transactions to other services.
+//
+// Events are written to the outbox in the same database transaction
+// as transactions themselves. Relay reads the outbox in the order of
+// notification date and publishes events to the broker, the event is
+// marked published only after the broker accepts it, so every event is
+// delivered at least once.
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+)
+
+const TypeTransactionCreated = "TransactionCreated"
+
+// Event is the outbox record.
+type Event struct {
+	// ID is the outbox sequence number.
+	ID   int64  `json:"id" db:"id"`
+	Type string `json:"type" db:"event_type"`
+	// Key is the partition key, events of the ticker keep their order.
+	Key              string          `json:"key" db:"event_key"`
+	NotificationDate time.Time       `json:"notification_date" db:"notification_date"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	Data             json.RawMessage `json:"data" db:"payload"`
+}
+
+// TransactionCreated returns the event of the saved transaction.
+func TransactionCreated(t insider.Transaction) (Event, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return Event{}, fmt.Errorf("marshal transaction: %w", err)
+	}
+
+	return Event{
+		Type:             TypeTransactionCreated,
+		Key:              t.Ticker,
+		NotificationDate: t.NotificationDate,
+		Data:             data,
+	}, nil
+}
+
+// Broker publishes events, Publish returns after the broker
+// has accepted the event.
+type Broker interface {
+	Publish(ctx context.Context, e Event) error
+	Close() error
+}
+
+type Storer interface {
+	// PendingEvents returns not published events in the order of notification date.
+	PendingEvents(ctx context.Context, limit int) ([]Event, error)
+	MarkPublished(ctx context.Context, id int64) error
+}
+
+type Relay struct {
+	store  Storer
+	broker Broker
+	batch  int
+}
+
+func NewRelay(cfg Config, store Storer, broker Broker) *Relay {
+	return &Relay{
+		store:  store,
+		broker: broker,
+		batch:  cfg.Batch,
+	}
+}
+
+// Relay publishes all pending events and returns their number.
+// It stops on the first failure to keep the order,
+// the rest is published by the next call.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	var published int
+	for {
+		events, err := r.store.PendingEvents(ctx, r.batch)
+		if err != nil {
+			return published, fmt.Errorf("failed get pending events: %w", err)
+		}
+
+		for _, e := range events {
+			if err := r.broker.Publish(ctx, e); err != nil {
+				return published, fmt.Errorf("failed publish event %d: %w", e.ID, err)
+			}
+
+			if err := r.store.MarkPublished(ctx, e.ID); err != nil {
+				return published, fmt.Errorf("failed mark event %d: %w", e.ID, err)
+			}
+			published++
+		}
+
+		if len(events) < r.batch {
+			return published, nil
+		}
+	}
+}
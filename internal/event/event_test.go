@@ -0,0 +1,165 @@
+package event
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	events    []Event
+	published map[int64]bool
+}
+
+func (m *memStore) PendingEvents(_ context.Context, limit int) ([]Event, error) {
+	var pending []Event
+	for _, e := range m.events {
+		if !m.published[e.ID] && len(pending) < limit {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (m *memStore) MarkPublished(_ context.Context, id int64) error {
+	m.published[id] = true
+	return nil
+}
+
+type failingBroker struct {
+	Broker
+	failID int64
+}
+
+func (b failingBroker) Publish(ctx context.Context, e Event) error {
+	if e.ID == b.failID {
+		return errors.New("broker is down")
+	}
+	return b.Broker.Publish(ctx, e)
+}
+
+func newEvents(t *testing.T, n int) []Event {
+	events := make([]Event, 0, n)
+	for i := 1; i <= n; i++ {
+		e, err := TransactionCreated(insider.Transaction{
+			ID:     "id",
+			Ticker: "EXTX",
+			SEC:    insider.SEC{NotificationDate: time.Date(2024, 6, 24, 0, i, 0, 0, time.UTC)},
+		})
+		require.NoError(t, err)
+		e.ID = int64(i)
+		events = append(events, e)
+	}
+	return events
+}
+
+func readLines(t *testing.T, path string) []Event {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var events []Event
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestRelay_Relay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	fb, err := NewFileBroker(path)
+	require.NoError(t, err)
+	defer fb.Close()
+
+	s := &memStore{events: newEvents(t, 5), published: map[int64]bool{}}
+
+	// stops on the failed event to keep the order
+	n, err := NewRelay(Config{Batch: 2}, s, failingBroker{Broker: fb, failID: 4}).Relay(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = NewRelay(Config{Batch: 2}, s, fb).Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	events := readLines(t, path)
+	require.Len(t, events, 5)
+	for i, e := range events {
+		assert.Equal(t, int64(i+1), e.ID)
+		assert.Equal(t, TypeTransactionCreated, e.Type)
+		assert.Equal(t, "EXTX", e.Key)
+	}
+
+	var tr insider.Transaction
+	require.NoError(t, json.Unmarshal(events[0].Data, &tr))
+	assert.Equal(t, "EXTX", tr.Ticker)
+}
+
+func TestWebhookBroker_Publish(t *testing.T) {
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.Header.Get("Idempotency-Key"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	e := newEvents(t, 1)[0]
+	require.NoError(t, NewWebhookBroker(srv.URL).Publish(context.Background(), e))
+	assert.Equal(t, e.ID, got.ID)
+
+	failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failed.Close()
+
+	assert.Error(t, NewWebhookBroker(failed.URL).Publish(context.Background(), e))
+}
+
+func TestKafkaBroker_Publish(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/insider.transactions", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Records []struct {
+				Key   string `json:"key"`
+				Value Event  `json:"value"`
+			} `json:"records"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &req))
+		assert.Len(t, req.Records, 1)
+		assert.Equal(t, "EXTX", req.Records[0].Key)
+
+		if req.Records[0].Value.ID == 2 {
+			_, _ = w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"not leader"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":42}]}`))
+	}))
+	defer srv.Close()
+
+	b := NewKafkaBroker(srv.URL+"/", "insider.transactions")
+	events := newEvents(t, 2)
+
+	assert.NoError(t, b.Publish(context.Background(), events[0]))
+	assert.ErrorContains(t, b.Publish(context.Background(), events[1]), "not leader")
+}
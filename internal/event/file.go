@@ -0,0 +1,40 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FileBroker appends events to the file as JSON lines,
+// it's useful for testing consumers locally.
+type FileBroker struct {
+	f *os.File
+}
+
+func NewFileBroker(path string) (*FileBroker, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	return &FileBroker{f: f}, nil
+}
+
+func (b *FileBroker) Publish(_ context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if _, err := b.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return b.f.Sync()
+}
+
+func (b *FileBroker) Close() error {
+	return b.f.Close()
+}
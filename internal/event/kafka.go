@@ -0,0 +1,79 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaBroker produces events through the Kafka REST Proxy (API v2),
+// the key is used for partitioning.
+type KafkaBroker struct {
+	client *http.Client
+	url    string
+}
+
+func NewKafkaBroker(proxyURL, topic string) *KafkaBroker {
+	return &KafkaBroker{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+	}
+}
+
+func (b *KafkaBroker) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(map[string]any{
+		"records": []any{
+			map[string]any{"key": e.Key, "value": e},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, msg)
+	}
+
+	// records are produced one by one, so errors of the record
+	// are returned in the only offset
+	var res struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	for _, o := range res.Offsets {
+		if o.Error != "" {
+			return fmt.Errorf("produce: %s", o.Error)
+		}
+	}
+
+	return nil
+}
+
+func (b *KafkaBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSBroker publishes events to JetStream, the stream for the subject
+// has to exist. The outbox ID is used as the message ID,
+// so JetStream drops events published twice.
+type NATSBroker struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+func NewNATSBroker(url, subject string) (*NATSBroker, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("jetstream: %w", err)
+	}
+
+	return &NATSBroker{conn: conn, js: js, subject: subject}, nil
+}
+
+func (b *NATSBroker) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	msg := nats.NewMsg(b.subject)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(e.ID, 10))
+
+	if _, err := b.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+func (b *NATSBroker) Close() error {
+	b.conn.Close()
+	return nil
+}
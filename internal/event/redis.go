@@ -0,0 +1,50 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker appends events to the Redis stream.
+type RedisBroker struct {
+	client *redis.Client
+	stream string
+}
+
+// NewRedisBroker connects to the address or the redis:// URL.
+func NewRedisBroker(url, stream string) (*RedisBroker, error) {
+	opt := &redis.Options{Addr: url}
+	if strings.Contains(url, "://") {
+		var err error
+		opt, err = redis.ParseURL(url)
+		if err != nil {
+			return nil, fmt.Errorf("parse url: %w", err)
+		}
+	}
+
+	return &RedisBroker{client: redis.NewClient(opt), stream: stream}, nil
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, e Event) error {
+	if err := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		Values: map[string]any{
+			"id":                e.ID,
+			"type":              e.Type,
+			"key":               e.Key,
+			"notification_date": e.NotificationDate,
+			"data":              string(e.Data),
+		},
+	}).Err(); err != nil {
+		return fmt.Errorf("xadd: %w", err)
+	}
+
+	return nil
+}
+
+func (b *RedisBroker) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,57 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookBroker posts every event as JSON, the outbox ID is sent
+// in the Idempotency-Key header to drop events delivered twice.
+type WebhookBroker struct {
+	client *http.Client
+	url    string
+}
+
+func NewWebhookBroker(url string) *WebhookBroker {
+	return &WebhookBroker{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    url,
+	}
+}
+
+func (b *WebhookBroker) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(e.ID, 10))
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
+
+func (b *WebhookBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package ingest runs the pipeline of the day: it fetches transactions
+// from all registered sources concurrently, merges them without duplicates,
+// saves new ones with the digests and publishes pending digests.
+// The health of every source and publishing results are recorded in the run.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/metrics"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Registry keeps sources in the order of registration,
+// transactions of earlier sources win over duplicates from later ones.
+type Registry struct {
+	names   []string
+	sources map[string]insider.Source
+}
+
+func NewRegistry() *Registry {
+	return &Registry{sources: make(map[string]insider.Source)}
+}
+
+// Register adds the source, the source with the same name is replaced.
+func (r *Registry) Register(name string, s insider.Source) {
+	if _, ok := r.sources[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.sources[name] = s
+}
+
+// Names returns names of sources in the order of registration.
+func (r *Registry) Names() []string {
+	return r.names
+}
+
+func (r *Registry) Len() int {
+	return len(r.names)
+}
+
+type Runner struct {
+	sources   *Registry
+	store     insider.Storer
+	publisher *publish.Publisher
+}
+
+func New(sources *Registry, store insider.Storer, publisher *publish.Publisher) *Runner {
+	return &Runner{
+		sources:   sources,
+		store:     store,
+		publisher: publisher,
+	}
+}
+
+// fetched is the result of the source.
+type fetched struct {
+	txs      insider.Transactions
+	duration time.Duration
+	err      error
+}
+
+// Fetch fetches transactions of the window from all sources concurrently
+// and merges them in the order of the registry. It fails only if all sources
+// fail without transactions, the failed source is recorded in the run, the rest
+// and transactions the failed source has fetched before the error are kept.
+func (r *Runner) Fetch(ctx context.Context, l *run.Ledger, w insider.Window) (insider.Transactions, error) {
+	names := r.sources.Names()
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no sources")
+	}
+
+	results := make([]fetched, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string, s insider.Source) {
+			defer wg.Done()
+
+			ctx, span := tracing.Start(ctx, "source.fetch", attribute.String("source", name))
+			start := time.Now()
+			txs, err := s.Fetch(ctx, w)
+			results[i] = fetched{txs: txs, duration: time.Since(start), err: err}
+			span.SetAttributes(attribute.Int("rows", len(txs)))
+			tracing.End(span, err)
+		}(i, name, r.sources.sources[name])
+	}
+	wg.Wait()
+
+	var (
+		txs   insider.Transactions
+		stats = insider.FetchStats{SourceURLs: []string{}}
+		errs  []error
+	)
+	dedup := insider.NewDedup(nil)
+	for i, name := range names {
+		res := results[i]
+		metrics.ScrapeDuration.WithLabelValues(name).Observe(res.duration.Seconds())
+		if err := l.Source(ctx, name, len(res.txs), res.duration, res.err); err != nil {
+			return nil, err
+		}
+
+		if loc, ok := r.sources.sources[name].(insider.Locator); ok {
+			stats.SourceURLs = append(stats.SourceURLs, loc.URLs(w)...)
+		}
+
+		if res.err != nil {
+			slog.ErrorContext(ctx, "source failed", "source", name, "rows", len(res.txs), "err", res.err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, res.err))
+		}
+
+		stats.Scraped += len(res.txs)
+		for _, t := range res.txs {
+			if dedup.Add(t) {
+				txs = append(txs, t)
+			}
+		}
+	}
+	stats.Kept = len(txs)
+
+	if len(errs) == len(names) && len(txs) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := l.Scraped(ctx, stats); err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// Ingest fetches transactions of the window and saves them with the digests,
+// it returns only new transactions.
+func (r *Runner) Ingest(ctx context.Context, l *run.Ledger, w insider.Window, digests ...publish.Digest) (insider.Transactions, error) {
+	txs, err := r.Fetch(ctx, l, w)
+	if err != nil {
+		return nil, err
+	}
+
+	sctx, span := tracing.Start(ctx, "ingest.save", attribute.Int("rows", len(txs)))
+	txs, err = insider.Save(sctx, r.store, txs, digests...)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.Inserted(ctx, txs); err != nil {
+		return nil, err
+	}
+	metrics.RowsInserted.Add(float64(l.Run().Inserted))
+	metrics.RowsDuplicate.Add(float64(l.Run().Duplicates))
+
+	return txs, nil
+}
+
+// Publish delivers pending digests and records results in the run.
+func (r *Runner) Publish(ctx context.Context, l *run.Ledger) error {
+	results, err := r.publisher.Drain(ctx)
+	for _, res := range results {
+		if lerr := l.Published(ctx, res.Sink, res.Err); lerr != nil {
+			return lerr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,185 @@
+package ingest_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/ingest"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// source returns transactions after all sources of the barrier have started,
+// so sources fetched one by one never return.
+type source struct {
+	txs     insider.Transactions
+	err     error
+	urls    []string
+	barrier *barrier
+}
+
+func (s *source) Fetch(ctx context.Context, _ insider.Window) (insider.Transactions, error) {
+	if s.barrier != nil {
+		if err := s.barrier.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.txs, s.err
+}
+
+func (s *source) URLs(insider.Window) []string {
+	return s.urls
+}
+
+type barrier struct {
+	mu      sync.Mutex
+	started int
+	n       int
+}
+
+func newBarrier(n int) *barrier {
+	return &barrier{n: n}
+}
+
+func (b *barrier) wait(ctx context.Context) error {
+	b.mu.Lock()
+	b.started++
+	b.mu.Unlock()
+
+	for {
+		b.mu.Lock()
+		done := b.started >= b.n
+		b.mu.Unlock()
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func transaction(day time.Time, ticker string, url string) insider.Transaction {
+	return insider.Transaction{
+		Ticker:          ticker,
+		Owner:           "Doe John",
+		TransactionDate: day.AddDate(0, 0, -1),
+		Transaction:     insider.Sale,
+		Shares:          100,
+		Value:           1000,
+		SEC: insider.SEC{
+			NotificationDate: day.Add(20 * time.Hour),
+			URL:              url,
+		},
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := ingest.NewRegistry()
+	r.Register("finviz", &source{})
+	r.Register("edgar", &source{})
+	r.Register("finviz", &source{})
+
+	assert.Equal(t, []string{"finviz", "edgar"}, r.Names(), "the replaced source keeps its order")
+	assert.Equal(t, 2, r.Len())
+}
+
+func TestRunner_Ingest(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+	a := transaction(day, "AAA", "http://www.sec.gov/Archives/edgar/data/1/000000000024000001/xslF345X05/ownership.xml")
+	// the same filing from EDGAR
+	dup := transaction(day, "AAA", "https://www.sec.gov/Archives/edgar/data/1/000000000024000001/0000000000-24-000001.txt")
+	dup.Owner = "DOE JOHN"
+	b := transaction(day, "BBB", "https://www.sec.gov/Archives/edgar/data/2/000000000024000002/0000000000-24-000002.txt")
+
+	wait := newBarrier(2)
+	sources := ingest.NewRegistry()
+	sources.Register("finviz", &source{txs: insider.Transactions{a}, urls: []string{"finviz"}, barrier: wait})
+	sources.Register("edgar", &source{txs: insider.Transactions{dup, b}, urls: []string{"edgar"}, barrier: wait})
+	sources.Register("broken", &source{err: errors.New("blocked")})
+
+	store := memory.New()
+	publisher := publish.New(publish.Config{Attempts: 1}, store)
+	var published []time.Time
+	publisher.Register(run.SinkDigest, func(_ context.Context, day time.Time, _ *publish.Progress) error {
+		published = append(published, day)
+		return nil
+	})
+
+	l, err := run.Start(ctx, store, day)
+	require.NoError(t, err)
+
+	runner := ingest.New(sources, store, publisher)
+	txs, err := runner.Ingest(ctx, l, insider.DayWindow(day), publish.NewDigest(day, run.SinkDigest))
+	require.NoError(t, err, "sources are fetched concurrently, the broken source is skipped")
+	require.Len(t, txs, 2)
+	assert.Equal(t, a.URL, txs[0].URL, "the earlier source wins")
+	assert.Equal(t, "BBB", txs[1].Ticker)
+
+	require.NoError(t, runner.Publish(ctx, l))
+	assert.Equal(t, []time.Time{day}, published)
+
+	r := l.Run()
+	assert.Equal(t, []string{"finviz", "edgar"}, r.SourceURLs)
+	assert.Equal(t, []int{3, 2, 2, 0}, []int{r.Scraped, r.Kept, r.Inserted, r.Duplicates})
+	assert.Equal(t, run.StatusSucceeded, r.Sources["finviz"].Status)
+	assert.Equal(t, 1, r.Sources["finviz"].Rows)
+	assert.Equal(t, 2, r.Sources["edgar"].Rows)
+	assert.Equal(t, run.SourceHealth{Status: run.StatusFailed, Error: "blocked"}, r.Sources["broken"])
+	assert.Equal(t, run.StatusSucceeded, r.Publish[run.SinkDigest], "by the sink name")
+
+	txs, err = runner.Ingest(ctx, l, insider.DayWindow(day))
+	require.NoError(t, err)
+	assert.Empty(t, txs, "stored transactions are skipped")
+}
+
+func TestRunner_AllSourcesFail(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	sources := ingest.NewRegistry()
+	sources.Register("finviz", &source{err: errors.New("blocked")})
+
+	store := memory.New()
+	l, err := run.Start(ctx, store, day)
+	require.NoError(t, err)
+
+	_, err = ingest.New(sources, store, publish.New(publish.Config{Attempts: 1}, store)).Ingest(ctx, l, insider.DayWindow(day))
+	assert.ErrorContains(t, err, "finviz: blocked")
+	assert.Equal(t, run.StatusFailed, l.Run().Sources["finviz"].Status)
+
+	_, err = ingest.New(ingest.NewRegistry(), store, nil).Fetch(ctx, l, insider.DayWindow(day))
+	assert.Error(t, err, "no sources")
+}
+
+func TestRunner_PartialSource(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	a := transaction(day, "AAA", "https://www.sec.gov/Archives/edgar/data/1/000000000024000001/0000000000-24-000001.txt")
+	sources := ingest.NewRegistry()
+	sources.Register("edgar", &source{txs: insider.Transactions{a}, err: errors.New("2024-06-25: 403 Forbidden")})
+
+	store := memory.New()
+	l, err := run.Start(ctx, store, day)
+	require.NoError(t, err)
+
+	txs, err := ingest.New(sources, store, publish.New(publish.Config{Attempts: 1}, store)).Ingest(ctx, l, insider.DayWindow(day))
+	require.NoError(t, err, "transactions fetched before the error are saved")
+	require.Len(t, txs, 1)
+	assert.Equal(t, run.SourceHealth{Status: run.StatusFailed, Rows: 1, Error: "2024-06-25: 403 Forbidden"}, l.Run().Sources["edgar"])
+}
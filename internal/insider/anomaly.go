@@ -0,0 +1,118 @@
+package insider
+
+import (
+	"fmt"
+	"time"
+)
+
+// AnomalyConfig contains thresholds for flagging unusual transactions.
+type AnomalyConfig struct {
+	// HoldingsChange is the fraction of the position bought or sold.
+	HoldingsChange float64
+	// ValueToMedian is the ratio of the value to the insider's historical median.
+	ValueToMedian float64
+	// MinHistory is the number of the insider's past transactions
+	// required to compare with the median.
+	MinHistory int
+	// FirstBuyYears is the number of years without buys of the ticker.
+	FirstBuyYears int
+}
+
+var DefaultAnomalyConfig = AnomalyConfig{
+	HoldingsChange: 0.5,
+	ValueToMedian:  5,
+	MinHistory:     3,
+	FirstBuyYears:  2,
+}
+
+// Anomaly contains metrics derived at ingest and reasons
+// why the transaction is unusual.
+type Anomaly struct {
+	// HoldingsChange is the fraction of the position before the transaction
+	// that was bought or sold, nil for a new position.
+	HoldingsChange *float64 `json:"holdings_change" db:"holdings_change"`
+	// ValueToMedian is the ratio of the value to the insider's historical
+	// median of the same transaction type, nil without enough history.
+	ValueToMedian *float64 `json:"value_to_median" db:"value_to_median"`
+	// FirstBuy is the first buy of the ticker by the insider in FirstBuyYears.
+	FirstBuy bool     `json:"first_buy" db:"first_buy"`
+	Flags    []string `json:"flags" db:"anomaly_flags"`
+}
+
+// History is the insider's past transactions
+// of the same ticker and transaction type.
+type History struct {
+	Owner        string          `json:"owner" db:"owner"`
+	Ticker       string          `json:"ticker" db:"ticker"`
+	Transaction  TransactionType `json:"transaction" db:"transaction_type"`
+	Transactions int             `json:"transactions" db:"transactions"`
+	// MedianValue is the median value of the insider's transactions
+	// of the type in all tickers.
+	MedianValue *float64 `json:"median_value" db:"median_value"`
+	// LastBuy is the last buy of the ticker by the insider.
+	LastBuy *time.Time `json:"last_buy" db:"last_buy"`
+}
+
+// Annotate computes anomaly metrics and flags of the transactions
+// using the insiders' history.
+func (t Transactions) Annotate(history []History, cfg AnomalyConfig) {
+	type key struct {
+		owner, ticker string
+		transaction   TransactionType
+	}
+
+	byKey := make(map[key]History, len(history))
+	for _, h := range history {
+		byKey[key{h.Owner, h.Ticker, h.Transaction}] = h
+	}
+
+	for i := range t {
+		tr := &t[i]
+		tr.Anomaly = tr.anomaly(byKey[key{tr.Owner, tr.Ticker, tr.Transaction}], cfg)
+	}
+}
+
+func (t Transaction) anomaly(h History, cfg AnomalyConfig) Anomaly {
+	var a Anomaly
+
+	before := t.SharesTotal - t.Shares
+	verb := "bought"
+	if t.Transaction == Sale {
+		before = t.SharesTotal + t.Shares
+		verb = "sold"
+	}
+
+	switch {
+	case before > 0:
+		change := float64(t.Shares) / float64(before)
+		a.HoldingsChange = &change
+		if change >= cfg.HoldingsChange {
+			a.Flags = append(a.Flags, fmt.Sprintf("%s %.0f%% of holdings", verb, change*100))
+		}
+	case t.Transaction == Buy:
+		a.Flags = append(a.Flags, "new position")
+	}
+
+	if h.MedianValue != nil && *h.MedianValue > 0 && h.Transactions >= cfg.MinHistory {
+		ratio := float64(t.Value) / *h.MedianValue
+		a.ValueToMedian = &ratio
+		if ratio >= cfg.ValueToMedian {
+			a.Flags = append(a.Flags, fmt.Sprintf("value is %.1fx the insider's median %s",
+				ratio, t.Transaction))
+		}
+	}
+
+	if t.Transaction == Buy {
+		since := t.TransactionDate.AddDate(-cfg.FirstBuyYears, 0, 0)
+		if h.LastBuy == nil || h.LastBuy.Before(since) {
+			a.FirstBuy = true
+		}
+
+		// without the insider's history every buy would be the first one
+		if a.FirstBuy && (h.LastBuy != nil || h.Transactions >= cfg.MinHistory) {
+			a.Flags = append(a.Flags, fmt.Sprintf("first buy of %s in %d years", t.Ticker, cfg.FirstBuyYears))
+		}
+	}
+
+	return a
+}
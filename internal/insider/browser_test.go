@@ -0,0 +1,111 @@
+package insider_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowser_Parse(t *testing.T) {
+	tests := []struct {
+		name      string
+		fileName  string
+		expectLen int
+	}{
+		{
+			name:      "Should Parse Transactions",
+			fileName:  "testdata/transactions.html",
+			expectLen: 199,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileData, err := os.ReadFile(tt.fileName)
+			if err != nil {
+				t.Fatalf("Could not read file: %v", err)
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Write(fileData)
+			}))
+			defer server.Close()
+
+			browser := insider.New()
+
+			transactions, err := browser.Parse(server.URL)
+			assert.NoError(t, err)
+			assert.Len(t, transactions, tt.expectLen)
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	fileData, err := os.ReadFile("testdata/transactions.html")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write(fileData)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := memory.New()
+
+	transactions, err := insider.New().Parse(server.URL)
+	require.NoError(t, err)
+
+	fresh, err := insider.Save(ctx, store, transactions)
+	require.NoError(t, err)
+	assert.NotEmpty(t, fresh)
+	assert.LessOrEqual(t, len(fresh), len(transactions), "duplicates of the page are skipped")
+	for _, tr := range fresh {
+		assert.NotEmpty(t, tr.ID)
+	}
+
+	fresh, err = insider.Save(ctx, store, transactions)
+	require.NoError(t, err)
+	assert.Empty(t, fresh, "stored transactions are skipped")
+}
+
+func TestSave_Resolve(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	require.NoError(t, store.SaveSymbols(ctx, []symbol.Symbol{
+		{Source: symbol.EDGAR, Symbol: "1067983", Ticker: "BRK.B", UpdatedAt: time.Now()},
+		{Source: symbol.Finviz, Symbol: "FB", Ticker: "META", UpdatedAt: time.Now()},
+	}))
+
+	notified := time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC)
+	tx := func(ticker, cik string) insider.Transaction {
+		return insider.Transaction{
+			Ticker:          ticker,
+			Owner:           "Smith John",
+			TransactionDate: notified.AddDate(0, 0, -1),
+			Transaction:     insider.Buy,
+			Shares:          100,
+			Value:           1000,
+			SEC:             insider.SEC{NotificationDate: notified, IssuerCIK: cik},
+		}
+	}
+
+	fresh, err := insider.Save(ctx, store, insider.Transactions{
+		tx("BRKB", "1067983"),
+		tx("FB", ""),
+		tx("BF-A", "14693"),
+	})
+	require.NoError(t, err)
+	require.Len(t, fresh, 3)
+	assert.Equal(t, "BRK.B", fresh[0].Ticker, "by the CIK")
+	assert.Equal(t, "META", fresh[1].Ticker, "by the finviz symbol")
+	assert.Equal(t, "BF.A", fresh[2].Ticker, "normalized without the mapping")
+}
@@ -0,0 +1,33 @@
+package insider
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	SourceFinviz = "finviz"
+	SourceEDGAR  = "edgar"
+)
+
+type Config struct {
+	// Sources are fetched in order, finviz and edgar.
+	Sources []string
+}
+
+// ParseInsiderConfig returns config from the environment:
+// INSIDER_SOURCES is a comma separated list, finviz by default.
+func ParseInsiderConfig() Config {
+	cfg := Config{Sources: []string{SourceFinviz}}
+
+	if v := os.Getenv("INSIDER_SOURCES"); v != "" {
+		cfg.Sources = nil
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+				cfg.Sources = append(cfg.Sources, s)
+			}
+		}
+	}
+
+	return cfg
+}
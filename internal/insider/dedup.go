@@ -0,0 +1,78 @@
+package insider
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// accessionRe finds the accession number in links to EDGAR archives:
+// /Archives/edgar/data/1397047/000121390024056822/...
+var accessionRe = regexp.MustCompile(`/Archives/edgar/data/\d+/(\d{18})/`)
+
+// Accession returns the accession number of the filing the transaction
+// was reported in, 18 digits without dashes. It's empty if SEC.URL
+// isn't the link to EDGAR archives.
+func (t Transaction) Accession() string {
+	m := accessionRe.FindStringSubmatch(t.URL)
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+// filingKey identifies the transaction in the filing regardless of the source,
+// sources spell owners and round values differently.
+func (t Transaction) filingKey() string {
+	acc := t.Accession()
+	if acc == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s|%s|%s", acc, t.Transaction, t.TransactionDate.UTC().Format(time.DateOnly))
+}
+
+// Dedup detects transactions that are already stored or fetched.
+// Transactions are the same by Key, or by the filing, the type and the date
+// when they come from different sources: links to the same filing differ.
+// Rows of one source with the same filing link are different transactions.
+type Dedup struct {
+	keys map[string]struct{}
+	// filings are links by the filing key
+	filings map[string]string
+}
+
+// NewDedup returns Dedup of the stored transactions.
+func NewDedup(stored Transactions) *Dedup {
+	d := &Dedup{
+		keys:    make(map[string]struct{}, len(stored)),
+		filings: make(map[string]string, len(stored)),
+	}
+
+	for _, t := range stored {
+		d.Add(t)
+	}
+
+	return d
+}
+
+// Add reports whether the transaction is new and remembers it.
+func (d *Dedup) Add(t Transaction) bool {
+	key := t.Key()
+	if _, ok := d.keys[key]; ok {
+		return false
+	}
+
+	fk := t.filingKey()
+	if url, ok := d.filings[fk]; ok && fk != "" && url != t.URL {
+		return false
+	}
+
+	d.keys[key] = struct{}{}
+	if _, ok := d.filings[fk]; !ok && fk != "" {
+		d.filings[fk] = t.URL
+	}
+
+	return true
+}
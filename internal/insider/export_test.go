@@ -0,0 +1,8 @@
+package insider
+
+import "context"
+
+// Parse exposes parse to external tests.
+func (b *Browser) Parse(url string) (Transactions, error) {
+	return b.parse(context.Background(), url)
+}
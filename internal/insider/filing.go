@@ -0,0 +1,62 @@
+package insider
+
+import "regexp"
+
+// TransactionCode is the SEC Form 4 transaction code.
+type TransactionCode string
+
+const (
+	// CodePurchase is an open market or private purchase.
+	CodePurchase TransactionCode = "P"
+	// CodeSale is an open market or private sale.
+	CodeSale TransactionCode = "S"
+	// CodeOptionExercise is an exercise or conversion of derivative security.
+	CodeOptionExercise TransactionCode = "M"
+	// CodeAward is a grant or award from the company.
+	CodeAward TransactionCode = "A"
+	// CodeTaxWithholding is a payment of exercise price or tax liability
+	// by delivering securities.
+	CodeTaxWithholding TransactionCode = "F"
+	// CodeGift is a bona fide gift.
+	CodeGift TransactionCode = "G"
+)
+
+// Filing contains details from the Form 4 filing
+// the transaction was reported in.
+type Filing struct {
+	AccessionNumber string          `json:"accession_number" db:"accession_number"`
+	TransactionCode TransactionCode `json:"transaction_code" db:"transaction_code"`
+	// IssuerCIK and OwnerCIK identify the company and the insider
+	// regardless of ticker changes and name variations.
+	IssuerCIK string `json:"issuer_cik" db:"issuer_cik"`
+	OwnerCIK  string `json:"owner_cik" db:"owner_cik"`
+	// Plan10b51 is the Rule 10b5-1 checkbox of the filing.
+	Plan10b51 bool `json:"plan_10b5_1" db:"plan_10b5_1"`
+	// DirectOwnership is nil if the row of the transaction isn't known.
+	DirectOwnership *bool    `json:"direct_ownership" db:"direct_ownership"`
+	Footnotes       []string `json:"footnotes" db:"footnotes"`
+}
+
+var (
+	// Rule 10b5-1 is written in footnotes in different ways: 10b5-1, 10b-5-1, 10b5–1
+	plan10b51Re = regexp.MustCompile(`(?i)10b-?5\s*[-–]\s*1`)
+	// "... were not made pursuant to a Rule 10b5-1 trading plan"
+	notPursuantRe = regexp.MustCompile(`(?i)not\s+(\w+\s+){0,2}pursuant`)
+)
+
+// Planned reports whether the transaction was pre-scheduled under
+// Rule 10b5-1 plan (by the checkbox or footnotes) rather than made
+// at the insider's discretion.
+func (f Filing) Planned() bool {
+	if f.Plan10b51 {
+		return true
+	}
+
+	for _, n := range f.Footnotes {
+		if plan10b51Re.MatchString(n) && !notPursuantRe.MatchString(n) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -6,12 +6,17 @@ package insider
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/RyabovNick/finviz_parser/internal/metrics"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/RyabovNick/finviz_parser/internal/tracing"
 	"github.com/gocolly/colly/v2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TransactionType string
@@ -27,43 +32,114 @@ const (
 	Sale TransactionType = "Sale"
 )
 
+// Browser is the finviz source of transactions.
 type Browser struct {
-	store               Storer
 	buyTransactionsURL  string
 	sellTransactionsURL string
 }
 
 type Storer interface {
-	InsertTransactions(context.Context, Transactions) error
+	// InsertTransactions saves new transactions and returns them with IDs,
+	// transactions that are already stored are skipped. Digests are
+	// enqueued in the same database transaction.
+	InsertTransactions(context.Context, Transactions, ...publish.Digest) (Transactions, error)
+	History(context.Context, Transactions) ([]History, error)
+	symbol.Storer
 }
 
-func New(store Storer) *Browser {
+func New() *Browser {
 	return &Browser{
-		store:               store,
 		buyTransactionsURL:  "https://finviz.com/insidertrading.ashx?tc=1",
 		sellTransactionsURL: "https://finviz.com/insidertrading.ashx?tc=2",
 	}
 }
 
-// LastDayTransaction parses all sell and buy transactions
-// and returns only transactions from the last day
-func (b *Browser) LastDayTransaction() (Transactions, error) {
-	txb, err := b.buyTransactions()
+// FetchStats describes the fetched sources.
+type FetchStats struct {
+	SourceURLs []string
+	// Scraped is the number of transactions fetched from all sources.
+	Scraped int
+	// Kept is the number of transactions without duplicates of other sources.
+	Kept int
+}
+
+// Fetch parses all sell and buy transactions
+// and returns only transactions notified in the window.
+func (b *Browser) Fetch(ctx context.Context, w Window) (Transactions, error) {
+	txb, err := b.buyTransactions(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("buyTransactions: %w", err)
 	}
 
-	txs, err := b.sellTransactions()
+	txs, err := b.sellTransactions(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("sellTransactions: %w", err)
 	}
 
-	return append(txb.lastDay(), txs.lastDay()...), nil
+	return append(w.Filter(txb), w.Filter(txs)...), nil
+}
+
+// URLs returns the pages of buys and sales.
+func (b *Browser) URLs(Window) []string {
+	return []string{b.buyTransactionsURL, b.sellTransactionsURL}
+}
+
+// Save resolves tickers with the stored symbols, computes anomaly metrics
+// using the insiders' history and saves all transactions with the digests
+// of the day to the storer, it returns only new transactions.
+func Save(ctx context.Context, store Storer, tx Transactions, digests ...publish.Digest) (Transactions, error) {
+	if err := tx.Resolve(ctx, symbol.NewMapper(store)); err != nil {
+		return nil, fmt.Errorf("resolve: %w", err)
+	}
+
+	history, err := store.History(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+
+	tx.Annotate(history, DefaultAnomalyConfig)
+
+	return store.InsertTransactions(ctx, tx, digests...)
+}
+
+// Resolve replaces tickers with the canonical ones: by the issuer's CIK
+// if EDGAR has reported it and it's mapped, otherwise by the finviz symbol.
+func (t Transactions) Resolve(ctx context.Context, m *symbol.Mapper) error {
+	resolved := make(map[string]string)
+	for i, tx := range t {
+		key := tx.IssuerCIK + "|" + tx.Ticker
+		if ticker, ok := resolved[key]; ok {
+			t[i].Ticker = ticker
+			continue
+		}
+
+		var ticker string
+		if tx.IssuerCIK != "" {
+			var err error
+			if ticker, err = m.Ticker(ctx, symbol.EDGAR, tx.IssuerCIK); err != nil {
+				return err
+			}
+		}
+
+		if ticker == "" {
+			var err error
+			if ticker, err = m.Ticker(ctx, symbol.Finviz, symbol.Format(tx.Ticker, symbol.Finviz)); err != nil {
+				return err
+			}
+		}
+
+		resolved[key] = ticker
+		t[i].Ticker = ticker
+	}
+
+	return nil
 }
 
-// Save saves all transactions to the storer
-func (b *Browser) Save(ctx context.Context, tx Transactions) error {
-	return b.store.InsertTransactions(ctx, tx)
+// LastDay returns the day which notifications are parsed
+// by the run at now.
+func LastDay(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
 }
 
 type Transactions []Transaction
@@ -73,22 +149,11 @@ type Transactions []Transaction
 // finviz returns N latest transactions, but we need only
 // transactions from the last day
 func (t Transactions) lastDay() Transactions {
-	var lastDay Transactions
-
-	y, m, d := time.Now().Date()
-	from := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
-	to := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
-
-	for _, transaction := range t {
-		if transaction.SEC.NotificationDate.After(from) && transaction.SEC.NotificationDate.Before(to) {
-			lastDay = append(lastDay, transaction)
-		}
-	}
-
-	return lastDay
+	return DayWindow(LastDay(time.Now())).Filter(t)
 }
 
 type Transaction struct {
+	ID              string          `json:"id" db:"id"`
 	Ticker          string          `json:"ticker" db:"ticker"`
 	Owner           string          `json:"owner" db:"owner"`
 	Relationship    string          `json:"relationship" db:"relationship"`
@@ -99,11 +164,40 @@ type Transaction struct {
 	Value           int             `json:"value" db:"value"`
 	SharesTotal     int             `json:"shares_total" db:"shares_total"`
 	SEC
+	Anomaly
+}
+
+// Key identifies the transaction, finviz has no IDs.
+func (t Transaction) Key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%d|%d|%s", t.Ticker, t.Owner,
+		t.TransactionDate.UTC().Format(time.RFC3339), t.Transaction,
+		t.Shares, t.Value, t.NotificationDate.UTC().Format(time.RFC3339))
 }
 
 type SEC struct {
 	NotificationDate time.Time `json:"notification_date" db:"notification_date"`
 	URL              string    `json:"url" db:"url"`
+	// IssuerCIK and OwnerCIK are empty until the filing is enriched.
+	IssuerCIK string `json:"issuer_cik" db:"issuer_cik"`
+	OwnerCIK  string `json:"owner_cik" db:"owner_cik"`
+}
+
+// OwnerID identifies the insider: the reporting owner's CIK
+// or the name if the CIK is unknown.
+func (t Transaction) OwnerID() string {
+	if t.OwnerCIK != "" {
+		return t.OwnerCIK
+	}
+
+	return t.Owner
+}
+
+// ReportFilter narrows transactions used in reports.
+type ReportFilter struct {
+	// Day is the notification day of reported transactions.
+	Day time.Time
+	// ExcludePlanned excludes transactions made under Rule 10b5-1 plans.
+	ExcludePlanned bool
 }
 
 type TransactionTypeCount struct {
@@ -117,21 +211,40 @@ type RelationshipCount struct {
 	TransactionTypeCount
 }
 
+// DailyTotal is the total of the transaction type notified on the day.
+type DailyTotal struct {
+	Day time.Time `json:"day" db:"day"`
+	TransactionTypeCount
+}
+
 type TotalTransaction struct {
 	Ticker     string  `json:"ticker" db:"ticker"`
 	TotalValue float64 `json:"total_value" db:"total_value"`
 }
 
 func (t TotalTransaction) FinvizTicker() string {
-	return fmt.Sprintf("<a href='https://finviz.com/quote.ashx?t=%s'>%s</a>", t.Ticker, t.Ticker)
+	return QuoteLink(t.Ticker)
+}
+
+// QuoteLink returns HTML link to the finviz quote page of the ticker.
+func QuoteLink(ticker string) string {
+	return fmt.Sprintf("<a href='https://finviz.com/quote.ashx?t=%s'>%s</a>", symbol.Format(ticker, symbol.Finviz), ticker)
 }
 
 type Tickers []string
 
 func (t Tickers) Finviz() string {
-	tick := strings.Join(t, ",")
-	return fmt.Sprintf("<a href='https://finviz.com/screener.ashx?v=340&t=%s&o=ticker'>Open ALL in Finviz Screener</a>", tick)
+	return fmt.Sprintf("<a href='%s'>Open ALL in Finviz Screener</a>", t.ScreenerURL())
+}
+
+// ScreenerURL returns the finviz screener URL of all tickers.
+func (t Tickers) ScreenerURL() string {
+	finviz := make([]string, 0, len(t))
+	for _, ticker := range t {
+		finviz = append(finviz, symbol.Format(ticker, symbol.Finviz))
+	}
 
+	return fmt.Sprintf("https://finviz.com/screener.ashx?v=340&t=%s&o=ticker", strings.Join(finviz, ","))
 }
 
 func TransactionTypeToEnum(s string) TransactionType {
@@ -146,16 +259,18 @@ func TransactionTypeToEnum(s string) TransactionType {
 }
 
 // buyTransactions returns the list of all buy transactions
-func (b *Browser) buyTransactions() (Transactions, error) {
-	return b.parse(b.buyTransactionsURL)
+func (b *Browser) buyTransactions(ctx context.Context) (Transactions, error) {
+	return b.parse(ctx, b.buyTransactionsURL)
 }
 
 // sellTransactions returns the list of all sell transactions
-func (b *Browser) sellTransactions() (Transactions, error) {
-	return b.parse(b.sellTransactionsURL)
+func (b *Browser) sellTransactions(ctx context.Context) (Transactions, error) {
+	return b.parse(ctx, b.sellTransactionsURL)
 }
 
-func (b *Browser) parse(url string) (Transactions, error) {
+func (b *Browser) parse(ctx context.Context, url string) (Transactions, error) {
+	ctx, span := tracing.Start(ctx, "finviz.fetch", attribute.String("url", url))
+
 	var insider []Transaction
 
 	c := colly.NewCollector()
@@ -165,64 +280,84 @@ func (b *Browser) parse(url string) (Transactions, error) {
 			return
 		}
 
-		date, err := time.Parse(insiderDateFormat, e.ChildText("td:nth-child(4)"))
+		_, row := tracing.Start(ctx, "finviz.parse_row", attribute.Int("row", e.Index))
+		t, field, err := parseRow(e)
 		if err != nil {
-			log.Printf("date: %s", err)
+			// rejected rows are logged with the field and the ticker
+			slog.WarnContext(ctx, "reject finviz row",
+				"source", SourceFinviz, "ticker", e.ChildText("td:nth-child(1)"),
+				"field", field, "err", err)
+			metrics.RowsRejected.WithLabelValues(SourceFinviz, field).Inc()
+			tracing.End(row, fmt.Errorf("%s: %w", field, err))
 			return
 		}
+		row.SetAttributes(attribute.String("ticker", t.Ticker))
+		tracing.End(row, nil)
 
-		secDate, err := time.Parse(insiderSECDateFormat, addYear(e.ChildText("td:nth-child(10)")))
-		if err != nil {
-			log.Printf("secDate: %s", err)
-			return
-		}
+		metrics.RowsParsed.WithLabelValues(SourceFinviz).Inc()
+		insider = append(insider, t)
+	})
 
-		cost, err := strconv.ParseFloat(e.ChildText("td:nth-child(6)"), 64)
-		if err != nil {
-			log.Printf("cost: %s", err)
-			return
-		}
+	if err := c.Visit(url); err != nil {
+		err = fmt.Errorf("visit: %w", err)
+		tracing.End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("rows", len(insider)))
+	tracing.End(span, nil)
+	slog.DebugContext(ctx, "parse finviz page", "url", url, "rows", len(insider))
 
-		shares, err := strconv.Atoi(removeComma(e.ChildText("td:nth-child(7)")))
-		if err != nil {
-			log.Printf("shares: %s", err)
-			return
-		}
+	return insider, nil
+}
 
-		value, err := strconv.Atoi(removeComma(e.ChildText("td:nth-child(8)")))
-		if err != nil {
-			log.Printf("value: %s", err)
-			return
-		}
+// parseRow returns the transaction of the table row,
+// the field that can't be parsed is returned with the error.
+func parseRow(e *colly.HTMLElement) (Transaction, string, error) {
+	date, err := time.Parse(insiderDateFormat, e.ChildText("td:nth-child(4)"))
+	if err != nil {
+		return Transaction{}, "date", err
+	}
 
-		sharesTotal, err := strconv.Atoi(removeComma(e.ChildText("td:nth-child(9)")))
-		if err != nil {
-			log.Printf("sharesTotal: %s", err)
-			return
-		}
+	secDate, err := time.Parse(insiderSECDateFormat, addYear(e.ChildText("td:nth-child(10)")))
+	if err != nil {
+		return Transaction{}, "notification_date", err
+	}
 
-		insider = append(insider, Transaction{
-			Ticker:          e.ChildText("td:nth-child(1)"),
-			Owner:           e.ChildText("td:nth-child(2)"),
-			Relationship:    e.ChildText("td:nth-child(3)"),
-			TransactionDate: date,
-			Transaction:     TransactionTypeToEnum(e.ChildText("td:nth-child(5)")),
-			Cost:            cost,
-			Shares:          shares,
-			Value:           value,
-			SharesTotal:     sharesTotal,
-			SEC: SEC{
-				NotificationDate: secDate,
-				URL:              e.ChildAttr("td:nth-child(10) > a", "href"),
-			},
-		})
-	})
+	cost, err := strconv.ParseFloat(e.ChildText("td:nth-child(6)"), 64)
+	if err != nil {
+		return Transaction{}, "cost", err
+	}
 
-	if err := c.Visit(url); err != nil {
-		return nil, fmt.Errorf("visit: %w", err)
+	shares, err := strconv.Atoi(removeComma(e.ChildText("td:nth-child(7)")))
+	if err != nil {
+		return Transaction{}, "shares", err
 	}
 
-	return insider, nil
+	value, err := strconv.Atoi(removeComma(e.ChildText("td:nth-child(8)")))
+	if err != nil {
+		return Transaction{}, "value", err
+	}
+
+	sharesTotal, err := strconv.Atoi(removeComma(e.ChildText("td:nth-child(9)")))
+	if err != nil {
+		return Transaction{}, "shares_total", err
+	}
+
+	return Transaction{
+		Ticker:          symbol.Normalize(e.ChildText("td:nth-child(1)")),
+		Owner:           e.ChildText("td:nth-child(2)"),
+		Relationship:    e.ChildText("td:nth-child(3)"),
+		TransactionDate: date,
+		Transaction:     TransactionTypeToEnum(e.ChildText("td:nth-child(5)")),
+		Cost:            cost,
+		Shares:          shares,
+		Value:           value,
+		SharesTotal:     sharesTotal,
+		SEC: SEC{
+			NotificationDate: secDate,
+			URL:              e.ChildAttr("td:nth-child(10) > a", "href"),
+		},
+	}, "", nil
 }
 
 func addYear(date string) string {
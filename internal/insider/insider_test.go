@@ -1,48 +1,12 @@
 package insider
 
 import (
-	"net/http"
-	"net/http/httptest"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestBrowser_Parse(t *testing.T) {
-	tests := []struct {
-		name      string
-		fileName  string
-		expectLen int
-	}{
-		{
-			name:      "Should Parse Transactions",
-			fileName:  "testdata/transactions.html",
-			expectLen: 199,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			fileData, err := os.ReadFile(tt.fileName)
-			if err != nil {
-				t.Fatalf("Could not read file: %v", err)
-			}
-
-			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-				rw.Write(fileData)
-			}))
-
-			browser := New(nil)
-
-			transactions, err := browser.parse(server.URL)
-			assert.NoError(t, err)
-			assert.Len(t, transactions, tt.expectLen)
-		})
-	}
-}
-
 func TestTransactions_LastDay(t *testing.T) {
 	tests := []struct {
 		name string
@@ -73,3 +37,113 @@ func TestTransactions_LastDay(t *testing.T) {
 		})
 	}
 }
+
+func TestFiling_Planned(t *testing.T) {
+	tests := []struct {
+		name string
+		f    Filing
+		want bool
+	}{
+		{
+			name: "checkbox",
+			f:    Filing{Plan10b51: true},
+			want: true,
+		},
+		{
+			name: "footnote",
+			f: Filing{Footnotes: []string{
+				"The price reported is a weighted average price.",
+				"The sales reported in this Form 4 were effected pursuant to a Rule 10b5-1 trading plan adopted by the reporting person on December 12, 2023.",
+			}},
+			want: true,
+		},
+		{
+			name: "footnote with another spelling",
+			f:    Filing{Footnotes: []string{"Sold pursuant to a Rule 10b-5-1 plan."}},
+			want: true,
+		},
+		{
+			name: "not pursuant to the plan",
+			f:    Filing{Footnotes: []string{"These shares were not sold pursuant to a Rule 10b5-1 trading plan."}},
+			want: false,
+		},
+		{
+			name: "discretionary",
+			f:    Filing{Footnotes: []string{"Shares are held by the family trust."}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.f.Planned())
+		})
+	}
+}
+
+func TestTransactions_Annotate(t *testing.T) {
+	median := 100000.0
+	lastBuy := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	date := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		tr             Transaction
+		history        []History
+		holdingsChange float64
+		flags          int
+		firstBuy       bool
+	}{
+		{
+			name: "sold most of holdings",
+			tr: Transaction{
+				Owner: "Doe John", Ticker: "EXTX", Transaction: Sale, TransactionDate: date,
+				Shares: 6000, SharesTotal: 4000, Value: 60000,
+			},
+			holdingsChange: 0.6,
+			flags:          1,
+		},
+		{
+			name: "large buy after years",
+			tr: Transaction{
+				Owner: "Smith Alice", Ticker: "SMPL", Transaction: Buy, TransactionDate: date,
+				Shares: 1000, SharesTotal: 11000, Value: 1000000,
+			},
+			history: []History{
+				{Owner: "Smith Alice", Ticker: "SMPL", Transaction: Buy, Transactions: 5, MedianValue: &median, LastBuy: &lastBuy},
+			},
+			holdingsChange: 0.1,
+			flags:          2,
+			firstBuy:       true,
+		},
+		{
+			name: "usual buy",
+			tr: Transaction{
+				Owner: "Smith Alice", Ticker: "SMPL", Transaction: Buy, TransactionDate: date,
+				Shares: 1000, SharesTotal: 11000, Value: 100000,
+			},
+			history: []History{
+				{Owner: "Smith Alice", Ticker: "SMPL", Transaction: Buy, Transactions: 5, MedianValue: &median, LastBuy: &date},
+			},
+			holdingsChange: 0.1,
+			flags:          0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := Transactions{tt.tr}
+			tr.Annotate(tt.history, DefaultAnomalyConfig)
+
+			got := tr[0].Anomaly
+			if assert.NotNil(t, got.HoldingsChange) {
+				assert.InDelta(t, tt.holdingsChange, *got.HoldingsChange, 1e-9)
+			}
+			assert.Len(t, got.Flags, tt.flags)
+			assert.Equal(t, tt.firstBuy, got.FirstBuy)
+		})
+	}
+}
+
+func TestTickers_ScreenerURL(t *testing.T) {
+	assert.Equal(t, "https://finviz.com/screener.ashx?v=340&t=AAPL,BRK-B&o=ticker", Tickers{"AAPL", "BRK.B"}.ScreenerURL())
+	assert.Equal(t, "<a href='https://finviz.com/quote.ashx?t=BRK-B'>BRK.B</a>", QuoteLink("BRK.B"))
+}
@@ -0,0 +1,56 @@
+package insider
+
+import (
+	"context"
+	"time"
+)
+
+// Source fetches insider transactions notified in the window.
+// The finviz Browser and EDGAR daily index are sources.
+type Source interface {
+	Fetch(ctx context.Context, w Window) (Transactions, error)
+}
+
+// Locator is the source that knows the pages it fetches,
+// they are recorded in the run.
+type Locator interface {
+	URLs(w Window) []string
+}
+
+// Window is the range of notification dates, To is excluded.
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// DayWindow returns the window of notifications of the day.
+func DayWindow(day time.Time) Window {
+	return Window{From: day, To: day.AddDate(0, 0, 1)}
+}
+
+// Contains reports whether the notification date is in the window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.From) && t.Before(w.To)
+}
+
+// Days returns days of the window.
+func (w Window) Days() []time.Time {
+	var days []time.Time
+	for d := w.From; d.Before(w.To); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+
+	return days
+}
+
+// Filter returns only transactions notified in the window.
+func (w Window) Filter(tr Transactions) Transactions {
+	var in Transactions
+	for _, t := range tr {
+		if w.Contains(t.NotificationDate) {
+			in = append(in, t)
+		}
+	}
+
+	return in
+}
@@ -0,0 +1,78 @@
+package insider_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction_Accession(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/xslF345X05/ownership.xml", want: "000121390024056822"},
+		{url: "https://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/0001213900-24-056822.txt", want: "000121390024056822"},
+		{url: "https://finviz.com/quote.ashx?t=AAPL", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			tx := insider.Transaction{SEC: insider.SEC{URL: tt.url}}
+			assert.Equal(t, tt.want, tx.Accession())
+		})
+	}
+}
+
+func TestDedup(t *testing.T) {
+	day := time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC)
+	finviz := insider.Transaction{
+		Ticker: "EXTX", Owner: "Doe John", TransactionDate: day, Transaction: insider.Sale,
+		Shares: 10000, Value: 243127,
+		SEC: insider.SEC{
+			NotificationDate: day.Add(20 * time.Hour),
+			URL:              "http://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/xslF345X05/ownership.xml",
+		},
+	}
+
+	d := insider.NewDedup(insider.Transactions{finviz})
+	assert.False(t, d.Add(finviz), "stored")
+
+	edgar := finviz
+	edgar.Owner = "DOE JOHN"
+	edgar.Value = 243130
+	edgar.NotificationDate = day.AddDate(0, 0, 1)
+	edgar.URL = "https://www.sec.gov/Archives/edgar/data/1397047/000121390024056822/0001213900-24-056822.txt"
+	assert.False(t, d.Add(edgar), "the same filing from another source")
+
+	other := finviz
+	other.Shares = 500
+	other.Value = 12000
+	assert.True(t, d.Add(other), "another row of the same filing")
+
+	buy := edgar
+	buy.Transaction = insider.Buy
+	assert.True(t, d.Add(buy), "another type")
+}
+
+func TestWindow(t *testing.T) {
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+	w := insider.DayWindow(day)
+
+	assert.True(t, w.Contains(day))
+	assert.True(t, w.Contains(day.Add(21*time.Hour)))
+	assert.False(t, w.Contains(day.AddDate(0, 0, 1)), "the end is excluded")
+	assert.False(t, w.Contains(day.Add(-time.Second)))
+	assert.Equal(t, []time.Time{day}, w.Days())
+
+	tr := insider.Transactions{
+		{Ticker: "AAA", SEC: insider.SEC{NotificationDate: day.Add(time.Hour)}},
+		{Ticker: "BBB", SEC: insider.SEC{NotificationDate: day.AddDate(0, 0, -1)}},
+	}
+	assert.Equal(t, tr[:1], w.Filter(tr))
+
+	w.To = day.AddDate(0, 0, 3)
+	assert.Len(t, w.Days(), 3)
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type Config struct {
+	// Format is text or json.
+	Format string
+	Level  slog.Level
+	// Secrets are values that are always redacted.
+	Secrets []string
+}
+
+// ParseLoggingConfig returns config from the environment:
+// LOG_FORMAT (text by default), LOG_LEVEL (info by default).
+// TG_TOKEN and PG_PASSWORD are redacted.
+func ParseLoggingConfig() Config {
+	cfg := Config{
+		Format:  FormatText,
+		Level:   slog.LevelInfo,
+		Secrets: []string{os.Getenv("TG_TOKEN"), os.Getenv("PG_PASSWORD")},
+	}
+
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		switch strings.ToLower(v) {
+		case FormatText, FormatJSON:
+			cfg.Format = strings.ToLower(v)
+		default:
+			log.Fatal("env: LOG_FORMAT must be text or json")
+		}
+	}
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			log.Fatal("env: LOG_LEVEL cannot convert")
+		}
+	}
+
+	return cfg
+}
@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const token = "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"
+
+func TestRunID(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Format: FormatJSON, Level: slog.LevelInfo}, &buf)
+
+	ctx := WithRunID(context.Background(), 42)
+	log.InfoContext(ctx, "run started", "day", "2024-06-26")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "run started", line["msg"])
+	assert.Equal(t, "INFO", line["level"])
+	assert.EqualValues(t, 42, line["run_id"])
+	assert.Equal(t, "2024-06-26", line["day"])
+
+	buf.Reset()
+	log.Info("no run")
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.NotContains(t, buf.String(), "run_id")
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Format: FormatText, Level: slog.LevelWarn}, &buf)
+
+	log.Info("skipped")
+	log.Debug("skipped")
+	log.Warn("kept")
+
+	assert.NotContains(t, buf.String(), "skipped")
+	assert.Contains(t, buf.String(), "level=WARN msg=kept")
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		want string
+		leak string
+	}{
+		{
+			name: "keyword password",
+			log: func(l *slog.Logger) {
+				l.Info("connect", "dsn", "host=db port=5432 user=finviz password=s3cret sslmode=disable")
+			},
+			want: "password=[REDACTED] sslmode",
+			leak: "s3cret",
+		},
+		{
+			name: "url password",
+			log: func(l *slog.Logger) {
+				l.Error("failed", "err", errors.New("dial postgres://finviz:s3cret@db:5432/finviz"))
+			},
+			want: "postgres://finviz:[REDACTED]@db:5432",
+			leak: "s3cret",
+		},
+		{
+			name: "bot token in message",
+			log: func(l *slog.Logger) {
+				l.Warn("Post https://api.telegram.org/bot" + token + "/sendMessage: timeout")
+			},
+			want: "api.telegram.org/bot[REDACTED]/sendMessage",
+			leak: token,
+		},
+		{
+			name: "configured secret",
+			log: func(l *slog.Logger) {
+				l.Info("value", "token", "short-secret")
+			},
+			want: "token=[REDACTED]",
+			leak: "short-secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(New(Config{Format: FormatText, Secrets: []string{"short-secret", ""}}, &buf))
+
+			assert.Contains(t, buf.String(), tt.want)
+			assert.NotContains(t, buf.String(), tt.leak)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"os"
+)
+
+type Config struct {
+	// Addr is the address of /metrics of the daemon.
+	Addr string
+	// Pushgateway is the URL metrics of the one-shot run are pushed to,
+	// they aren't pushed if it's empty.
+	Pushgateway string
+	// Job is the job name in the Pushgateway.
+	Job string
+}
+
+func ParseMetricsConfig() Config {
+	cfg := Config{
+		Addr: ":8080",
+		Job:  "finviz_parser",
+	}
+
+	if v := os.Getenv("METRICS_ADDR"); v != "" {
+		cfg.Addr = v
+	}
+
+	cfg.Pushgateway = os.Getenv("METRICS_PUSHGATEWAY")
+
+	if v := os.Getenv("METRICS_JOB"); v != "" {
+		cfg.Job = v
+	}
+
+	return cfg
+}
@@ -0,0 +1,180 @@
+// Package metrics exposes Prometheus metrics of the job:
+// the daemon serves them on /metrics, the one-shot run
+// pushes them to the Pushgateway.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const namespace = "finviz_parser"
+
+// Registry contains all metrics of the job.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ScrapeDuration is the duration of fetching transactions by the source.
+	ScrapeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scrape_duration_seconds",
+		Help:      "Duration of fetching transactions by the source.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"source"})
+
+	// HTTPResponses counts responses by the host and the status code,
+	// failed requests have the "error" code.
+	HTTPResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_responses_total",
+		Help:      "HTTP responses by the host and the status code.",
+	}, []string{"host", "code"})
+
+	RowsParsed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_parsed_total",
+		Help:      "Rows parsed by the source.",
+	}, []string{"source"})
+
+	// RowsRejected counts rows skipped by the field that can't be parsed.
+	RowsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_rejected_total",
+		Help:      "Rows rejected by the source and the field that can't be parsed.",
+	}, []string{"source", "field"})
+
+	RowsInserted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_inserted_total",
+		Help:      "New transactions saved to the storage.",
+	})
+
+	RowsDuplicate = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_duplicate_total",
+		Help:      "Fetched transactions that are already stored.",
+	})
+
+	TelegramSendDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_duration_seconds",
+		Help:      "Latency of sending Telegram messages.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	TelegramSendFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_failures_total",
+		Help:      "Telegram messages that failed to send.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ScrapeDuration,
+		HTTPResponses,
+		RowsParsed,
+		RowsRejected,
+		RowsInserted,
+		RowsDuplicate,
+		TelegramSendDuration,
+		TelegramSendFailures,
+	)
+}
+
+type Storer interface {
+	// NewestNotification returns the notification date of the newest
+	// stored transaction, it's zero if there are no transactions.
+	NewestNotification(ctx context.Context) (time.Time, error)
+}
+
+// WatchNewest registers the age of the newest stored notification,
+// it's queried on every collection.
+func WatchNewest(store Storer) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "newest_notification_age_seconds",
+		Help:      "Age of the newest stored notification, -1 if unknown.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		newest, err := store.NewestNotification(ctx)
+		if err != nil || newest.IsZero() {
+			return -1
+		}
+
+		return time.Since(newest).Seconds()
+	}))
+}
+
+// Handler serves metrics of the registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Serve serves /metrics until ctx is done.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed serve metrics: %w", err)
+	}
+
+	return nil
+}
+
+// Push pushes metrics to the Pushgateway, metrics of the previous push
+// of the job are replaced.
+func Push(ctx context.Context, cfg Config) error {
+	if err := push.New(cfg.Pushgateway, cfg.Job).Gatherer(Registry).PushContext(ctx); err != nil {
+		return fmt.Errorf("failed push metrics: %w", err)
+	}
+
+	return nil
+}
+
+// Transport counts responses of the wrapped transport.
+type Transport struct {
+	next http.RoundTripper
+}
+
+// NewTransport wraps the transport, nil is http.DefaultTransport.
+func NewTransport(next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{next: next}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	HTTPResponses.WithLabelValues(req.URL.Host, code).Inc()
+
+	return resp, err
+}
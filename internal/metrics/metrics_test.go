@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type newestStore struct {
+	newest time.Time
+}
+
+func (s newestStore) NewestNotification(context.Context) (time.Time, error) {
+	return s.newest, nil
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	for _, path := range []string{"/", "/", "/missing"} {
+		resp, err := client.Get(srv.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(HTTPResponses.WithLabelValues(host, "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(HTTPResponses.WithLabelValues(host, "404")))
+
+	_, err := client.Get("http://127.0.0.1:1/")
+	require.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(HTTPResponses.WithLabelValues("127.0.0.1:1", "error")))
+}
+
+func TestHandler(t *testing.T) {
+	WatchNewest(newestStore{newest: time.Now().Add(-time.Hour)})
+	RowsRejected.WithLabelValues("finviz", "cost").Inc()
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	body := string(b)
+
+	assert.Contains(t, body, `finviz_parser_rows_rejected_total{field="cost",source="finviz"} 1`)
+	assert.Contains(t, body, "finviz_parser_newest_notification_age_seconds 3600")
+	assert.Contains(t, body, "finviz_parser_rows_inserted_total")
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestPush(t *testing.T) {
+	var pushed *url.URL
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed = r.URL
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, Push(context.Background(), Config{Pushgateway: srv.URL, Job: "finviz_parser"}))
+	require.NotNil(t, pushed)
+	assert.Equal(t, "/metrics/job/finviz_parser", pushed.Path)
+}
@@ -0,0 +1,54 @@
+package price
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/RyabovNick/finviz_parser/internal/quote"
+)
+
+const (
+	ProviderCSV    = "csv"
+	ProviderFinviz = "finviz"
+)
+
+type Config struct {
+	// Provider is csv or finviz, prices are not collected if empty.
+	Provider string
+	// CSVDir is the directory with {TICKER}.csv files for csv provider.
+	CSVDir string
+	// Benchmark is the ticker excess returns are calculated against.
+	Benchmark string
+}
+
+func ParsePriceConfig() Config {
+	benchmark := os.Getenv("PRICE_BENCHMARK")
+	if benchmark == "" {
+		benchmark = "SPY"
+	}
+
+	return Config{
+		Provider:  os.Getenv("PRICE_PROVIDER"),
+		CSVDir:    os.Getenv("PRICE_CSV_DIR"),
+		Benchmark: benchmark,
+	}
+}
+
+func (c Config) Enabled() bool {
+	return c.Provider != ""
+}
+
+// NewProvider creates the provider from the config.
+func NewProvider(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderCSV:
+		if cfg.CSVDir == "" {
+			return nil, fmt.Errorf("PRICE_CSV_DIR is required for csv provider")
+		}
+		return NewCSVProvider(cfg.CSVDir), nil
+	case ProviderFinviz:
+		return NewFinvizProvider(quote.New()), nil
+	default:
+		return nil, fmt.Errorf("unknown price provider: %q", cfg.Provider)
+	}
+}
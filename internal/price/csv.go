@@ -0,0 +1,97 @@
+package price
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+const csvDateFormat = "2006-01-02"
+
+// CSVProvider reads prices from local files {dir}/{SYMBOL}.csv named by
+// Yahoo symbols (BRK-B.csv) in the common export format (Yahoo, Stooq, ...):
+//
+//	Date,Open,High,Low,Close,Adj Close,Volume
+//	2024-06-24,10.5,11.2,10.1,11.0,11.0,120000
+type CSVProvider struct {
+	dir string
+}
+
+func NewCSVProvider(dir string) *CSVProvider {
+	return &CSVProvider{dir: dir}
+}
+
+func (p *CSVProvider) Closes(_ context.Context, sym string, from, to time.Time) ([]Close, error) {
+	f, err := os.Open(filepath.Join(p.dir, sym+".csv"))
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	return parseCSV(f, sym, from, to)
+}
+
+func (p *CSVProvider) Source() symbol.Source {
+	return symbol.Yahoo
+}
+
+func parseCSV(r io.Reader, ticker string, from, to time.Time) ([]Close, error) {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	dateCol, closeCol := -1, -1
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "date":
+			dateCol = i
+		case "close":
+			closeCol = i
+		}
+	}
+	if dateCol == -1 || closeCol == -1 {
+		return nil, fmt.Errorf("date or close column not found in %v", header)
+	}
+
+	var closes []Close
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return closes, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read: %w", err)
+		}
+
+		date, err := time.Parse(csvDateFormat, rec[dateCol])
+		if err != nil {
+			return nil, fmt.Errorf("date: %w", err)
+		}
+
+		if date.Before(from.Truncate(24*time.Hour)) || date.After(to) {
+			continue
+		}
+
+		c, err := strconv.ParseFloat(rec[closeCol], 64)
+		if err != nil {
+			return nil, fmt.Errorf("close: %w", err)
+		}
+
+		closes = append(closes, Close{
+			Ticker: ticker,
+			Date:   date,
+			Close:  c,
+		})
+	}
+}
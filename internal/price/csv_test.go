@@ -0,0 +1,94 @@
+package price
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVProvider_Closes(t *testing.T) {
+	tests := []struct {
+		name      string
+		ticker    string
+		from      time.Time
+		to        time.Time
+		expectLen int
+		wantErr   bool
+	}{
+		{
+			name:      "all closes",
+			ticker:    "EXTX",
+			from:      time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			to:        time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+			expectLen: 5,
+		},
+		{
+			name:      "closes in the window",
+			ticker:    "EXTX",
+			from:      time.Date(2024, 6, 24, 10, 0, 0, 0, time.UTC),
+			to:        time.Date(2024, 6, 26, 0, 0, 0, 0, time.UTC),
+			expectLen: 3,
+		},
+		{
+			name:    "unknown ticker",
+			ticker:  "NOPE",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewCSVProvider("testdata")
+
+			closes, err := p.Closes(context.Background(), tt.ticker, tt.from, tt.to)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, closes, tt.expectLen)
+		})
+	}
+}
+
+func TestLastSession(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "tuesday after the close in New York",
+			now:  time.Date(2024, 6, 25, 21, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "tuesday before the close in New York",
+			now:  time.Date(2024, 6, 25, 19, 59, 0, 0, time.UTC),
+			want: time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "wednesday in UTC is tuesday in New York",
+			now:  time.Date(2024, 6, 26, 2, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "saturday evening",
+			now:  time.Date(2024, 6, 29, 22, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "monday after weekend",
+			now:  time.Date(2024, 6, 24, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lastSession(tt.now))
+		})
+	}
+}
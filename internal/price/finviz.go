@@ -0,0 +1,92 @@
+package price
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/quote"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+// FinvizProvider scrapes the quote page, it has only the last
+// two closes ("Price" and "Prev Close"), so the history
+// is collected day by day.
+type FinvizProvider struct {
+	browser *quote.Browser
+	now     func() time.Time
+}
+
+func NewFinvizProvider(browser *quote.Browser) *FinvizProvider {
+	return &FinvizProvider{
+		browser: browser,
+		now:     time.Now,
+	}
+}
+
+func (p *FinvizProvider) Closes(_ context.Context, sym string, from, to time.Time) ([]Close, error) {
+	page, err := p.browser.Page(sym)
+	if err != nil {
+		return nil, fmt.Errorf("quote page: %w", err)
+	}
+
+	last, err := page.Float("Price")
+	if err != nil {
+		return nil, fmt.Errorf("price: %w", err)
+	}
+
+	prev, err := page.Float("Prev Close")
+	if err != nil {
+		return nil, fmt.Errorf("prev close: %w", err)
+	}
+
+	// the job runs when the US market is closed, so "Price"
+	// is the close of the last session
+	lastDay := lastSession(p.now())
+	prevDay := previousWeekday(lastDay)
+
+	var closes []Close
+	for _, c := range []Close{
+		{Ticker: sym, Date: prevDay, Close: prev},
+		{Ticker: sym, Date: lastDay, Close: last},
+	} {
+		if c.Date.Before(from.Truncate(24*time.Hour)) || c.Date.After(to) {
+			continue
+		}
+		closes = append(closes, c)
+	}
+
+	return closes, nil
+}
+
+// marketClose is the hour the session closes in New York.
+const marketClose = 16
+
+func (p *FinvizProvider) Source() symbol.Source {
+	return symbol.Finviz
+}
+
+// lastSession returns the last closed session at t in New York:
+// the day itself after the close, the previous weekday before it.
+// Exchange holidays are not taken into account.
+func lastSession(t time.Time) time.Time {
+	if ny, err := time.LoadLocation("America/New_York"); err == nil {
+		t = t.In(ny)
+	}
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if t.Hour() >= marketClose && day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+		return day
+	}
+
+	return previousWeekday(day)
+}
+
+func previousWeekday(d time.Time) time.Time {
+	d = d.AddDate(0, 0, -1)
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, -1)
+	}
+
+	return d
+}
@@ -0,0 +1,131 @@
+// Package price collects daily close prices of the traded tickers
+// to find out how the stock did after the insider's transaction.
+package price
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+// Forward returns are calculated for 60 trading days,
+// it's about 90 calendar days.
+const historyDays = 90
+
+// Close is the daily close price.
+type Close struct {
+	Ticker string    `json:"ticker" db:"ticker"`
+	Date   time.Time `json:"date" db:"date"`
+	Close  float64   `json:"close" db:"close"`
+}
+
+// Provider returns daily close prices of the symbol for [from, to].
+type Provider interface {
+	Closes(ctx context.Context, symbol string, from, to time.Time) ([]Close, error)
+	// Source is the format of symbols of the provider.
+	Source() symbol.Source
+}
+
+type Storer interface {
+	TradedTickers(ctx context.Context, since time.Time) (insider.Tickers, error)
+	SaveCloses(ctx context.Context, closes []Close) error
+	symbol.Storer
+}
+
+type Updater struct {
+	provider  Provider
+	store     Storer
+	mapper    *symbol.Mapper
+	benchmark string
+}
+
+func NewUpdater(cfg Config, provider Provider, store Storer) *Updater {
+	return &Updater{
+		provider:  provider,
+		store:     store,
+		mapper:    symbol.NewMapper(store),
+		benchmark: cfg.Benchmark,
+	}
+}
+
+// Update saves close prices of the benchmark and all tickers traded
+// by insiders during the time forward returns are calculated.
+// Tickers are requested by symbols of the provider and closes are saved
+// by the canonical tickers. Tickers without prices are skipped.
+func (u *Updater) Update(ctx context.Context) error {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -historyDays)
+
+	tickers, err := u.store.TradedTickers(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed get traded tickers: %w", err)
+	}
+
+	if u.benchmark != "" {
+		tickers = append(tickers, u.benchmark)
+	}
+
+	for _, t := range tickers {
+		sym, err := u.mapper.Symbol(ctx, t, u.provider.Source())
+		if err != nil {
+			return fmt.Errorf("failed get symbol: %w", err)
+		}
+
+		closes, err := u.provider.Closes(ctx, sym, from, to)
+		if err != nil {
+			slog.WarnContext(ctx, "skip ticker without closes", "ticker", t, "symbol", sym, "err", err)
+			continue
+		}
+
+		for i := range closes {
+			closes[i].Ticker = t
+		}
+
+		if err := u.store.SaveCloses(ctx, closes); err != nil {
+			return fmt.Errorf("failed save closes: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Returns are forward returns from the insider's price (Transaction.Cost)
+// to the close after 1, 5, 20 and 60 trading days.
+// Return is nil if there is no close for the horizon yet.
+type Returns struct {
+	Return1d  *float64 `json:"return_1d" db:"return_1d"`
+	Return5d  *float64 `json:"return_5d" db:"return_5d"`
+	Return20d *float64 `json:"return_20d" db:"return_20d"`
+	Return60d *float64 `json:"return_60d" db:"return_60d"`
+}
+
+type TransactionReturn struct {
+	TransactionID   string                  `json:"transaction_id" db:"id"`
+	Ticker          string                  `json:"ticker" db:"ticker"`
+	Owner           string                  `json:"owner" db:"owner"`
+	Relationship    string                  `json:"relationship" db:"relationship"`
+	Transaction     insider.TransactionType `json:"transaction" db:"transaction_type"`
+	TransactionDate time.Time               `json:"transaction_date" db:"transaction_date"`
+	Cost            float64                 `json:"cost" db:"cost"`
+	Returns
+}
+
+// TypeReturn is the average forward returns by transaction type.
+type TypeReturn struct {
+	Transaction      insider.TransactionType `json:"transaction" db:"transaction_type"`
+	TransactionCount int                     `json:"transaction_count" db:"transaction_count"`
+	Returns
+	// HitRate20d is the share of transactions where insider was right
+	// after 20 trading days: price went up after buy or down after sale.
+	HitRate20d *float64 `json:"hit_rate_20d" db:"hit_rate_20d"`
+}
+
+// InsiderReturn is the average forward returns of the insider.
+type InsiderReturn struct {
+	Owner string `json:"owner" db:"owner"`
+	TypeReturn
+}
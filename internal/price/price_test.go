@@ -0,0 +1,70 @@
+package price_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type provider struct {
+	symbols []string
+}
+
+func (p *provider) Closes(_ context.Context, sym string, _, to time.Time) ([]price.Close, error) {
+	p.symbols = append(p.symbols, sym)
+	return []price.Close{{Ticker: sym, Date: to.Truncate(24 * time.Hour), Close: 10}}, nil
+}
+
+func (p *provider) Source() symbol.Source {
+	return symbol.Yahoo
+}
+
+type store struct {
+	*memory.Store
+	closes []price.Close
+}
+
+func (s *store) SaveCloses(ctx context.Context, closes []price.Close) error {
+	s.closes = append(s.closes, closes...)
+	return s.Store.SaveCloses(ctx, closes)
+}
+
+func TestUpdater_Update(t *testing.T) {
+	ctx := context.Background()
+	s := &store{Store: memory.New()}
+	require.NoError(t, s.SaveSymbols(ctx, []symbol.Symbol{
+		// the vendor lists the ticker under the old name
+		{Source: symbol.Yahoo, Symbol: "FB", Ticker: "META", UpdatedAt: time.Now()},
+	}))
+
+	now := time.Now().UTC()
+	var txs insider.Transactions
+	for _, ticker := range []string{"META", "BRK.B"} {
+		txs = append(txs, insider.Transaction{
+			Ticker:          ticker,
+			Owner:           "Smith John",
+			TransactionDate: now.AddDate(0, 0, -2),
+			Transaction:     insider.Buy,
+			Shares:          100,
+			Value:           1000,
+			SEC:             insider.SEC{NotificationDate: now.AddDate(0, 0, -1)},
+		})
+	}
+	_, err := s.InsertTransactions(ctx, txs)
+	require.NoError(t, err)
+
+	p := &provider{}
+	require.NoError(t, price.NewUpdater(price.Config{}, p, s).Update(ctx))
+
+	assert.Equal(t, []string{"BRK-B", "FB"}, p.symbols, "requested by symbols of the provider")
+	require.Len(t, s.closes, 2)
+	assert.Equal(t, "BRK.B", s.closes[0].Ticker, "saved by canonical tickers")
+	assert.Equal(t, "META", s.closes[1].Ticker)
+}
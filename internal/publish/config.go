@@ -0,0 +1,54 @@
+package publish
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	// Attempts is the number of delivery attempts of the digest per run,
+	// pending digests are retried by the next run as well.
+	Attempts int
+	// Backoff is the delay before the second attempt,
+	// it grows linearly.
+	Backoff time.Duration
+	// Lease is how long the claimed digest is kept from other publishers,
+	// the digest of the crashed publisher is retried after it.
+	Lease time.Duration
+}
+
+func ParsePublishConfig() Config {
+	cfg := Config{
+		Attempts: 3,
+		Backoff:  5 * time.Second,
+		Lease:    10 * time.Minute,
+	}
+
+	if v := os.Getenv("PUBLISH_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil || attempts < 1 {
+			log.Fatal("env: PUBLISH_ATTEMPTS cannot convert")
+		}
+		cfg.Attempts = attempts
+	}
+
+	if v := os.Getenv("PUBLISH_BACKOFF"); v != "" {
+		backoff, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatal("env: PUBLISH_BACKOFF cannot convert")
+		}
+		cfg.Backoff = backoff
+	}
+
+	if v := os.Getenv("PUBLISH_LEASE"); v != "" {
+		lease, err := time.ParseDuration(v)
+		if err != nil || lease <= 0 {
+			log.Fatal("env: PUBLISH_LEASE cannot convert")
+		}
+		cfg.Lease = lease
+	}
+
+	return cfg
+}
@@ -0,0 +1,198 @@
+// Package publish delivers daily digests to sinks.
+//
+// The digest is enqueued in the same database transaction as
+// the ingested transactions, so the saved day is never left
+// without the report. Publisher drains pending digests and retries
+// failed ones; the digest is marked delivered by its idempotency key,
+// so it's delivered once per sink. Every message of the digest is
+// recorded as sent, so the retry sends only the rest.
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Digest is the report of the day for the sink.
+type Digest struct {
+	// Key is the idempotency key: day and sink.
+	Key         string     `json:"key" db:"key"`
+	Day         time.Time  `json:"day" db:"day"`
+	Sink        string     `json:"sink" db:"sink"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	LastError   string     `json:"last_error" db:"last_error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at" db:"delivered_at"`
+}
+
+func NewDigest(day time.Time, sink string) Digest {
+	return Digest{
+		Key:  fmt.Sprintf("%s/%s", day.Format(time.DateOnly), sink),
+		Day:  day,
+		Sink: sink,
+	}
+}
+
+type Storer interface {
+	// PendingDigests returns not delivered digests, the oldest first.
+	PendingDigests(ctx context.Context) ([]Digest, error)
+	// ClaimDigest leases the pending digest until the lease expires,
+	// so concurrent publishers never deliver it at the same time. It returns
+	// false if the digest is already delivered or leased by another publisher.
+	ClaimDigest(ctx context.Context, key string, lease time.Duration) (Digest, bool, error)
+	// DigestParts returns parts of the digest sent by earlier attempts.
+	DigestParts(ctx context.Context, key string) ([]string, error)
+	// SaveDigestPart records the sent part of the digest.
+	SaveDigestPart(ctx context.Context, key, part string) error
+	// ReleaseDigest records the attempt and releases the lease,
+	// the digest is marked delivered if err is nil.
+	ReleaseDigest(ctx context.Context, key string, err error) error
+}
+
+// Sink sends the report of the day, every message is the part
+// of the digest recorded in p, so retries skip sent messages.
+type Sink func(ctx context.Context, day time.Time, p *Progress) error
+
+// Progress keeps parts of the digest sent by the sink. The nil progress
+// sends every part, it's used outside of digests.
+type Progress struct {
+	key   string
+	store Storer
+	sent  map[string]struct{}
+}
+
+// Sent reports whether the part has been sent by an earlier attempt.
+func (p *Progress) Sent(part string) bool {
+	if p == nil {
+		return false
+	}
+
+	_, ok := p.sent[part]
+	return ok
+}
+
+// Done records the sent part.
+func (p *Progress) Done(ctx context.Context, part string) error {
+	if p == nil {
+		return nil
+	}
+
+	if err := p.store.SaveDigestPart(ctx, p.key, part); err != nil {
+		return fmt.Errorf("failed save part %s: %w", part, err)
+	}
+	p.sent[part] = struct{}{}
+
+	return nil
+}
+
+// Result is the result of the digest delivery.
+type Result struct {
+	Digest
+	Err error
+}
+
+type Publisher struct {
+	cfg   Config
+	store Storer
+	sinks map[string]Sink
+}
+
+func New(cfg Config, store Storer) *Publisher {
+	return &Publisher{
+		cfg:   cfg,
+		store: store,
+		sinks: make(map[string]Sink),
+	}
+}
+
+// Register adds the sink, digests of unknown sinks stay pending.
+func (p *Publisher) Register(name string, s Sink) {
+	p.sinks[name] = s
+}
+
+// Drain delivers all pending digests, every digest is tried
+// up to cfg.Attempts times. It returns results of the tried digests.
+func (p *Publisher) Drain(ctx context.Context) ([]Result, error) {
+	pending, err := p.store.PendingDigests(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed get pending digests: %w", err)
+	}
+
+	var (
+		results []Result
+		errs    []error
+	)
+	for _, d := range pending {
+		sink, ok := p.sinks[d.Sink]
+		if !ok {
+			slog.WarnContext(ctx, "skip digest of unregistered sink", "digest", d.Key, "sink", d.Sink)
+			continue
+		}
+
+		sctx, span := tracing.Start(ctx, "publish.digest",
+			attribute.String("digest", d.Key), attribute.String("sink", d.Sink))
+		err := p.deliver(sctx, d, sink)
+		tracing.End(span, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest %s: %w", d.Key, err))
+		}
+
+		results = append(results, Result{Digest: d, Err: err})
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func (p *Publisher) deliver(ctx context.Context, d Digest, sink Sink) error {
+	var err error
+	for attempt := 1; attempt <= p.cfg.Attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(p.cfg.Backoff * time.Duration(attempt-1)):
+			}
+		}
+
+		var claimed bool
+		claimed, err = p.attempt(ctx, d.Key, sink)
+		if err == nil || !claimed {
+			return err
+		}
+
+		slog.WarnContext(ctx, "digest delivery failed", "digest", d.Key, "attempt", attempt, "err", err)
+	}
+
+	return err
+}
+
+// attempt sends parts of the claimed digest that aren't sent yet, the sink
+// is called without the database transaction. It returns false if the digest
+// isn't claimed: it's delivered or leased by another publisher.
+func (p *Publisher) attempt(ctx context.Context, key string, sink Sink) (bool, error) {
+	d, ok, err := p.store.ClaimDigest(ctx, key, p.cfg.Lease)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	progress := &Progress{key: key, store: p.store, sent: make(map[string]struct{})}
+	parts, err := p.store.DigestParts(ctx, key)
+	if err == nil {
+		for _, part := range parts {
+			progress.sent[part] = struct{}{}
+		}
+		err = sink(ctx, d.Day, progress)
+	}
+
+	if rerr := p.store.ReleaseDigest(ctx, key, err); rerr != nil {
+		return true, errors.Join(err, rerr)
+	}
+
+	return true, err
+}
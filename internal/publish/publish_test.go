@@ -0,0 +1,151 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	digests []Digest
+	parts   map[string][]string
+}
+
+func (m *memStore) PendingDigests(context.Context) ([]Digest, error) {
+	var pending []Digest
+	for _, d := range m.digests {
+		if d.DeliveredAt == nil {
+			pending = append(pending, d)
+		}
+	}
+	return pending, nil
+}
+
+func (m *memStore) find(key string) *Digest {
+	for i := range m.digests {
+		if m.digests[i].Key == key {
+			return &m.digests[i]
+		}
+	}
+	return nil
+}
+
+func (m *memStore) ClaimDigest(_ context.Context, key string, _ time.Duration) (Digest, bool, error) {
+	d := m.find(key)
+	if d == nil || d.DeliveredAt != nil {
+		return Digest{}, false, nil
+	}
+	return *d, true, nil
+}
+
+func (m *memStore) DigestParts(_ context.Context, key string) ([]string, error) {
+	return m.parts[key], nil
+}
+
+func (m *memStore) SaveDigestPart(_ context.Context, key, part string) error {
+	if m.parts == nil {
+		m.parts = make(map[string][]string)
+	}
+	m.parts[key] = append(m.parts[key], part)
+	return nil
+}
+
+func (m *memStore) ReleaseDigest(_ context.Context, key string, err error) error {
+	d := m.find(key)
+	d.Attempts++
+	if err != nil {
+		d.LastError = err.Error()
+		return nil
+	}
+
+	now := time.Now()
+	d.DeliveredAt = &now
+	return nil
+}
+
+func TestPublisher_Drain(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	s := &memStore{digests: []Digest{
+		NewDigest(day, "telegram"),
+		NewDigest(day, "flaky"),
+		NewDigest(day, "broken"),
+		NewDigest(day, "unknown"),
+	}}
+
+	sent := map[string]int{}
+	flaky := 0
+
+	p := New(Config{Attempts: 3}, s)
+	p.Register("telegram", func(_ context.Context, d time.Time, _ *Progress) error {
+		assert.Equal(t, day, d)
+		sent["telegram"]++
+		return nil
+	})
+	p.Register("flaky", func(context.Context, time.Time, *Progress) error {
+		flaky++
+		if flaky < 3 {
+			return errors.New("timeout")
+		}
+		sent["flaky"]++
+		return nil
+	})
+	p.Register("broken", func(context.Context, time.Time, *Progress) error {
+		return errors.New("chat not found")
+	})
+
+	results, err := p.Drain(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2024-06-24/broken")
+	assert.Len(t, results, 3)
+
+	assert.Equal(t, 1, s.digests[0].Attempts)
+	assert.Equal(t, 3, s.digests[1].Attempts)
+	assert.NotNil(t, s.digests[1].DeliveredAt)
+	assert.Equal(t, 3, s.digests[2].Attempts)
+	assert.Equal(t, "chat not found", s.digests[2].LastError)
+	assert.Nil(t, s.digests[3].DeliveredAt)
+
+	// delivered digests are never sent again
+	_, err = p.Drain(ctx)
+	require.Error(t, err)
+	assert.Equal(t, map[string]int{"telegram": 1, "flaky": 1}, sent)
+}
+
+func TestPublisher_DrainSkipsSentParts(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+	s := &memStore{digests: []Digest{NewDigest(day, "telegram")}}
+
+	var sent []string
+	failed := false
+
+	p := New(Config{Attempts: 2}, s)
+	p.Register("telegram", func(ctx context.Context, _ time.Time, progress *Progress) error {
+		for _, part := range []string{"1/0", "1/1", "2/0"} {
+			if progress.Sent(part) {
+				continue
+			}
+			if part == "1/1" && !failed {
+				failed = true
+				return errors.New("timeout")
+			}
+			sent = append(sent, part)
+			if err := progress.Done(ctx, part); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	_, err := p.Drain(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1/0", "1/1", "2/0"}, sent, "the retry skips sent messages")
+	assert.Equal(t, 2, s.digests[0].Attempts)
+	assert.Equal(t, []string{"1/0", "1/1", "2/0"}, s.parts[s.digests[0].Key])
+}
@@ -0,0 +1,123 @@
+// Package quote parses the finviz quote page of the ticker.
+package quote
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/gocolly/colly/v2"
+)
+
+// ErrNoValue is returned when the snapshot value is missing ("-" on the page).
+var ErrNoValue = fmt.Errorf("no value")
+
+type Browser struct {
+	quoteURL string
+}
+
+func New() *Browser {
+	return &Browser{
+		quoteURL: "https://finviz.com/quote.ashx?t=%s",
+	}
+}
+
+// Page is the parsed quote page.
+type Page struct {
+	Ticker string
+	// Company is the company name from the header.
+	Company string
+	// Sector, Industry and Country are the screener links of the header.
+	Sector   string
+	Industry string
+	Country  string
+	// Snapshot is the table with fundamentals: "Market Cap", "Price", "Prev Close", ...
+	Snapshot map[string]string
+}
+
+// Page returns the quote page of the canonical ticker.
+func (b *Browser) Page(ticker string) (*Page, error) {
+	page := &Page{
+		Ticker:   ticker,
+		Snapshot: make(map[string]string),
+	}
+
+	c := colly.NewCollector()
+	c.OnHTML(".quote-header_ticker-wrapper_company", func(e *colly.HTMLElement) {
+		page.Company = strings.TrimSpace(e.Text)
+	})
+	c.OnHTML(".quote-links a[href*='screener.ashx']", func(e *colly.HTMLElement) {
+		// links are filters of the screener: f=sec_healthcare
+		href := e.Attr("href")
+		switch {
+		case strings.Contains(href, "f=sec_"):
+			page.Sector = strings.TrimSpace(e.Text)
+		case strings.Contains(href, "f=ind_"):
+			page.Industry = strings.TrimSpace(e.Text)
+		case strings.Contains(href, "f=geo_"):
+			page.Country = strings.TrimSpace(e.Text)
+		}
+	})
+	c.OnHTML("table.snapshot-table2 tr", func(e *colly.HTMLElement) {
+		// cells go in pairs: label, value
+		var cells []string
+		e.ForEach("td", func(_ int, td *colly.HTMLElement) {
+			cells = append(cells, strings.TrimSpace(td.Text))
+		})
+
+		for i := 0; i+1 < len(cells); i += 2 {
+			page.Snapshot[cells[i]] = cells[i+1]
+		}
+	})
+
+	if err := c.Visit(fmt.Sprintf(b.quoteURL, symbol.Format(ticker, symbol.Finviz))); err != nil {
+		return nil, fmt.Errorf("visit: %w", err)
+	}
+
+	if len(page.Snapshot) == 0 {
+		return nil, fmt.Errorf("snapshot not found for %s", ticker)
+	}
+
+	return page, nil
+}
+
+// Float returns the numeric snapshot value,
+// suffixes are expanded: 1.5B -> 1500000000, 12.5% -> 12.5
+func (p *Page) Float(key string) (float64, error) {
+	v, ok := p.Snapshot[key]
+	if !ok || v == "" || v == "-" {
+		return 0, fmt.Errorf("%s: %w", key, ErrNoValue)
+	}
+
+	v = strings.TrimSuffix(removeComma(v), "%")
+	if v == "" {
+		return 0, fmt.Errorf("%s: %w", key, ErrNoValue)
+	}
+
+	mult := 1.0
+	switch v[len(v)-1] {
+	case 'K':
+		mult = 1e3
+	case 'M':
+		mult = 1e6
+	case 'B':
+		mult = 1e9
+	case 'T':
+		mult = 1e12
+	}
+	if mult != 1 {
+		v = v[:len(v)-1]
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return f * mult, nil
+}
+
+func removeComma(s string) string {
+	return strings.ReplaceAll(s, ",", "")
+}
@@ -0,0 +1,59 @@
+package quote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowser_Page(t *testing.T) {
+	fileData, err := os.ReadFile("testdata/quote.html")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write(fileData)
+	}))
+	defer server.Close()
+
+	browser := New()
+	browser.quoteURL = server.URL + "?t=%s"
+
+	page, err := browser.Page("EXTX")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Example Therapeutics, Inc.", page.Company)
+	assert.Equal(t, "Healthcare", page.Sector)
+	assert.Equal(t, "Biotechnology", page.Industry)
+	assert.Equal(t, "USA", page.Country)
+
+	tests := []struct {
+		key     string
+		want    float64
+		wantErr bool
+	}{
+		{key: "Price", want: 24},
+		{key: "Prev Close", want: 23.82},
+		{key: "Market Cap", want: 1.26e9},
+		{key: "Shs Float", want: 48.1e6},
+		{key: "Perf Week", want: 2.13},
+		{key: "Volume", want: 760900},
+		{key: "P/E", wantErr: true},
+		{key: "Unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := page.Float(tt.key)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrNoValue)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-6)
+		})
+	}
+}
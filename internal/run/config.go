@@ -0,0 +1,41 @@
+package run
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	// Interval is the pause between runs of the daemon.
+	Interval time.Duration
+	// Backfill is the max number of days fetched by the run
+	// to fill the gap since the last fetched day.
+	Backfill int
+}
+
+func ParseRunConfig() Config {
+	cfg := Config{
+		Interval: time.Hour,
+		Backfill: 7,
+	}
+
+	if v := os.Getenv("DAEMON_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			log.Fatal("env: DAEMON_INTERVAL cannot convert")
+		}
+		cfg.Interval = interval
+	}
+
+	if v := os.Getenv("RUN_BACKFILL_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			log.Fatal("env: RUN_BACKFILL_DAYS cannot convert")
+		}
+		cfg.Backfill = days
+	}
+
+	return cfg
+}
@@ -0,0 +1,266 @@
+// Package run keeps the ledger of pipeline executions:
+// what was fetched, how many rows were saved and where
+// the reports were published.
+package run
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusSkipped is set when the day has already been fetched.
+	StatusSkipped Status = "skipped"
+)
+
+// Sinks where reports are published.
+const (
+	SinkAlerts      = "alerts"
+	SinkDigest      = "telegram"
+	SinkPerformance = "telegram_performance"
+	SinkEvents      = "events"
+)
+
+type Run struct {
+	ID         int64      `json:"id" db:"id"`
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt *time.Time `json:"finished_at" db:"finished_at"`
+	// Day is the day of notifications fetched by the run.
+	Day        time.Time `json:"day" db:"day"`
+	SourceURLs []string  `json:"source_urls" db:"source_urls"`
+	Scraped    int       `json:"rows_scraped" db:"rows_scraped"`
+	Kept       int       `json:"rows_kept" db:"rows_kept"`
+	Inserted   int       `json:"rows_inserted" db:"rows_inserted"`
+	Duplicates int       `json:"rows_duplicate" db:"rows_duplicate"`
+	Status     Status    `json:"status" db:"status"`
+	Error      string    `json:"error" db:"error"`
+	// Publish is the status of every sink.
+	Publish map[string]Status `json:"publish" db:"publish"`
+	// Sources is the health of every source of transactions.
+	Sources map[string]SourceHealth `json:"sources" db:"sources"`
+}
+
+// SourceHealth is the result of fetching transactions from the source.
+type SourceHealth struct {
+	Status Status `json:"status"`
+	// Rows is the number of fetched transactions.
+	Rows       int    `json:"rows"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (r Run) Duration() time.Duration {
+	if r.FinishedAt == nil {
+		return 0
+	}
+
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Ingest is sent to subscribers after transactions of the run are saved.
+type Ingest struct {
+	RunID int64 `json:"run_id"`
+	// From and To are the range of notification dates of inserted transactions,
+	// they are empty if nothing is inserted.
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	Scraped    int        `json:"rows_scraped"`
+	Kept       int        `json:"rows_kept"`
+	Inserted   int        `json:"rows_inserted"`
+	Duplicates int        `json:"rows_duplicate"`
+}
+
+type Storer interface {
+	// StartRun saves the new run and sets its ID.
+	StartRun(ctx context.Context, r *Run) error
+	SaveRun(ctx context.Context, r Run) error
+	// Fetched reports whether the day has been fetched by the succeeded run.
+	Fetched(ctx context.Context, day time.Time) (bool, error)
+	// LastFetched returns the latest day fetched by the succeeded run,
+	// zero if there is no such run.
+	LastFetched(ctx context.Context) (time.Time, error)
+	// Runs returns the latest runs, newest first.
+	Runs(ctx context.Context, limit int) ([]Run, error)
+	// NotifyIngest notifies subscribers about the saved transactions.
+	NotifyIngest(ctx context.Context, in Ingest) error
+}
+
+// Ledger records the progress of the current run.
+type Ledger struct {
+	store Storer
+	run   Run
+}
+
+// Start records the new run of the day.
+func Start(ctx context.Context, store Storer, day time.Time) (*Ledger, error) {
+	l := &Ledger{
+		store: store,
+		run: Run{
+			StartedAt:  time.Now(),
+			Day:        day,
+			SourceURLs: []string{},
+			Status:     StatusRunning,
+			Publish:    map[string]Status{},
+			Sources:    map[string]SourceHealth{},
+		},
+	}
+
+	if err := store.StartRun(ctx, &l.run); err != nil {
+		return nil, fmt.Errorf("failed start run: %w", err)
+	}
+
+	return l, nil
+}
+
+// Days returns days to fetch at now, oldest first: the days after the last
+// fetched one up to the last day, at most limit days back. The last day is
+// always returned, the run of the fetched day only delivers leftover digests,
+// and it's the only day until something is fetched.
+func Days(ctx context.Context, store Storer, now time.Time, limit int) ([]time.Time, error) {
+	day := insider.LastDay(now)
+
+	last, err := store.LastFetched(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed get last fetched: %w", err)
+	}
+
+	from := day
+	if !last.IsZero() {
+		y, m, d := last.Date()
+		if next := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1); next.Before(from) {
+			from = next
+		}
+	}
+	if oldest := day.AddDate(0, 0, 1-limit); limit > 0 && from.Before(oldest) {
+		from = oldest
+	}
+
+	return insider.Window{From: from, To: day.AddDate(0, 0, 1)}.Days(), nil
+}
+
+func (l *Ledger) Run() Run {
+	return l.run
+}
+
+// Fetched reports whether the day of the run has already been fetched.
+func (l *Ledger) Fetched(ctx context.Context) (bool, error) {
+	ok, err := l.store.Fetched(ctx, l.run.Day)
+	if err != nil {
+		return false, fmt.Errorf("failed check fetched: %w", err)
+	}
+
+	return ok, nil
+}
+
+// Scraped records the parsed pages.
+func (l *Ledger) Scraped(ctx context.Context, stats insider.FetchStats) error {
+	l.run.SourceURLs = stats.SourceURLs
+	l.run.Scraped = stats.Scraped
+	l.run.Kept = stats.Kept
+
+	return l.save(ctx)
+}
+
+// Inserted records new rows, the rest of kept rows are duplicates,
+// and notifies subscribers.
+func (l *Ledger) Inserted(ctx context.Context, tr insider.Transactions) error {
+	l.run.Inserted = len(tr)
+	l.run.Duplicates = l.run.Kept - len(tr)
+
+	if err := l.save(ctx); err != nil {
+		return err
+	}
+
+	in := Ingest{
+		RunID:      l.run.ID,
+		Scraped:    l.run.Scraped,
+		Kept:       l.run.Kept,
+		Inserted:   l.run.Inserted,
+		Duplicates: l.run.Duplicates,
+	}
+	for _, t := range tr {
+		d := t.NotificationDate
+		if in.From == nil || d.Before(*in.From) {
+			in.From = &d
+		}
+		if in.To == nil || d.After(*in.To) {
+			in.To = &d
+		}
+	}
+
+	if err := l.store.NotifyIngest(ctx, in); err != nil {
+		return fmt.Errorf("failed notify ingest: %w", err)
+	}
+
+	return nil
+}
+
+// Source records the result of fetching from the source.
+func (l *Ledger) Source(ctx context.Context, name string, rows int, d time.Duration, err error) error {
+	h := SourceHealth{
+		Status:     StatusSucceeded,
+		Rows:       rows,
+		DurationMs: d.Milliseconds(),
+	}
+	if err != nil {
+		h.Status = StatusFailed
+		h.Error = err.Error()
+	}
+	l.run.Sources[name] = h
+
+	return l.save(ctx)
+}
+
+// Published records the result of publishing to the sink.
+func (l *Ledger) Published(ctx context.Context, sink string, err error) error {
+	l.run.Publish[sink] = StatusSucceeded
+	if err != nil {
+		l.run.Publish[sink] = StatusFailed
+	}
+
+	return l.save(ctx)
+}
+
+// Skip finishes the run without doing anything.
+func (l *Ledger) Skip(ctx context.Context) error {
+	return l.finish(ctx, StatusSkipped, "")
+}
+
+// Finish finishes the run, it's failed if err is not nil.
+// The skipped run is left as is.
+func (l *Ledger) Finish(ctx context.Context, err error) error {
+	if l.run.Status == StatusSkipped && err == nil {
+		return nil
+	}
+
+	if err != nil {
+		return l.finish(ctx, StatusFailed, err.Error())
+	}
+
+	return l.finish(ctx, StatusSucceeded, "")
+}
+
+func (l *Ledger) finish(ctx context.Context, status Status, errText string) error {
+	now := time.Now()
+	l.run.FinishedAt = &now
+	l.run.Status = status
+	l.run.Error = errText
+
+	return l.save(ctx)
+}
+
+func (l *Ledger) save(ctx context.Context) error {
+	if err := l.store.SaveRun(ctx, l.run); err != nil {
+		return fmt.Errorf("failed save run: %w", err)
+	}
+
+	return nil
+}
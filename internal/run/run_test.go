@@ -0,0 +1,162 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	runs     map[int64]Run
+	fetched  bool
+	last     time.Time
+	ingested []Ingest
+}
+
+func (m *memStore) StartRun(_ context.Context, r *Run) error {
+	r.ID = int64(len(m.runs) + 1)
+	m.runs[r.ID] = *r
+	return nil
+}
+
+func (m *memStore) SaveRun(_ context.Context, r Run) error {
+	m.runs[r.ID] = r
+	return nil
+}
+
+func (m *memStore) Fetched(context.Context, time.Time) (bool, error) {
+	return m.fetched, nil
+}
+
+func (m *memStore) LastFetched(context.Context) (time.Time, error) {
+	return m.last, nil
+}
+
+func (m *memStore) Runs(context.Context, int) ([]Run, error) {
+	return nil, nil
+}
+
+func (m *memStore) NotifyIngest(_ context.Context, in Ingest) error {
+	m.ingested = append(m.ingested, in)
+	return nil
+}
+
+func TestLedger(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	t.Run("succeeded", func(t *testing.T) {
+		s := &memStore{runs: map[int64]Run{}}
+		l, err := Start(ctx, s, day)
+		require.NoError(t, err)
+
+		require.NoError(t, l.Scraped(ctx, insider.FetchStats{SourceURLs: []string{"a", "b"}, Scraped: 200, Kept: 12}))
+		first := time.Date(2024, 6, 24, 9, 0, 0, 0, time.UTC)
+		last := time.Date(2024, 6, 24, 21, 30, 0, 0, time.UTC)
+		tr := make(insider.Transactions, 9)
+		tr[3].NotificationDate = last
+		for i := range tr {
+			if tr[i].NotificationDate.IsZero() {
+				tr[i].NotificationDate = first
+			}
+		}
+		require.NoError(t, l.Inserted(ctx, tr))
+		require.NoError(t, l.Published(ctx, SinkAlerts, nil))
+		require.NoError(t, l.Published(ctx, SinkDigest, errors.New("bot is down")))
+		require.NoError(t, l.Finish(ctx, nil))
+
+		r := s.runs[1]
+		assert.Equal(t, day, r.Day)
+		assert.Equal(t, []string{"a", "b"}, r.SourceURLs)
+		assert.Equal(t, 200, r.Scraped)
+		assert.Equal(t, 12, r.Kept)
+		assert.Equal(t, 9, r.Inserted)
+		assert.Equal(t, 3, r.Duplicates)
+		assert.Equal(t, StatusSucceeded, r.Status)
+		assert.Equal(t, map[string]Status{SinkAlerts: StatusSucceeded, SinkDigest: StatusFailed}, r.Publish)
+		assert.NotNil(t, r.FinishedAt)
+
+		require.Len(t, s.ingested, 1)
+		assert.Equal(t, Ingest{RunID: 1, From: &first, To: &last, Scraped: 200, Kept: 12, Inserted: 9, Duplicates: 3}, s.ingested[0])
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		s := &memStore{runs: map[int64]Run{}}
+		l, err := Start(ctx, s, day)
+		require.NoError(t, err)
+
+		require.NoError(t, l.Finish(ctx, errors.New("visit: timeout")))
+		assert.Equal(t, StatusFailed, s.runs[1].Status)
+		assert.Equal(t, "visit: timeout", s.runs[1].Error)
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		s := &memStore{runs: map[int64]Run{}, fetched: true}
+		l, err := Start(ctx, s, day)
+		require.NoError(t, err)
+
+		fetched, err := l.Fetched(ctx)
+		require.NoError(t, err)
+		assert.True(t, fetched)
+
+		require.NoError(t, l.Skip(ctx))
+		require.NoError(t, l.Finish(ctx, nil))
+		assert.Equal(t, StatusSkipped, s.runs[1].Status)
+	})
+}
+
+func TestDays(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 25, 8, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		last  time.Time
+		limit int
+		want  []time.Time
+	}{
+		{
+			name:  "nothing fetched",
+			limit: 7,
+			want:  []time.Time{day},
+		},
+		{
+			name:  "last day fetched",
+			last:  day,
+			limit: 7,
+			want:  []time.Time{day},
+		},
+		{
+			name:  "previous day fetched",
+			last:  day.AddDate(0, 0, -1),
+			limit: 7,
+			want:  []time.Time{day},
+		},
+		{
+			name:  "gap",
+			last:  day.AddDate(0, 0, -3),
+			limit: 7,
+			want:  []time.Time{day.AddDate(0, 0, -2), day.AddDate(0, 0, -1), day},
+		},
+		{
+			name:  "gap over the limit",
+			last:  day.AddDate(0, 0, -30),
+			limit: 2,
+			want:  []time.Time{day.AddDate(0, 0, -1), day},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Days(ctx, &memStore{last: tt.last}, now, tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package score
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	// Horizons in trading days, up to 60 days
+	// as prices are collected for 90 calendar days.
+	Horizons []int
+	// Benchmark is the ticker excess returns are calculated against.
+	Benchmark string
+}
+
+// ParseScoreConfig returns config from the environment,
+// SCORE_HORIZONS is a comma separated list: 5,20,60.
+func ParseScoreConfig(benchmark string) Config {
+	horizons := []int{20, 60}
+	if v := os.Getenv("SCORE_HORIZONS"); v != "" {
+		horizons = horizons[:0]
+		for _, h := range strings.Split(v, ",") {
+			d, err := strconv.Atoi(strings.TrimSpace(h))
+			if err != nil || d <= 0 {
+				log.Fatal("env: SCORE_HORIZONS cannot convert")
+			}
+			horizons = append(horizons, d)
+		}
+	}
+
+	return Config{
+		Horizons:  horizons,
+		Benchmark: benchmark,
+	}
+}
@@ -0,0 +1,158 @@
+// Package score ranks insiders by how well their past buys performed
+// relative to the benchmark.
+package score
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Kind string
+
+const (
+	// KindInsider is the score of the insider, the name is the owner's CIK
+	// or the owner's name if the CIK is unknown.
+	KindInsider Kind = "insider"
+	// KindRole is the score of the relationship to the company: CEO, Director, ...
+	KindRole Kind = "role"
+)
+
+// BuyReturn is the forward return of the buy transaction
+// and the benchmark return for the same period.
+type BuyReturn struct {
+	TransactionID   string  `json:"transaction_id" db:"id"`
+	Owner           string  `json:"owner" db:"owner"`
+	OwnerCIK        string  `json:"owner_cik" db:"owner_cik"`
+	Relationship    string  `json:"relationship" db:"relationship"`
+	Return          float64 `json:"return" db:"return"`
+	BenchmarkReturn float64 `json:"benchmark_return" db:"benchmark_return"`
+}
+
+// OwnerID identifies the insider like insider.Transaction.OwnerID.
+func (r BuyReturn) OwnerID() string {
+	if r.OwnerCIK != "" {
+		return r.OwnerCIK
+	}
+
+	return r.Owner
+}
+
+// Excess is the return over the benchmark.
+func (r BuyReturn) Excess() float64 {
+	return r.Return - r.BenchmarkReturn
+}
+
+// Score is the track record of the insider or the role over the horizon.
+type Score struct {
+	Kind Kind   `json:"kind" db:"kind"`
+	Name string `json:"name" db:"name"`
+	// Horizon in trading days.
+	Horizon      int `json:"horizon" db:"horizon"`
+	Transactions int `json:"transactions" db:"transactions"`
+	// HitRate is the share of buys that beat the benchmark.
+	HitRate         float64   `json:"hit_rate" db:"hit_rate"`
+	AvgExcessReturn float64   `json:"avg_excess_return" db:"avg_excess_return"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TransactionScore is the score of the insider who made the transaction.
+type TransactionScore struct {
+	TransactionID string `json:"transaction_id" db:"id"`
+	Ticker        string `json:"ticker" db:"ticker"`
+	Owner         string `json:"owner" db:"owner"`
+	Relationship  string `json:"relationship" db:"relationship"`
+	Value         int    `json:"value" db:"value"`
+	Score
+}
+
+type Storer interface {
+	BuyReturns(ctx context.Context, horizon int, benchmark string) ([]BuyReturn, error)
+	SaveScores(ctx context.Context, scores []Score) error
+}
+
+type Scorer struct {
+	store Storer
+	cfg   Config
+}
+
+func New(cfg Config, store Storer) *Scorer {
+	return &Scorer{
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// Update recalculates scores for all horizons.
+func (s *Scorer) Update(ctx context.Context) error {
+	for _, h := range s.cfg.Horizons {
+		rr, err := s.store.BuyReturns(ctx, h, s.cfg.Benchmark)
+		if err != nil {
+			return fmt.Errorf("failed get buy returns for %d days: %w", h, err)
+		}
+
+		if err := s.store.SaveScores(ctx, Compute(rr, h)); err != nil {
+			return fmt.Errorf("failed save scores for %d days: %w", h, err)
+		}
+	}
+
+	return nil
+}
+
+// Compute calculates scores of insiders and roles.
+func Compute(rr []BuyReturn, horizon int) []Score {
+	type key struct {
+		kind Kind
+		name string
+	}
+
+	type acc struct {
+		n      int
+		hits   int
+		excess float64
+	}
+
+	groups := make(map[key]*acc)
+	add := func(k key, r BuyReturn) {
+		a, ok := groups[k]
+		if !ok {
+			a = &acc{}
+			groups[k] = a
+		}
+
+		a.n++
+		a.excess += r.Excess()
+		if r.Excess() > 0 {
+			a.hits++
+		}
+	}
+
+	for _, r := range rr {
+		add(key{kind: KindInsider, name: r.OwnerID()}, r)
+		add(key{kind: KindRole, name: r.Relationship}, r)
+	}
+
+	now := time.Now().UTC()
+	scores := make([]Score, 0, len(groups))
+	for k, a := range groups {
+		scores = append(scores, Score{
+			Kind:            k.kind,
+			Name:            k.name,
+			Horizon:         horizon,
+			Transactions:    a.n,
+			HitRate:         float64(a.hits) / float64(a.n),
+			AvgExcessReturn: a.excess / float64(a.n),
+			UpdatedAt:       now,
+		})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Kind != scores[j].Kind {
+			return scores[i].Kind < scores[j].Kind
+		}
+		return scores[i].Name < scores[j].Name
+	})
+
+	return scores
+}
@@ -0,0 +1,52 @@
+package score
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	rr := []BuyReturn{
+		{Owner: "Doe John", Relationship: "CEO", Return: 0.10, BenchmarkReturn: 0.02},
+		{Owner: "Doe John", Relationship: "CEO", Return: -0.05, BenchmarkReturn: 0.01},
+		{Owner: "Smith Alice", OwnerCIK: "1234567", Relationship: "Director", Return: 0.03, BenchmarkReturn: -0.01},
+		// the name variation of the same insider
+		{Owner: "SMITH ALICE B", OwnerCIK: "1234567", Relationship: "Director", Return: 0.01, BenchmarkReturn: 0.02},
+		{Owner: "Roe Jane", Relationship: "CEO", Return: 0.04, BenchmarkReturn: 0.01},
+	}
+
+	tests := []struct {
+		kind         Kind
+		name         string
+		transactions int
+		hitRate      float64
+		avgExcess    float64
+	}{
+		{kind: KindInsider, name: "Doe John", transactions: 2, hitRate: 0.5, avgExcess: 0.01},
+		{kind: KindInsider, name: "1234567", transactions: 2, hitRate: 0.5, avgExcess: 0.015},
+		{kind: KindRole, name: "CEO", transactions: 3, hitRate: 2.0 / 3, avgExcess: (0.08 - 0.06 + 0.03) / 3},
+		{kind: KindRole, name: "Director", transactions: 2, hitRate: 0.5, avgExcess: 0.015},
+	}
+
+	scores := Compute(rr, 20)
+	assert.Len(t, scores, 5)
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind)+" "+tt.name, func(t *testing.T) {
+			var got *Score
+			for i := range scores {
+				if scores[i].Kind == tt.kind && scores[i].Name == tt.name {
+					got = &scores[i]
+				}
+			}
+
+			if assert.NotNil(t, got) {
+				assert.Equal(t, 20, got.Horizon)
+				assert.Equal(t, tt.transactions, got.Transactions)
+				assert.InDelta(t, tt.hitRate, got.HitRate, 1e-9)
+				assert.InDelta(t, tt.avgExcess, got.AvgExcessReturn, 1e-9)
+			}
+		})
+	}
+}
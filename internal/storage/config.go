@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+type Config struct {
+	// DSN selects the backend, see Open.
+	DSN string
+	// AutoMigrate applies migrations on start.
+	AutoMigrate bool
+}
+
+func ParseStorageConfig() Config {
+	cfg := Config{
+		DSN: os.Getenv("DATABASE_URL"),
+	}
+
+	if v := os.Getenv("AUTO_MIGRATE"); v != "" {
+		autoMigrate, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatal("env: AUTO_MIGRATE cannot convert")
+		}
+		cfg.AutoMigrate = autoMigrate
+	}
+
+	return cfg
+}
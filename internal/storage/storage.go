@@ -0,0 +1,95 @@
+// Package storage opens the storage backend selected by the DSN.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/edgar"
+	"github.com/RyabovNick/finviz_parser/internal/event"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/metrics"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+	"github.com/RyabovNick/finviz_parser/internal/store"
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	"github.com/RyabovNick/finviz_parser/internal/store/sqlite"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/RyabovNick/finviz_parser/internal/telegram"
+	"github.com/RyabovNick/finviz_parser/migrations"
+)
+
+// Storage is implemented by every backend.
+type Storage interface {
+	insider.Storer
+	telegram.Storer
+	edgar.Storer
+	price.Storer
+	score.Storer
+	alert.Storer
+	run.Storer
+	publish.Storer
+	event.Storer
+	company.Storer
+	symbol.Storer
+	metrics.Storer
+
+	RelationshipCount(ctx context.Context, f insider.ReportFilter) ([]insider.RelationshipCount, error)
+	TransactionReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.TransactionReturn, error)
+	Scores(ctx context.Context, kind score.Kind, horizon int) ([]score.Score, error)
+	DeleteRule(ctx context.Context, name string) error
+	Firings(ctx context.Context, rule string, limit int) ([]alert.Firing, error)
+
+	// Subscribe returns ingest notifications until ctx is done.
+	Subscribe(ctx context.Context) (<-chan run.Ingest, error)
+	Migrator() (migrations.Migrator, error)
+	Close()
+}
+
+var (
+	_ Storage = (*store.Store)(nil)
+	_ Storage = (*sqlite.Store)(nil)
+	_ Storage = (*memory.Store)(nil)
+)
+
+// Open connects to the backend by the DSN scheme:
+// postgres:// or postgresql:// for PostgreSQL, sqlite://path for the SQLite file,
+// memory:// for the in-memory store that is lost on exit.
+// PostgreSQL options are read from PG_* variables when the DSN is empty.
+func Open(ctx context.Context, dsn string) (Storage, error) {
+	if dsn == "" {
+		return open(store.New(ctx, store.ParseOptions()))
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse dsn: %w", err)
+	}
+
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		return open(store.Connect(ctx, dsn))
+	case "sqlite":
+		return open(sqlite.New(ctx, strings.TrimPrefix(dsn, "sqlite://")))
+	case "memory":
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage scheme %q", u.Scheme)
+	}
+}
+
+// open keeps the nil backend from becoming the non-nil Storage.
+func open[T Storage](s T, err error) (Storage, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,999 @@
+// Package storagetest is the conformance suite of storage backends,
+// every backend has to pass it to be interchangeable.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/event"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+	"github.com/RyabovNick/finviz_parser/internal/storage"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Open returns the empty storage, it's called by every test.
+type Open func(t *testing.T) storage.Storage
+
+// Run runs the suite against the backend.
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, db storage.Storage)
+	}{
+		{"InsertTransactions", testInsertTransactions},
+		{"CrossSource", testCrossSource},
+		{"NewestNotification", testNewestNotification},
+		{"History", testHistory},
+		{"Identity", testIdentity},
+		{"Reports", testReports},
+		{"Planned", testPlanned},
+		{"Returns", testReturns},
+		{"Scores", testScores},
+		{"Alerts", testAlerts},
+		{"Runs", testRuns},
+		{"Digests", testDigests},
+		{"Events", testEvents},
+		{"Subscribe", testSubscribe},
+		{"Companies", testCompanies},
+		{"Significance", testSignificance},
+		{"Symbols", testSymbols},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// reportDay is yesterday, so transactions are recent enough to be enriched.
+func reportDay() time.Time {
+	return insider.LastDay(time.Now())
+}
+
+// transaction returns the transaction notified in the evening of the day.
+func transaction(day time.Time, ticker, owner string, typ insider.TransactionType, value int) insider.Transaction {
+	return insider.Transaction{
+		Ticker:          ticker,
+		Owner:           owner,
+		Relationship:    "CEO",
+		TransactionDate: day.AddDate(0, 0, -2),
+		Transaction:     typ,
+		Cost:            10,
+		Shares:          value / 10,
+		Value:           value,
+		SharesTotal:     100_000,
+		SEC: insider.SEC{
+			NotificationDate: day.Add(21 * time.Hour),
+			URL:              "https://www.sec.gov/Archives/edgar/data/1/000000000024000001/form4.xml",
+		},
+	}
+}
+
+func insert(t *testing.T, db storage.Storage, tr ...insider.Transaction) insider.Transactions {
+	t.Helper()
+
+	fresh, err := db.InsertTransactions(context.Background(), tr)
+	require.NoError(t, err)
+	require.Len(t, fresh, len(tr))
+
+	return fresh
+}
+
+func testInsertTransactions(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+	digest := publish.NewDigest(day, run.SinkDigest)
+
+	a := transaction(day, "AAA", "Smith John", insider.Buy, 1000)
+	b := transaction(day, "BBB", "Doe Jane", insider.Sale, 2000)
+	c := transaction(day, "CCC", "Doe Jane", insider.Sale, 3000)
+
+	fresh, err := db.InsertTransactions(ctx, insider.Transactions{a, b, a}, digest)
+	require.NoError(t, err)
+	require.Len(t, fresh, 2, "duplicates of the page are skipped")
+	assert.NotEmpty(t, fresh[0].ID)
+	assert.NotEqual(t, fresh[0].ID, fresh[1].ID)
+	assert.Equal(t, "AAA", fresh[0].Ticker)
+
+	stored, err := db.UnenrichedTransactions(ctx)
+	require.NoError(t, err)
+	tickers := make(map[string]string, len(stored))
+	for _, s := range stored {
+		tickers[s.ID] = s.Ticker
+	}
+	for _, f := range fresh {
+		assert.Equal(t, f.Ticker, tickers[f.ID], "the id of the stored row")
+	}
+
+	fresh, err = db.InsertTransactions(ctx, insider.Transactions{a, c}, digest)
+	require.NoError(t, err)
+	require.Len(t, fresh, 1, "stored transactions are skipped")
+	assert.Equal(t, "CCC", fresh[0].Ticker)
+
+	fresh, err = db.InsertTransactions(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, fresh)
+
+	events, err := db.PendingEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3, "event of every inserted transaction")
+	for _, e := range events {
+		assert.Equal(t, event.TypeTransactionCreated, e.Type)
+		assert.NotEmpty(t, e.Data)
+	}
+
+	digests, err := db.PendingDigests(ctx)
+	require.NoError(t, err)
+	require.Len(t, digests, 1, "the digest is enqueued once")
+	assert.Equal(t, digest.Key, digests[0].Key)
+	assert.Equal(t, run.SinkDigest, digests[0].Sink)
+	assert.Zero(t, digests[0].Attempts)
+	assert.Nil(t, digests[0].DeliveredAt)
+}
+
+func testCrossSource(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+
+	finviz := transaction(day, "AAA", "Smith John", insider.Sale, 1000)
+	finviz.URL = "http://www.sec.gov/Archives/edgar/data/1/000000000024000002/xslF345X05/ownership.xml"
+	insert(t, db, finviz)
+
+	// EDGAR spells the owner and rounds the value differently,
+	// the filing is notified on the day without the time
+	edgar := finviz
+	edgar.Owner = "SMITH JOHN A"
+	edgar.Value = 1004
+	edgar.NotificationDate = day
+	edgar.URL = "https://www.sec.gov/Archives/edgar/data/1/000000000024000002/0000000000-24-000002.txt"
+	edgar.IssuerCIK = "1"
+	edgar.OwnerCIK = "2"
+	other := transaction(day, "BBB", "Doe Jane", insider.Buy, 2000)
+	other.URL = "https://www.sec.gov/Archives/edgar/data/3/000000000024000003/0000000000-24-000003.txt"
+	other.IssuerCIK = "3"
+	other.OwnerCIK = "4"
+
+	fresh, err := db.InsertTransactions(ctx, insider.Transactions{edgar, other})
+	require.NoError(t, err)
+	require.Len(t, fresh, 1, "the filing from another source is skipped")
+	assert.Equal(t, "BBB", fresh[0].Ticker)
+
+	tr, err := db.UnenrichedTransactions(ctx)
+	require.NoError(t, err)
+	require.Len(t, tr, 2)
+	for _, tx := range tr {
+		if tx.Ticker == "BBB" {
+			assert.Equal(t, "3", tx.IssuerCIK)
+			assert.Equal(t, "4", tx.OwnerCIK)
+		}
+	}
+}
+
+func testNewestNotification(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+
+	newest, err := db.NewestNotification(ctx)
+	require.NoError(t, err)
+	assert.True(t, newest.IsZero(), "no transactions")
+
+	insert(t, db,
+		transaction(day, "AAA", "Smith John", insider.Buy, 1000),
+		transaction(day.AddDate(0, 0, -3), "BBB", "Smith John", insider.Buy, 1000),
+	)
+
+	newest, err = db.NewestNotification(ctx)
+	require.NoError(t, err)
+	assert.True(t, day.Add(21*time.Hour).Equal(newest), newest)
+}
+
+func testHistory(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+
+	past := transaction(day.AddDate(0, 0, -30), "AAA", "Smith John", insider.Buy, 100)
+	insert(t, db,
+		past,
+		transaction(day.AddDate(0, 0, -20), "BBB", "Smith John", insider.Buy, 300),
+		transaction(day.AddDate(0, 0, -10), "BBB", "Smith John", insider.Buy, 200),
+		transaction(day.AddDate(0, 0, -10), "BBB", "Smith John", insider.Sale, 5000),
+		transaction(day.AddDate(0, 0, -10), "AAA", "Doe Jane", insider.Buy, 7000),
+	)
+
+	history, err := db.History(ctx, insider.Transactions{
+		transaction(day, "AAA", "Smith John", insider.Buy, 400),
+		transaction(day, "AAA", "Smith John", insider.Buy, 500),
+		transaction(day, "CCC", "New Owner", insider.Sale, 400),
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 2, "one row by owner, ticker and type")
+
+	sort.Slice(history, func(i, j int) bool { return history[i].Owner < history[j].Owner })
+
+	h := history[1]
+	assert.Equal(t, "Smith John", h.Owner)
+	assert.Equal(t, "AAA", h.Ticker)
+	assert.Equal(t, insider.Buy, h.Transaction)
+	assert.Equal(t, 3, h.Transactions, "buys of all tickers")
+	require.NotNil(t, h.MedianValue)
+	assert.InDelta(t, 200, *h.MedianValue, 1e-9)
+	require.NotNil(t, h.LastBuy)
+	assert.True(t, past.TransactionDate.Equal(*h.LastBuy), h.LastBuy)
+
+	h = history[0]
+	assert.Equal(t, "New Owner", h.Owner)
+	assert.Zero(t, h.Transactions)
+	assert.Nil(t, h.MedianValue)
+	assert.Nil(t, h.LastBuy)
+}
+
+// testIdentity checks that CIKs of filings join the history
+// of name variations and ticker changes.
+func testIdentity(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+
+	old := transaction(day.AddDate(0, 0, -3), "FB", "Smith John", insider.Buy, 100)
+	renamed := transaction(day.AddDate(0, 0, -2), "META", "Smith John A", insider.Buy, 300)
+	tr := insert(t, db,
+		old,
+		renamed,
+		transaction(day.AddDate(0, 0, -1), "FB", "Smith John", insider.Sale, 200),
+		transaction(day.AddDate(0, 0, -1), "META", "Other Owner", insider.Buy, 700),
+		// the namesake at another company
+		transaction(day.AddDate(0, 0, -1), "ZZZ", "Smith John", insider.Buy, 9000),
+	)
+	require.Len(t, tr, 5)
+
+	byOwner := func(owner string, typ insider.TransactionType) insider.Transaction {
+		for _, t := range tr {
+			if t.Owner == owner && t.Transaction == typ && t.Ticker != "ZZZ" {
+				return t
+			}
+		}
+		return insider.Transaction{}
+	}
+
+	require.NoError(t, db.SaveFiling(ctx, byOwner("Smith John", insider.Buy).ID, insider.Filing{
+		AccessionNumber: "0000000000-24-000001",
+		IssuerCIK:       "1326801",
+		OwnerCIK:        "1111111",
+	}))
+	require.NoError(t, db.SaveFiling(ctx, byOwner("Smith John A", insider.Buy).ID, insider.Filing{
+		AccessionNumber: "0000000000-24-000002",
+		IssuerCIK:       "1326801",
+		OwnerCIK:        "1111111",
+	}))
+
+	unenriched, err := db.UnenrichedTransactions(ctx)
+	require.NoError(t, err)
+	require.Len(t, unenriched, 3)
+	for _, u := range unenriched {
+		switch {
+		case u.Ticker == "ZZZ":
+			assert.Empty(t, u.OwnerCIK, "owner cik of the namesake at another company")
+			assert.Empty(t, u.IssuerCIK)
+		case u.Owner == "Smith John":
+			assert.Equal(t, "1111111", u.OwnerCIK, "owner cik of the same name and issuer")
+			assert.Equal(t, "1326801", u.IssuerCIK, "issuer cik of the same ticker")
+		case u.Owner == "Other Owner":
+			assert.Empty(t, u.OwnerCIK)
+			assert.Equal(t, "1326801", u.IssuerCIK)
+		}
+	}
+
+	history, err := db.History(ctx, insider.Transactions{
+		transaction(day, "META", "Smith John", insider.Buy, 400),
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	h := history[0]
+	assert.Equal(t, 2, h.Transactions, "buys of both names without the namesake")
+	require.NotNil(t, h.MedianValue)
+	assert.InDelta(t, 200, *h.MedianValue, 1e-9)
+	require.NotNil(t, h.LastBuy)
+	assert.True(t, renamed.TransactionDate.Equal(*h.LastBuy), h.LastBuy)
+
+	history, err = db.History(ctx, insider.Transactions{
+		transaction(day, "FB", "Smith John A", insider.Buy, 400),
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.NotNil(t, history[0].LastBuy)
+	assert.True(t, renamed.TransactionDate.Equal(*history[0].LastBuy), "last buy of the renamed ticker")
+
+	history, err = db.History(ctx, insider.Transactions{
+		transaction(day, "ZZZ", "Smith John", insider.Buy, 400),
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 2, history[0].Transactions, "the unknown owner is matched by the name")
+
+	namesake := transaction(day, "ZZZ", "Smith John", insider.Buy, 400)
+	namesake.OwnerCIK = "2222222"
+	history, err = db.History(ctx, insider.Transactions{namesake})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Zero(t, history[0].Transactions, "the known owner is matched by the cik only")
+}
+
+func testReports(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+	f := insider.ReportFilter{Day: day, ExcludePlanned: true}
+
+	unusual := transaction(day, "CCC", "Smith John", insider.Buy, 200)
+	unusual.Flags = []string{"first buy"}
+
+	insert(t, db,
+		transaction(day, "AAA", "Smith John", insider.Buy, 1000),
+		transaction(day, "AAA", "Doe Jane", insider.Sale, 400),
+		transaction(day, "BBB", "Doe Jane", insider.Sale, 700),
+		unusual,
+		// other days are not reported
+		transaction(day.AddDate(0, 0, -1), "DDD", "Smith John", insider.Buy, 5000),
+		transaction(day.AddDate(0, 0, 1), "DDD", "Smith John", insider.Sale, 5000),
+	)
+
+	tc, err := db.TransactionTypeCount(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, []insider.TransactionTypeCount{
+		{Transaction: insider.Buy, TransactionCount: 2, TotalValue: 1200},
+		{Transaction: insider.Sale, TransactionCount: 2, TotalValue: 1100},
+	}, tc)
+
+	dt, err := db.DailyTotals(ctx, day.AddDate(0, 0, -1), f)
+	require.NoError(t, err)
+	require.Len(t, dt, 3, "days from since to the day of the filter")
+	for i, want := range []struct {
+		day time.Time
+		insider.TransactionTypeCount
+	}{
+		{day.AddDate(0, 0, -1), insider.TransactionTypeCount{Transaction: insider.Buy, TransactionCount: 1, TotalValue: 5000}},
+		{day, insider.TransactionTypeCount{Transaction: insider.Buy, TransactionCount: 2, TotalValue: 1200}},
+		{day, insider.TransactionTypeCount{Transaction: insider.Sale, TransactionCount: 2, TotalValue: 1100}},
+	} {
+		assert.Equal(t, want.day.Format(time.DateOnly), dt[i].Day.Format(time.DateOnly))
+		assert.Equal(t, want.TransactionTypeCount, dt[i].TransactionTypeCount)
+	}
+
+	rc, err := db.RelationshipCount(ctx, f)
+	require.NoError(t, err)
+	require.Len(t, rc, 2)
+	assert.Equal(t, insider.Buy, rc[0].Transaction, "the largest total goes first")
+	assert.InDelta(t, 1200, rc[0].TotalValue, 1e-9)
+
+	// net value is buys minus sales of the ticker
+	top, err := db.TopBuy(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, []insider.TotalTransaction{
+		{Ticker: "AAA", TotalValue: 600},
+		{Ticker: "CCC", TotalValue: 200},
+		{Ticker: "BBB", TotalValue: -700},
+	}, top)
+
+	top, err = db.TopSell(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, []insider.TotalTransaction{
+		{Ticker: "BBB", TotalValue: -700},
+		{Ticker: "CCC", TotalValue: 200},
+		{Ticker: "AAA", TotalValue: 600},
+	}, top)
+
+	tickers, err := db.BuyTicker(ctx, f)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, insider.Tickers{"AAA", "CCC"}, tickers)
+
+	tickers, err = db.SaleTicker(ctx, f)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, insider.Tickers{"AAA", "BBB"}, tickers)
+
+	tr, err := db.UnusualTransactions(ctx, f)
+	require.NoError(t, err)
+	require.Len(t, tr, 1)
+	assert.Equal(t, "CCC", tr[0].Ticker)
+	assert.Equal(t, []string{"first buy"}, tr[0].Flags)
+
+	tickers, err = db.TradedTickers(ctx, day.AddDate(0, 0, -2))
+	require.NoError(t, err)
+	assert.Equal(t, insider.Tickers{"AAA", "BBB", "CCC", "DDD"}, tickers, "sorted by ticker")
+
+	tickers, err = db.TradedTickers(ctx, day.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Equal(t, insider.Tickers{"DDD"}, tickers, "by the transaction date")
+}
+
+func testPlanned(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+
+	tr := insert(t, db,
+		transaction(day, "AAA", "Smith John", insider.Sale, 1000),
+		transaction(day, "BBB", "Doe Jane", insider.Sale, 700),
+	)
+
+	unenriched, err := db.UnenrichedTransactions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, unenriched, 2)
+
+	err = db.SaveFiling(ctx, tr[1].ID, insider.Filing{
+		AccessionNumber: "0000000000-24-000001",
+		TransactionCode: insider.CodeSale,
+		Plan10b51:       true,
+		Footnotes:       []string{"The sale was effected pursuant to a Rule 10b5-1 trading plan."},
+	})
+	require.NoError(t, err)
+
+	unenriched, err = db.UnenrichedTransactions(ctx)
+	require.NoError(t, err)
+	require.Len(t, unenriched, 1)
+	assert.Equal(t, tr[0].ID, unenriched[0].ID)
+
+	top, err := db.TopSell(ctx, insider.ReportFilter{Day: day, ExcludePlanned: true})
+	require.NoError(t, err)
+	assert.Equal(t, []insider.TotalTransaction{{Ticker: "AAA", TotalValue: -1000}}, top)
+
+	top, err = db.TopSell(ctx, insider.ReportFilter{Day: day})
+	require.NoError(t, err)
+	assert.Len(t, top, 2, "planned sales are reported without the filter")
+
+	top, err = db.TopPlannedSell(ctx, insider.ReportFilter{Day: day, ExcludePlanned: true})
+	require.NoError(t, err)
+	assert.Equal(t, []insider.TotalTransaction{{Ticker: "BBB", TotalValue: 700}}, top)
+}
+
+func testReturns(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+
+	buy := transaction(day, "AAA", "Smith John", insider.Buy, 1000)
+	buy.TransactionDate = day.AddDate(0, 0, -10)
+	sale := transaction(day, "AAA", "Doe Jane", insider.Sale, 1000)
+	sale.TransactionDate = day.AddDate(0, 0, -10)
+	insert(t, db, buy, sale)
+
+	closes := []price.Close{
+		// the first close after the transaction is overwritten below
+		{Ticker: "AAA", Date: day.AddDate(0, 0, -9), Close: 12},
+		{Ticker: "AAA", Date: day.AddDate(0, 0, -8), Close: 9},
+		{Ticker: "SPY", Date: day.AddDate(0, 0, -10), Close: 100},
+		{Ticker: "SPY", Date: day.AddDate(0, 0, -9), Close: 105},
+	}
+	require.NoError(t, db.SaveCloses(ctx, closes))
+	require.NoError(t, db.SaveCloses(ctx, []price.Close{{Ticker: "AAA", Date: day.AddDate(0, 0, -9), Close: 11}}))
+	require.NoError(t, db.SaveCloses(ctx, nil))
+
+	tr, err := db.TransactionReturns(ctx, day.AddDate(0, 0, -1), insider.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, tr, 2)
+	for _, r := range tr {
+		require.NotNil(t, r.Return1d, r.Transaction)
+		assert.InDelta(t, 0.1, *r.Return1d, 1e-9)
+		assert.Nil(t, r.Return5d, "there is no close after 5 days yet")
+	}
+
+	types, err := db.TypeReturns(ctx, day.AddDate(0, 0, -1), insider.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, types, 2)
+	assert.Equal(t, insider.Buy, types[0].Transaction)
+	assert.Equal(t, 1, types[0].TransactionCount)
+	require.NotNil(t, types[0].Return1d)
+	assert.InDelta(t, 0.1, *types[0].Return1d, 1e-9)
+	assert.Nil(t, types[0].Return20d)
+	assert.Nil(t, types[0].HitRate20d)
+
+	insiders, err := db.InsiderReturns(ctx, day.AddDate(0, 0, -1), insider.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, insiders, 2)
+
+	br, err := db.BuyReturns(ctx, 1, "SPY")
+	require.NoError(t, err)
+	require.Len(t, br, 1, "only buys with the closes of the horizon")
+	assert.Equal(t, "Smith John", br[0].Owner)
+	assert.InDelta(t, 0.1, br[0].Return, 1e-9)
+	assert.InDelta(t, 0.05, br[0].BenchmarkReturn, 1e-9)
+
+	br, err = db.BuyReturns(ctx, 2, "SPY")
+	require.NoError(t, err)
+	assert.Empty(t, br, "there is no benchmark close after 2 days")
+
+	// the close of the first session after the transaction is missed,
+	// the next stored close is the return after 2 days, not after 1 day
+	gap := transaction(day, "BBB", "Smith John", insider.Buy, 1000)
+	gap.TransactionDate = day.AddDate(0, 0, -10)
+	gapID := insert(t, db, gap)[0].ID
+	require.NoError(t, db.SaveCloses(ctx, []price.Close{{Ticker: "BBB", Date: day.AddDate(0, 0, -8), Close: 15}}))
+
+	tr, err = db.TransactionReturns(ctx, day.AddDate(0, 0, -1), insider.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, tr, 3)
+	for _, r := range tr {
+		if r.Ticker == "BBB" {
+			assert.Nil(t, r.Return1d, "the close of the session isn't stored")
+		}
+	}
+
+	br, err = db.BuyReturns(ctx, 1, "SPY")
+	require.NoError(t, err)
+	require.Len(t, br, 1, "scores use the calendar of the returns")
+	assert.NotEqual(t, gapID, br[0].TransactionID)
+
+	// the stock and the benchmark are compared on the same session
+	require.NoError(t, db.SaveCloses(ctx, []price.Close{{Ticker: "SPY", Date: day.AddDate(0, 0, -8), Close: 110}}))
+	br, err = db.BuyReturns(ctx, 2, "SPY")
+	require.NoError(t, err)
+	require.Len(t, br, 2)
+	for _, r := range br {
+		assert.InDelta(t, 0.1, r.BenchmarkReturn, 1e-9)
+		if r.TransactionID == gapID {
+			assert.InDelta(t, 0.5, r.Return, 1e-9)
+		} else {
+			assert.InDelta(t, -0.1, r.Return, 1e-9)
+		}
+	}
+}
+
+func testScores(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+	updated := day.Add(time.Hour)
+
+	tr := insert(t, db,
+		transaction(day, "AAA", "Smith John", insider.Buy, 1000),
+		transaction(day, "BBB", "Doe Jane", insider.Buy, 2000),
+	)
+
+	scores := []score.Score{
+		{Kind: score.KindInsider, Name: "Smith John", Horizon: 20, Transactions: 5, HitRate: 0.6, AvgExcessReturn: 0.02, UpdatedAt: updated},
+		{Kind: score.KindInsider, Name: "Smith John", Horizon: 60, Transactions: 4, HitRate: 0.5, AvgExcessReturn: 0.01, UpdatedAt: updated},
+		{Kind: score.KindInsider, Name: "Other", Horizon: 20, Transactions: 3, HitRate: 0.3, AvgExcessReturn: -0.01, UpdatedAt: updated},
+		{Kind: score.KindRole, Name: "CEO", Horizon: 20, Transactions: 8, HitRate: 0.7, AvgExcessReturn: 0.03, UpdatedAt: updated},
+	}
+	require.NoError(t, db.SaveScores(ctx, scores))
+
+	// upsert
+	scores[2].AvgExcessReturn = 0.05
+	require.NoError(t, db.SaveScores(ctx, scores[2:3]))
+
+	sc, err := db.Scores(ctx, score.KindInsider, 20)
+	require.NoError(t, err)
+	require.Len(t, sc, 2)
+	assert.Equal(t, "Other", sc[0].Name, "the best goes first")
+	assert.InDelta(t, 0.05, sc[0].AvgExcessReturn, 1e-9)
+	assert.True(t, updated.Equal(sc[0].UpdatedAt), sc[0].UpdatedAt)
+
+	ts, err := db.TransactionScores(ctx, insider.ReportFilter{Day: day})
+	require.NoError(t, err)
+	require.Len(t, ts, 2, "scores of all horizons of the insider")
+	for i, h := range []int{20, 60} {
+		assert.Equal(t, tr[0].ID, ts[i].TransactionID)
+		assert.Equal(t, "AAA", ts[i].Ticker)
+		assert.Equal(t, 1000, ts[i].Value)
+		assert.Equal(t, h, ts[i].Horizon)
+	}
+}
+
+func testAlerts(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	tr := insert(t, db, transaction(reportDay(), "AAA", "Smith John", insider.Buy, 1000))
+
+	require.NoError(t, db.SaveRule(ctx, alert.Rule{Name: "large", Expr: "value > 100", Enabled: true}))
+	require.NoError(t, db.SaveRule(ctx, alert.Rule{Name: "ceo", Expr: `relationship == "CEO"`}))
+	require.NoError(t, db.SaveRule(ctx, alert.Rule{Name: "ceo", Expr: `relationship == "CEO"`, Enabled: true}))
+
+	rules, err := db.Rules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "ceo", rules[0].Name, "sorted by name")
+	assert.True(t, rules[0].Enabled, "updated")
+	assert.False(t, rules[0].CreatedAt.IsZero())
+
+	fired, err := db.Fire(ctx, "large", tr[0].ID)
+	require.NoError(t, err)
+	assert.True(t, fired)
+
+	fired, err = db.Fire(ctx, "large", tr[0].ID)
+	require.NoError(t, err)
+	assert.False(t, fired, "the rule fires once for the transaction")
+
+	fired, err = db.Fire(ctx, "ceo", tr[0].ID)
+	require.NoError(t, err)
+	require.True(t, fired)
+
+	pending, err := db.Undelivered(ctx, "ceo")
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "the firing is pending until delivered")
+	assert.Equal(t, tr[0].ID, pending[0].ID)
+	assert.Equal(t, "AAA", pending[0].Ticker)
+
+	require.NoError(t, db.Deliver(ctx, "ceo", tr[0].ID))
+	pending, err = db.Undelivered(ctx, "ceo")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	firings, err := db.Firings(ctx, "ceo", 10)
+	require.NoError(t, err)
+	require.Len(t, firings, 1)
+	assert.NotNil(t, firings[0].DeliveredAt)
+
+	firings, err = db.Firings(ctx, "large", 10)
+	require.NoError(t, err)
+	require.Len(t, firings, 1)
+	assert.Equal(t, tr[0].ID, firings[0].TransactionID)
+	assert.Nil(t, firings[0].DeliveredAt)
+
+	require.NoError(t, db.DeleteRule(ctx, "large"))
+
+	rules, err = db.Rules(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rules, 1)
+
+	firings, err = db.Firings(ctx, "large", 10)
+	require.NoError(t, err)
+	assert.Empty(t, firings, "history is deleted with the rule")
+}
+
+func testRuns(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+	started := day.Add(time.Hour)
+
+	r := run.Run{
+		StartedAt:  started,
+		Day:        day,
+		SourceURLs: []string{},
+		Status:     run.StatusRunning,
+		Publish:    map[string]run.Status{},
+		Sources:    map[string]run.SourceHealth{},
+	}
+	require.NoError(t, db.StartRun(ctx, &r))
+	assert.NotZero(t, r.ID)
+
+	fetched, err := db.Fetched(ctx, day)
+	require.NoError(t, err)
+	assert.False(t, fetched)
+
+	last, err := db.LastFetched(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero(), last)
+
+	finished := started.Add(time.Minute)
+	r.FinishedAt = &finished
+	r.SourceURLs = []string{"https://finviz.com/insidertrading.ashx?tc=1"}
+	r.Scraped, r.Kept, r.Inserted, r.Duplicates = 200, 10, 8, 2
+	r.Status = run.StatusSucceeded
+	r.Publish[run.SinkDigest] = run.StatusSucceeded
+	r.Sources["finviz"] = run.SourceHealth{Status: run.StatusSucceeded, Rows: 10, DurationMs: 1500}
+	r.Sources["edgar"] = run.SourceHealth{Status: run.StatusFailed, Error: "unexpected status: 503"}
+	require.NoError(t, db.SaveRun(ctx, r))
+
+	fetched, err = db.Fetched(ctx, day)
+	require.NoError(t, err)
+	assert.True(t, fetched)
+
+	fetched, err = db.Fetched(ctx, day.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.False(t, fetched)
+
+	last, err = db.LastFetched(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, day.Format(time.DateOnly), last.Format(time.DateOnly))
+
+	next := run.Run{StartedAt: started.Add(time.Hour), Day: day, Status: run.StatusSkipped, SourceURLs: []string{}, Publish: map[string]run.Status{}, Sources: map[string]run.SourceHealth{}}
+	require.NoError(t, db.StartRun(ctx, &next))
+	assert.Greater(t, next.ID, r.ID)
+
+	failed := run.Run{StartedAt: started.Add(2 * time.Hour), Day: day.AddDate(0, 0, 1), Status: run.StatusFailed, SourceURLs: []string{}, Publish: map[string]run.Status{}, Sources: map[string]run.SourceHealth{}}
+	require.NoError(t, db.StartRun(ctx, &failed))
+
+	last, err = db.LastFetched(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, day.Format(time.DateOnly), last.Format(time.DateOnly), "failed runs are not fetched")
+
+	runs, err := db.Runs(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, failed.ID, runs[0].ID, "the latest goes first")
+
+	got := runs[2]
+	assert.True(t, started.Equal(got.StartedAt), got.StartedAt)
+	require.NotNil(t, got.FinishedAt)
+	assert.True(t, finished.Equal(*got.FinishedAt), got.FinishedAt)
+	assert.Equal(t, day.Format(time.DateOnly), got.Day.Format(time.DateOnly))
+	assert.Equal(t, r.SourceURLs, got.SourceURLs)
+	assert.Equal(t, []int{200, 10, 8, 2}, []int{got.Scraped, got.Kept, got.Inserted, got.Duplicates})
+	assert.Equal(t, run.StatusSucceeded, got.Status)
+	assert.Equal(t, r.Publish, got.Publish)
+	assert.Equal(t, r.Sources, got.Sources)
+
+	runs, err = db.Runs(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
+
+func testDigests(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+	digest := publish.NewDigest(day, run.SinkDigest)
+
+	_, err := db.InsertTransactions(ctx, insider.Transactions{transaction(day, "AAA", "Smith John", insider.Buy, 1000)}, digest)
+	require.NoError(t, err)
+
+	d, ok, err := db.ClaimDigest(ctx, digest.Key, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, digest.Key, d.Key)
+	assert.Equal(t, day.Format(time.DateOnly), d.Day.Format(time.DateOnly))
+
+	_, ok, err = db.ClaimDigest(ctx, digest.Key, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "the leased digest isn't claimed again")
+
+	require.NoError(t, db.SaveDigestPart(ctx, digest.Key, "1/0"))
+	require.NoError(t, db.SaveDigestPart(ctx, digest.Key, "1/0"), "the part is saved once")
+	require.NoError(t, db.SaveDigestPart(ctx, digest.Key, "1/1"))
+
+	errSend := errors.New("send failed")
+	require.NoError(t, db.ReleaseDigest(ctx, digest.Key, errSend))
+
+	pending, err := db.PendingDigests(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "the failed digest stays pending")
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, errSend.Error(), pending[0].LastError)
+
+	// the expired lease is claimed by another publisher
+	_, ok, err = db.ClaimDigest(ctx, digest.Key, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, ok, err = db.ClaimDigest(ctx, digest.Key, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	parts, err := db.DigestParts(ctx, digest.Key)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1/0", "1/1"}, parts, "sent parts survive the failed attempt")
+
+	require.NoError(t, db.ReleaseDigest(ctx, digest.Key, nil))
+
+	_, ok, err = db.ClaimDigest(ctx, digest.Key, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "the delivered digest isn't claimed")
+
+	_, ok, err = db.ClaimDigest(ctx, "unknown", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	pending, err = db.PendingDigests(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// the day without new transactions still has the digest
+	quiet := publish.NewDigest(day.AddDate(0, 0, 1), run.SinkDigest)
+	fresh, err := db.InsertTransactions(ctx, nil, quiet)
+	require.NoError(t, err)
+	assert.Empty(t, fresh)
+
+	pending, err = db.PendingDigests(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, quiet.Key, pending[0].Key)
+}
+
+func testEvents(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+
+	late := transaction(day, "AAA", "Smith John", insider.Buy, 1000)
+	early := transaction(day, "BBB", "Smith John", insider.Buy, 1000)
+	early.NotificationDate = early.NotificationDate.Add(-time.Hour)
+	insert(t, db, late, early)
+
+	events, err := db.PendingEvents(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "BBB", events[0].Key, "in the order of notification date")
+	assert.True(t, early.NotificationDate.Equal(events[0].NotificationDate), events[0].NotificationDate)
+
+	var tr insider.Transaction
+	require.NoError(t, json.Unmarshal(events[0].Data, &tr))
+	assert.Equal(t, "BBB", tr.Ticker)
+	assert.NotEmpty(t, tr.ID)
+
+	require.NoError(t, db.MarkPublished(ctx, events[0].ID))
+
+	events, err = db.PendingEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "AAA", events[0].Key)
+}
+
+func testSubscribe(t *testing.T, db storage.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := db.Subscribe(ctx)
+	require.NoError(t, err)
+
+	in := run.Ingest{RunID: 7, Scraped: 200, Kept: 10, Inserted: 8, Duplicates: 2}
+	require.NoError(t, db.NotifyIngest(ctx, in))
+
+	select {
+	case got := <-ch:
+		assert.Equal(t, in, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func testCompanies(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+	updated := day.Add(time.Hour)
+	marketCap := 1.5e9
+
+	insert(t, db,
+		transaction(day, "AAA", "Smith John", insider.Buy, 1000),
+		transaction(day, "AAA", "Doe Jane", insider.Sale, 400),
+		transaction(day, "BBB", "Doe Jane", insider.Buy, 700),
+		// without the company
+		transaction(day, "CCC", "Doe Jane", insider.Buy, 5000),
+		transaction(day.AddDate(0, 0, -1), "BBB", "Doe Jane", insider.Buy, 5000),
+	)
+
+	require.NoError(t, db.SaveCompany(ctx, company.Company{Ticker: "AAA", Name: "Old", Sector: "Energy", UpdatedAt: updated}))
+	require.NoError(t, db.SaveCompany(ctx, company.Company{
+		Ticker:    "AAA",
+		Name:      "AAA Inc.",
+		Sector:    "Technology",
+		Industry:  "Software",
+		Country:   "USA",
+		MarketCap: &marketCap,
+		UpdatedAt: updated,
+	}))
+	require.NoError(t, db.SaveCompany(ctx, company.Company{Ticker: "BBB", Name: "BBB Corp.", Sector: "Technology", UpdatedAt: updated}))
+
+	c, err := db.Companies(ctx, []string{"AAA", "BBB", "CCC"})
+	require.NoError(t, err)
+	require.Len(t, c, 2)
+	assert.Equal(t, "AAA Inc.", c[0].Name, "updated")
+	assert.Equal(t, "Software", c[0].Industry)
+	require.NotNil(t, c[0].MarketCap)
+	assert.InDelta(t, marketCap, *c[0].MarketCap, 1e-6)
+	assert.True(t, updated.Equal(c[0].UpdatedAt), c[0].UpdatedAt)
+	assert.Nil(t, c[1].MarketCap)
+
+	c, err = db.Companies(ctx, []string{"BBB"})
+	require.NoError(t, err)
+	require.Len(t, c, 1)
+	assert.Equal(t, "BBB", c[0].Ticker)
+
+	st, err := db.SectorTotals(ctx, insider.ReportFilter{Day: day})
+	require.NoError(t, err)
+	assert.Equal(t, []company.SectorTotal{
+		{Sector: "Technology", TransactionTypeCount: insider.TransactionTypeCount{Transaction: insider.Buy, TransactionCount: 2, TotalValue: 1700}},
+		{Sector: "Technology", TransactionTypeCount: insider.TransactionTypeCount{Transaction: insider.Sale, TransactionCount: 1, TotalValue: 400}},
+	}, st)
+}
+
+func testSignificance(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	day := reportDay()
+	ptr := func(v float64) *float64 { return &v }
+
+	insert(t, db,
+		transaction(day, "AAA", "Smith John", insider.Buy, 1000),
+		transaction(day, "AAA", "Doe Jane", insider.Sale, 400),
+		transaction(day, "BBB", "Doe Jane", insider.Sale, 3000),
+		// without the market cap
+		transaction(day, "CCC", "Doe Jane", insider.Buy, 5000),
+		// without the company
+		transaction(day, "DDD", "Doe Jane", insider.Buy, 100),
+		transaction(day, "EEE", "Doe Jane", insider.Buy, 2000),
+		transaction(day.AddDate(0, 0, -1), "EEE", "Doe Jane", insider.Buy, 5000),
+	)
+
+	for _, c := range []company.Company{
+		{Ticker: "AAA", MarketCap: ptr(1e6), SharesOutstanding: ptr(1e5), SharesFloat: ptr(6000)},
+		{Ticker: "BBB", MarketCap: ptr(2e6), SharesOutstanding: ptr(30_000)},
+		{Ticker: "CCC", SharesFloat: ptr(6000)},
+		{Ticker: "EEE", MarketCap: ptr(1e7)},
+	} {
+		c.UpdatedAt = day
+		require.NoError(t, db.SaveCompany(ctx, c))
+	}
+
+	c, err := db.Companies(ctx, []string{"AAA"})
+	require.NoError(t, err)
+	require.Len(t, c, 1)
+	require.NotNil(t, c[0].SharesOutstanding)
+	require.NotNil(t, c[0].SharesFloat)
+	assert.InDelta(t, 1e5, *c[0].SharesOutstanding, 1e-6)
+	assert.InDelta(t, 6000, *c[0].SharesFloat, 1e-6)
+
+	buy, err := db.SignificantBuy(ctx, insider.ReportFilter{Day: day})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AAA", "EEE"}, significanceTickers(buy), "the net sale isn't the buy")
+
+	assert.InDelta(t, 600, buy[0].TotalValue, 1e-6)
+	assert.Equal(t, int64(60), buy[0].TotalShares)
+	assert.InDelta(t, 1e6, buy[0].MarketCap, 1e-6)
+	assert.InDelta(t, 6, buy[0].ValueBps, 1e-6)
+	require.NotNil(t, buy[0].FloatShare, "float")
+	assert.InDelta(t, 0.01, *buy[0].FloatShare, 1e-9)
+
+	assert.InDelta(t, 2, buy[1].ValueBps, 1e-6)
+	assert.Nil(t, buy[1].FloatShare, "no shares")
+
+	sell, err := db.SignificantSell(ctx, insider.ReportFilter{Day: day})
+	require.NoError(t, err)
+	require.Equal(t, []string{"BBB"}, significanceTickers(sell), "the net buy isn't the sale")
+
+	assert.InDelta(t, -15, sell[0].ValueBps, 1e-6)
+	require.NotNil(t, sell[0].FloatShare, "shares outstanding")
+	assert.InDelta(t, -0.01, *sell[0].FloatShare, 1e-9)
+}
+
+func significanceTickers(sig []company.Significance) []string {
+	t := make([]string, 0, len(sig))
+	for _, s := range sig {
+		t = append(t, s.Ticker)
+	}
+
+	return t
+}
+
+func testSymbols(t *testing.T, db storage.Storage) {
+	ctx := context.Background()
+	updated := reportDay().Add(time.Hour)
+
+	require.NoError(t, db.SaveSymbols(ctx, nil))
+	require.NoError(t, db.SaveSymbols(ctx, []symbol.Symbol{
+		{Source: symbol.EDGAR, Symbol: "1067983", Ticker: "BRK.A", UpdatedAt: updated},
+		{Source: symbol.Finviz, Symbol: "BRK-B", Ticker: "BRK.B", UpdatedAt: updated},
+	}))
+	require.NoError(t, db.SaveSymbols(ctx, []symbol.Symbol{
+		{Source: symbol.EDGAR, Symbol: "1067983", Ticker: "BRK.B", UpdatedAt: updated},
+		{Source: symbol.Yahoo, Symbol: "BRK-B", Ticker: "BRK.B", UpdatedAt: updated},
+	}))
+
+	sym, err := db.Symbols(ctx, "BRK.B")
+	require.NoError(t, err)
+	require.Len(t, sym, 3)
+	assert.Equal(t, symbol.EDGAR, sym[0].Source, "ordered by source")
+	assert.Equal(t, "1067983", sym[0].Symbol, "updated")
+	assert.True(t, updated.Equal(sym[0].UpdatedAt), sym[0].UpdatedAt)
+	assert.Equal(t, symbol.Finviz, sym[1].Source)
+	assert.Equal(t, symbol.Yahoo, sym[2].Source)
+
+	sym, err = db.Symbols(ctx, "BRK.A")
+	require.NoError(t, err)
+	assert.Empty(t, sym)
+
+	ticker, err := db.SymbolTicker(ctx, symbol.EDGAR, "1067983")
+	require.NoError(t, err)
+	assert.Equal(t, "BRK.B", ticker)
+
+	ticker, err = db.SymbolTicker(ctx, symbol.Bloomberg, "BRK/B")
+	require.NoError(t, err)
+	assert.Empty(t, ticker, "not mapped")
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// ParseOptions returns options from the environment,
+// defaults are for the local database from docker-compose.
+func ParseOptions() Options {
+	return Options{
+		Host:     env("PG_HOST", "localhost:5432"),
+		Database: env("PG_DATABASE", "finviz"),
+		Username: env("PG_USERNAME", "finviz"),
+		Password: env("PG_PASSWORD", "finviz"),
+		MaxPool:  envInt("PG_POOL_MAX_CONNS", 10),
+		MinPool:  envInt("PG_POOL_MIN_CONNS", 2),
+	}
+}
+
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
+
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("env: %s cannot convert", key)
+	}
+
+	return i
+}
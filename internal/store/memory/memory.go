@@ -0,0 +1,1501 @@
+// Package memory is the in-memory storage backend for tests and dry runs,
+// it follows the semantics of the PostgreSQL queries.
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/event"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+// topLimit is the number of tickers in top reports.
+const topLimit = 20
+
+// row is the stored transaction with details of the filing.
+type row struct {
+	insider.Transaction
+	filing  *insider.Filing
+	planned bool
+}
+
+type scoreKey struct {
+	kind    score.Kind
+	name    string
+	horizon int
+}
+
+type symbolKey struct {
+	source symbol.Source
+	symbol string
+}
+
+type Store struct {
+	mu sync.Mutex
+
+	transactions []row
+	// closes are daily closes by ticker and date
+	closes    map[string]map[string]float64
+	companies map[string]company.Company
+	symbols   map[symbolKey]symbol.Symbol
+	scores    map[scoreKey]score.Score
+	rules     map[string]alert.Rule
+	firings   []alert.Firing
+	runs      []run.Run
+	digests   map[string]publish.Digest
+	// leases are expirations of claimed digests by key
+	leases map[string]time.Time
+	// parts are sent parts of digests by key
+	parts     map[string]map[string]struct{}
+	events    []event.Event
+	published map[int64]struct{}
+
+	subs map[chan run.Ingest]struct{}
+}
+
+func New() *Store {
+	return &Store{
+		closes:    make(map[string]map[string]float64),
+		companies: make(map[string]company.Company),
+		symbols:   make(map[symbolKey]symbol.Symbol),
+		scores:    make(map[scoreKey]score.Score),
+		rules:     make(map[string]alert.Rule),
+		digests:   make(map[string]publish.Digest),
+		leases:    make(map[string]time.Time),
+		parts:     make(map[string]map[string]struct{}),
+		published: make(map[int64]struct{}),
+		subs:      make(map[chan run.Ingest]struct{}),
+	}
+}
+
+func (s *Store) Close() {}
+
+// day is the date of t like timestamptz::date in UTC.
+func day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// newID returns the random UUID.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("rand: %w", err)
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// report reports whether the row is in the report of the filter.
+func (r row) report(f insider.ReportFilter) bool {
+	return day(r.NotificationDate) == day(f.Day) && !(f.ExcludePlanned && r.planned)
+}
+
+// InsertTransactions inserts transactions which are not stored yet,
+// their TransactionCreated events and pending digests at once,
+// it returns inserted transactions with IDs.
+func (s *Store) InsertTransactions(_ context.Context, tr insider.Transactions, digests ...publish.Digest) (insider.Transactions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make(insider.Transactions, 0, len(s.transactions))
+	for _, r := range s.transactions {
+		all = append(all, r.Transaction)
+	}
+	stored := insider.NewDedup(all)
+
+	var (
+		fresh  insider.Transactions
+		events []event.Event
+	)
+	for _, t := range tr {
+		// skip duplicates of stored rows, of the same page and of other sources
+		if !stored.Add(t) {
+			continue
+		}
+
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		t.ID = id
+		t.Flags = slices.Clone(t.Flags)
+
+		e, err := event.TransactionCreated(t)
+		if err != nil {
+			return nil, err
+		}
+
+		fresh = append(fresh, t)
+		events = append(events, e)
+	}
+
+	now := time.Now()
+	for _, t := range fresh {
+		s.transactions = append(s.transactions, row{Transaction: t})
+	}
+
+	for _, e := range events {
+		e.ID = int64(len(s.events) + 1)
+		e.CreatedAt = now
+		s.events = append(s.events, e)
+	}
+
+	for _, d := range digests {
+		// the digest of the day is enqueued once, reruns keep its state
+		if _, ok := s.digests[d.Key]; ok {
+			continue
+		}
+
+		s.digests[d.Key] = publish.Digest{
+			Key:       d.Key,
+			Day:       d.Day,
+			Sink:      d.Sink,
+			CreatedAt: now,
+		}
+	}
+
+	return fresh, nil
+}
+
+// History returns the insiders' history for the transactions.
+func (s *Store) History(_ context.Context, tr insider.Transactions) ([]insider.History, error) {
+	type key struct {
+		owner, ticker string
+		transaction   insider.TransactionType
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history []insider.History
+	seen := make(map[key]struct{}, len(tr))
+	for _, t := range tr {
+		k := key{t.Owner, t.Ticker, t.Transaction}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+
+		h := insider.History{
+			Owner:       t.Owner,
+			Ticker:      t.Ticker,
+			Transaction: t.Transaction,
+		}
+
+		// insiders and companies are identified by CIKs when known,
+		// so name variations and ticker changes share the history.
+		// The owner's CIK is looked up by the name at the same issuer,
+		// namesakes at other companies are different insiders.
+		issuerCIK := t.IssuerCIK
+		for _, r := range s.transactions {
+			if issuerCIK == "" && r.Ticker == t.Ticker && r.IssuerCIK != "" {
+				issuerCIK = r.IssuerCIK
+			}
+		}
+
+		sameIssuer := func(r row) bool {
+			return r.Ticker == t.Ticker || (issuerCIK != "" && r.IssuerCIK == issuerCIK)
+		}
+
+		ownerCIK := t.OwnerCIK
+		for _, r := range s.transactions {
+			if ownerCIK == "" && r.Owner == t.Owner && sameIssuer(r) && r.OwnerCIK != "" {
+				ownerCIK = r.OwnerCIK
+			}
+		}
+
+		sameOwner := func(r row) bool {
+			if ownerCIK != "" {
+				return r.OwnerCIK == ownerCIK
+			}
+			return r.Owner == t.Owner
+		}
+
+		var values []float64
+		for _, r := range s.transactions {
+			if sameOwner(r) && r.Transaction.Transaction == t.Transaction {
+				values = append(values, float64(r.Value))
+			}
+
+			if sameOwner(r) && sameIssuer(r) && r.Transaction.Transaction == insider.Buy &&
+				(h.LastBuy == nil || r.TransactionDate.After(*h.LastBuy)) {
+				lastBuy := r.TransactionDate
+				h.LastBuy = &lastBuy
+			}
+		}
+
+		sort.Float64s(values)
+		h.Transactions = len(values)
+		h.MedianValue = median(values)
+
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
+// median is percentile_cont(0.5) of sorted values, nil if there are none.
+func median(sorted []float64) *float64 {
+	n := len(sorted)
+	if n == 0 {
+		return nil
+	}
+
+	m := sorted[n/2]
+	if n%2 == 0 {
+		m = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	return &m
+}
+
+// UnenrichedTransactions returns transactions for the last week
+// without details from the Form 4 filing.
+func (s *Store) UnenrichedTransactions(_ context.Context) (insider.Transactions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	y, m, d := time.Now().UTC().Date()
+	since := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -7)
+
+	var tr insider.Transactions
+	for _, r := range s.transactions {
+		if r.filing == nil && r.NotificationDate.After(since) {
+			tr = append(tr, r.Transaction)
+		}
+	}
+
+	sort.SliceStable(tr, func(i, j int) bool {
+		return tr[i].NotificationDate.Before(tr[j].NotificationDate)
+	})
+
+	return tr, nil
+}
+
+// NewestNotification returns the notification date of the newest transaction.
+func (s *Store) NewestNotification(_ context.Context) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var newest time.Time
+	for _, r := range s.transactions {
+		if r.NotificationDate.After(newest) {
+			newest = r.NotificationDate
+		}
+	}
+
+	return newest, nil
+}
+
+// SaveFiling stores details from the Form 4 filing against the transaction.
+// The issuer's CIK is also set to transactions of the same ticker without it,
+// the owner's CIK to transactions of the same name at the same issuer.
+func (s *Store) SaveFiling(_ context.Context, transactionID string, f insider.Filing) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.transactions {
+		r := &s.transactions[i]
+		if r.ID != transactionID {
+			continue
+		}
+
+		f.Footnotes = slices.Clone(f.Footnotes)
+		r.filing = &f
+		r.planned = f.Planned()
+		r.IssuerCIK, r.OwnerCIK = f.IssuerCIK, f.OwnerCIK
+
+		for j := range s.transactions {
+			t := &s.transactions[j]
+			if t.Ticker == r.Ticker && t.IssuerCIK == "" {
+				t.IssuerCIK = r.IssuerCIK
+			}
+			sameIssuer := t.Ticker == r.Ticker || (r.IssuerCIK != "" && t.IssuerCIK == r.IssuerCIK)
+			if t.Owner == r.Owner && sameIssuer && t.OwnerCIK == "" {
+				t.OwnerCIK = r.OwnerCIK
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) TransactionTypeCount(_ context.Context, f insider.ReportFilter) ([]insider.TransactionTypeCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byType := make(map[insider.TransactionType]*insider.TransactionTypeCount)
+	for _, r := range s.transactions {
+		if !r.report(f) {
+			continue
+		}
+
+		c, ok := byType[r.Transaction.Transaction]
+		if !ok {
+			c = &insider.TransactionTypeCount{Transaction: r.Transaction.Transaction}
+			byType[r.Transaction.Transaction] = c
+		}
+		c.TransactionCount++
+		c.TotalValue += float64(r.Value)
+	}
+
+	tc := make([]insider.TransactionTypeCount, 0, len(byType))
+	for _, c := range byType {
+		tc = append(tc, *c)
+	}
+
+	sort.Slice(tc, func(i, j int) bool {
+		return tc[i].Transaction < tc[j].Transaction
+	})
+
+	return tc, nil
+}
+
+// DailyTotals returns totals by notification day and type
+// from since to the day of the filter.
+func (s *Store) DailyTotals(_ context.Context, since time.Time, f insider.ReportFilter) ([]insider.DailyTotal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type key struct {
+		day string
+		typ insider.TransactionType
+	}
+
+	from, to := day(since), day(f.Day)
+	byDay := make(map[key]*insider.DailyTotal)
+	for _, r := range s.transactions {
+		d := day(r.NotificationDate)
+		if d < from || d > to || (f.ExcludePlanned && r.planned) {
+			continue
+		}
+
+		k := key{d, r.Transaction.Transaction}
+		t, ok := byDay[k]
+		if !ok {
+			dt, err := time.Parse(time.DateOnly, d)
+			if err != nil {
+				return nil, fmt.Errorf("parse day: %w", err)
+			}
+
+			t = &insider.DailyTotal{Day: dt}
+			t.Transaction = k.typ
+			byDay[k] = t
+		}
+		t.TransactionCount++
+		t.TotalValue += float64(r.Value)
+	}
+
+	dt := make([]insider.DailyTotal, 0, len(byDay))
+	for _, t := range byDay {
+		dt = append(dt, *t)
+	}
+
+	sort.Slice(dt, func(i, j int) bool {
+		if !dt[i].Day.Equal(dt[j].Day) {
+			return dt[i].Day.Before(dt[j].Day)
+		}
+		return dt[i].Transaction < dt[j].Transaction
+	})
+
+	return dt, nil
+}
+
+func (s *Store) RelationshipCount(_ context.Context, f insider.ReportFilter) ([]insider.RelationshipCount, error) {
+	type key struct {
+		relationship string
+		transaction  insider.TransactionType
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byKey := make(map[key]*insider.RelationshipCount)
+	for _, r := range s.transactions {
+		if !r.report(f) {
+			continue
+		}
+
+		k := key{r.Relationship, r.Transaction.Transaction}
+		c, ok := byKey[k]
+		if !ok {
+			c = &insider.RelationshipCount{Relationship: r.Relationship}
+			c.Transaction = r.Transaction.Transaction
+			byKey[k] = c
+		}
+		c.TransactionCount++
+		c.TotalValue += float64(r.Value)
+	}
+
+	rc := make([]insider.RelationshipCount, 0, len(byKey))
+	for _, c := range byKey {
+		rc = append(rc, *c)
+	}
+
+	sort.Slice(rc, func(i, j int) bool {
+		if rc[i].TotalValue != rc[j].TotalValue {
+			return rc[i].TotalValue > rc[j].TotalValue
+		}
+		if rc[i].Relationship != rc[j].Relationship {
+			return rc[i].Relationship < rc[j].Relationship
+		}
+		return rc[i].Transaction < rc[j].Transaction
+	})
+
+	return rc, nil
+}
+
+// TopBuy returns tickers with the largest net buy: buys minus sales.
+func (s *Store) TopBuy(_ context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
+	tt := s.netValue(f)
+	sort.SliceStable(tt, func(i, j int) bool {
+		return tt[i].TotalValue > tt[j].TotalValue
+	})
+
+	return top(tt), nil
+}
+
+// TopSell returns tickers with the largest net sale: buys minus sales.
+func (s *Store) TopSell(_ context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
+	tt := s.netValue(f)
+	sort.SliceStable(tt, func(i, j int) bool {
+		return tt[i].TotalValue < tt[j].TotalValue
+	})
+
+	return top(tt), nil
+}
+
+// netValue returns buys minus sales by ticker sorted by ticker.
+func (s *Store) netValue(f insider.ReportFilter) []insider.TotalTransaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byTicker := make(map[string]float64)
+	for _, r := range s.transactions {
+		if !r.report(f) {
+			continue
+		}
+
+		switch r.Transaction.Transaction {
+		case insider.Buy:
+			byTicker[r.Ticker] += float64(r.Value)
+		case insider.Sale:
+			byTicker[r.Ticker] -= float64(r.Value)
+		}
+	}
+
+	return totals(byTicker)
+}
+
+// totals returns values by ticker sorted by ticker.
+func totals(byTicker map[string]float64) []insider.TotalTransaction {
+	tt := make([]insider.TotalTransaction, 0, len(byTicker))
+	for ticker, v := range byTicker {
+		tt = append(tt, insider.TotalTransaction{Ticker: ticker, TotalValue: v})
+	}
+
+	sort.Slice(tt, func(i, j int) bool {
+		return tt[i].Ticker < tt[j].Ticker
+	})
+
+	return tt
+}
+
+func top(tt []insider.TotalTransaction) []insider.TotalTransaction {
+	if len(tt) > topLimit {
+		return tt[:topLimit]
+	}
+
+	return tt
+}
+
+// UnusualTransactions returns transactions of the report day flagged at ingest.
+func (s *Store) UnusualTransactions(_ context.Context, f insider.ReportFilter) (insider.Transactions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tr insider.Transactions
+	for _, r := range s.transactions {
+		if r.report(f) && len(r.Flags) > 0 {
+			tr = append(tr, r.Transaction)
+		}
+	}
+
+	sort.SliceStable(tr, func(i, j int) bool {
+		return tr[i].Value > tr[j].Value
+	})
+
+	return tr, nil
+}
+
+// TopPlannedSell returns tickers with the largest sales
+// made under Rule 10b5-1 plans on the report day.
+func (s *Store) TopPlannedSell(_ context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byTicker := make(map[string]float64)
+	for _, r := range s.transactions {
+		if day(r.NotificationDate) == day(f.Day) && r.planned && r.Transaction.Transaction == insider.Sale {
+			byTicker[r.Ticker] += float64(r.Value)
+		}
+	}
+
+	tt := totals(byTicker)
+	sort.SliceStable(tt, func(i, j int) bool {
+		return tt[i].TotalValue > tt[j].TotalValue
+	})
+
+	return top(tt), nil
+}
+
+func (s *Store) SaleTicker(_ context.Context, f insider.ReportFilter) (insider.Tickers, error) {
+	return s.tickers(func(r row) bool {
+		return r.report(f) && r.Transaction.Transaction == insider.Sale
+	}), nil
+}
+
+func (s *Store) BuyTicker(_ context.Context, f insider.ReportFilter) (insider.Tickers, error) {
+	return s.tickers(func(r row) bool {
+		return r.report(f) && r.Transaction.Transaction == insider.Buy
+	}), nil
+}
+
+// TradedTickers returns tickers with transactions since the date.
+func (s *Store) TradedTickers(_ context.Context, since time.Time) (insider.Tickers, error) {
+	return s.tickers(func(r row) bool {
+		return !r.TransactionDate.Before(since)
+	}), nil
+}
+
+// tickers returns distinct sorted tickers of matched rows.
+func (s *Store) tickers(match func(r row) bool) insider.Tickers {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var t insider.Tickers
+	for _, r := range s.transactions {
+		if match(r) && !slices.Contains(t, r.Ticker) {
+			t = append(t, r.Ticker)
+		}
+	}
+	sort.Strings(t)
+
+	return t
+}
+
+// Companies returns stored companies of the tickers.
+func (s *Store) Companies(_ context.Context, tickers []string) ([]company.Company, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var c []company.Company
+	for _, t := range tickers {
+		if v, ok := s.companies[t]; ok {
+			c = append(c, v)
+		}
+	}
+
+	sort.Slice(c, func(i, j int) bool {
+		return c[i].Ticker < c[j].Ticker
+	})
+
+	return slices.CompactFunc(c, func(a, b company.Company) bool { return a.Ticker == b.Ticker }), nil
+}
+
+// SaveCompany inserts or updates the company.
+func (s *Store) SaveCompany(_ context.Context, c company.Company) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.companies[c.Ticker] = c
+
+	return nil
+}
+
+// SectorTotals returns totals by sector and type of the report day,
+// tickers without the company are not counted.
+func (s *Store) SectorTotals(_ context.Context, f insider.ReportFilter) ([]company.SectorTotal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type key struct {
+		sector string
+		typ    insider.TransactionType
+	}
+
+	bySector := make(map[key]*company.SectorTotal)
+	for _, r := range s.transactions {
+		c, ok := s.companies[r.Ticker]
+		if !ok || c.Sector == "" || !r.report(f) {
+			continue
+		}
+
+		k := key{c.Sector, r.Transaction.Transaction}
+		t, ok := bySector[k]
+		if !ok {
+			t = &company.SectorTotal{Sector: c.Sector}
+			t.Transaction = k.typ
+			bySector[k] = t
+		}
+		t.TransactionCount++
+		t.TotalValue += float64(r.Value)
+	}
+
+	st := make([]company.SectorTotal, 0, len(bySector))
+	for _, t := range bySector {
+		st = append(st, *t)
+	}
+
+	sort.Slice(st, func(i, j int) bool {
+		if st[i].Sector != st[j].Sector {
+			return st[i].Sector < st[j].Sector
+		}
+		return st[i].Transaction < st[j].Transaction
+	})
+
+	return st, nil
+}
+
+// SignificantBuy returns top tickers of the report day with the net buy
+// by the value in basis points of the market cap, tickers without the market cap are skipped.
+func (s *Store) SignificantBuy(_ context.Context, f insider.ReportFilter) ([]company.Significance, error) {
+	sig := slices.DeleteFunc(s.significance(f), func(v company.Significance) bool {
+		return v.TotalValue <= 0
+	})
+	sort.SliceStable(sig, func(i, j int) bool {
+		return sig[i].ValueBps > sig[j].ValueBps
+	})
+
+	return sig[:min(len(sig), topLimit)], nil
+}
+
+// SignificantSell returns top tickers of the report day with the net sale
+// by the value in basis points of the market cap, tickers without the market cap are skipped.
+func (s *Store) SignificantSell(_ context.Context, f insider.ReportFilter) ([]company.Significance, error) {
+	sig := slices.DeleteFunc(s.significance(f), func(v company.Significance) bool {
+		return v.TotalValue >= 0
+	})
+	sort.SliceStable(sig, func(i, j int) bool {
+		return sig[i].ValueBps < sig[j].ValueBps
+	})
+
+	return sig[:min(len(sig), topLimit)], nil
+}
+
+// significance returns net transactions of the report day by ticker.
+func (s *Store) significance(f insider.ReportFilter) []company.Significance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byTicker := make(map[string]*company.Significance)
+	for _, r := range s.transactions {
+		c, ok := s.companies[r.Ticker]
+		if !ok || c.MarketCap == nil || *c.MarketCap <= 0 || !r.report(f) {
+			continue
+		}
+
+		sign := 1
+		switch r.Transaction.Transaction {
+		case insider.Buy:
+		case insider.Sale:
+			sign = -1
+		default:
+			continue
+		}
+
+		sig, ok := byTicker[r.Ticker]
+		if !ok {
+			sig = &company.Significance{Ticker: r.Ticker, MarketCap: *c.MarketCap}
+			byTicker[r.Ticker] = sig
+		}
+		sig.TotalValue += float64(sign * r.Value)
+		sig.TotalShares += int64(sign * r.Shares)
+	}
+
+	sig := make([]company.Significance, 0, len(byTicker))
+	for _, v := range byTicker {
+		v.ValueBps = v.TotalValue / v.MarketCap * 10000
+
+		shares := s.companies[v.Ticker].SharesFloat
+		if shares == nil {
+			shares = s.companies[v.Ticker].SharesOutstanding
+		}
+		if shares != nil && *shares != 0 {
+			share := float64(v.TotalShares) / *shares
+			v.FloatShare = &share
+		}
+
+		sig = append(sig, *v)
+	}
+
+	sort.Slice(sig, func(i, j int) bool {
+		return sig[i].Ticker < sig[j].Ticker
+	})
+
+	return sig
+}
+
+// SaveSymbols inserts or updates symbols of sources.
+func (s *Store) SaveSymbols(_ context.Context, symbols []symbol.Symbol) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sym := range symbols {
+		s.symbols[symbolKey{sym.Source, sym.Symbol}] = sym
+	}
+
+	return nil
+}
+
+// Symbols returns symbols of the canonical ticker.
+func (s *Store) Symbols(_ context.Context, ticker string) ([]symbol.Symbol, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sym []symbol.Symbol
+	for _, v := range s.symbols {
+		if v.Ticker == ticker {
+			sym = append(sym, v)
+		}
+	}
+
+	sort.Slice(sym, func(i, j int) bool {
+		if sym[i].Source != sym[j].Source {
+			return sym[i].Source < sym[j].Source
+		}
+		return sym[i].Symbol < sym[j].Symbol
+	})
+
+	return sym, nil
+}
+
+// SymbolTicker returns the canonical ticker of the symbol of the source,
+// it's empty if the symbol isn't mapped.
+func (s *Store) SymbolTicker(_ context.Context, source symbol.Source, sym string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.symbols[symbolKey{source, sym}].Ticker, nil
+}
+
+// SaveCloses inserts or updates daily close prices.
+func (s *Store) SaveCloses(_ context.Context, closes []price.Close) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range closes {
+		byDate, ok := s.closes[c.Ticker]
+		if !ok {
+			byDate = make(map[string]float64)
+			s.closes[c.Ticker] = byDate
+		}
+		byDate[day(c.Date)] = c.Close
+	}
+
+	return nil
+}
+
+// closeAt returns the last close of the ticker on or before the day.
+func (s *Store) closeAt(ticker, at string) (float64, bool) {
+	var (
+		last  string
+		close float64
+	)
+	for d, c := range s.closes[ticker] {
+		if d <= at && d > last {
+			last, close = d, c
+		}
+	}
+
+	return close, last != ""
+}
+
+// tradingDays returns dates with any stored close after the day, the benchmark
+// is stored every session, so they are the trading calendar.
+func (s *Store) tradingDays(after string) []string {
+	seen := make(map[string]struct{})
+	for _, byDate := range s.closes {
+		for d := range byDate {
+			if d > after {
+				seen[d] = struct{}{}
+			}
+		}
+	}
+
+	days := make([]string, 0, len(seen))
+	for d := range seen {
+		days = append(days, d)
+	}
+	sort.Strings(days)
+
+	return days
+}
+
+// forward returns the return from cost to the close of the ticker after
+// n trading days, nil if the close of the day isn't stored or the cost is zero.
+func (s *Store) forward(ticker string, days []string, n int, cost float64) *float64 {
+	if len(days) < n || cost == 0 {
+		return nil
+	}
+
+	c, ok := s.closes[ticker][days[n-1]]
+	if !ok {
+		return nil
+	}
+
+	r := c/cost - 1
+	return &r
+}
+
+// returns is the transaction_returns view.
+func (s *Store) returns(since time.Time, f insider.ReportFilter) []struct {
+	row
+	price.Returns
+} {
+	var rr []struct {
+		row
+		price.Returns
+	}
+	for _, r := range s.transactions {
+		if r.NotificationDate.Before(since) || (f.ExcludePlanned && r.planned) {
+			continue
+		}
+
+		days := s.tradingDays(day(r.TransactionDate))
+		rr = append(rr, struct {
+			row
+			price.Returns
+		}{
+			row: r,
+			Returns: price.Returns{
+				Return1d:  s.forward(r.Ticker, days, 1, r.Cost),
+				Return5d:  s.forward(r.Ticker, days, 5, r.Cost),
+				Return20d: s.forward(r.Ticker, days, 20, r.Cost),
+				Return60d: s.forward(r.Ticker, days, 60, r.Cost),
+			},
+		})
+	}
+
+	sort.SliceStable(rr, func(i, j int) bool {
+		return rr[i].NotificationDate.Before(rr[j].NotificationDate)
+	})
+
+	return rr
+}
+
+// TransactionReturns returns forward returns of transactions
+// notified since the date.
+func (s *Store) TransactionReturns(_ context.Context, since time.Time, f insider.ReportFilter) ([]price.TransactionReturn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tr []price.TransactionReturn
+	for _, r := range s.returns(since, f) {
+		tr = append(tr, price.TransactionReturn{
+			TransactionID:   r.ID,
+			Ticker:          r.Ticker,
+			Owner:           r.Owner,
+			Relationship:    r.Relationship,
+			Transaction:     r.Transaction.Transaction,
+			TransactionDate: r.TransactionDate,
+			Cost:            r.Cost,
+			Returns:         r.Returns,
+		})
+	}
+
+	return tr, nil
+}
+
+// avg is the average of not nil values, nil if there are none.
+type avg struct {
+	sum float64
+	n   int
+}
+
+func (a *avg) add(v *float64) {
+	if v != nil {
+		a.sum += *v
+		a.n++
+	}
+}
+
+func (a avg) value() *float64 {
+	if a.n == 0 {
+		return nil
+	}
+
+	v := a.sum / float64(a.n)
+	return &v
+}
+
+// typeReturn accumulates averages of price.TypeReturn.
+type typeReturn struct {
+	owner       string
+	transaction insider.TransactionType
+	count       int
+	r1, r5      avg
+	r20, r60    avg
+	hit         avg
+}
+
+func (t *typeReturn) add(r price.Returns) {
+	t.count++
+	t.r1.add(r.Return1d)
+	t.r5.add(r.Return5d)
+	t.r20.add(r.Return20d)
+	t.r60.add(r.Return60d)
+
+	if r.Return20d != nil {
+		var hit float64
+		if (t.transaction == insider.Buy && *r.Return20d > 0) || (t.transaction != insider.Buy && *r.Return20d < 0) {
+			hit = 1
+		}
+		t.hit.add(&hit)
+	}
+}
+
+func (t *typeReturn) result() price.TypeReturn {
+	return price.TypeReturn{
+		Transaction:      t.transaction,
+		TransactionCount: t.count,
+		Returns: price.Returns{
+			Return1d:  t.r1.value(),
+			Return5d:  t.r5.value(),
+			Return20d: t.r20.value(),
+			Return60d: t.r60.value(),
+		},
+		HitRate20d: t.hit.value(),
+	}
+}
+
+// groupReturns groups returns by the owner if byOwner is set and transaction type.
+func (s *Store) groupReturns(since time.Time, f insider.ReportFilter, byOwner bool) []*typeReturn {
+	type key struct {
+		owner       string
+		transaction insider.TransactionType
+	}
+
+	var groups []*typeReturn
+	byKey := make(map[key]*typeReturn)
+	for _, r := range s.returns(since, f) {
+		k := key{transaction: r.Transaction.Transaction}
+		if byOwner {
+			k.owner = r.Owner
+		}
+
+		g, ok := byKey[k]
+		if !ok {
+			g = &typeReturn{owner: k.owner, transaction: k.transaction}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+		g.add(r.Returns)
+	}
+
+	return groups
+}
+
+// TypeReturns returns average forward returns by transaction type
+// of transactions notified since the date.
+func (s *Store) TypeReturns(_ context.Context, since time.Time, f insider.ReportFilter) ([]price.TypeReturn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tr []price.TypeReturn
+	for _, g := range s.groupReturns(since, f, false) {
+		tr = append(tr, g.result())
+	}
+
+	sort.Slice(tr, func(i, j int) bool {
+		return tr[i].Transaction < tr[j].Transaction
+	})
+
+	return tr, nil
+}
+
+// InsiderReturns returns average forward returns by insider and transaction type,
+// insiders with the best 20 days return go first.
+func (s *Store) InsiderReturns(_ context.Context, since time.Time, f insider.ReportFilter) ([]price.InsiderReturn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ir []price.InsiderReturn
+	for _, g := range s.groupReturns(since, f, true) {
+		ir = append(ir, price.InsiderReturn{Owner: g.owner, TypeReturn: g.result()})
+	}
+
+	// nulls last
+	sort.SliceStable(ir, func(i, j int) bool {
+		a, b := ir[i].Return20d, ir[j].Return20d
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a > *b
+	})
+
+	return ir, nil
+}
+
+// BuyReturns returns discretionary buys with the return after horizon trading days
+// and the benchmark return for the same period.
+func (s *Store) BuyReturns(_ context.Context, horizon int, benchmark string) ([]score.BuyReturn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rr []score.BuyReturn
+	for _, r := range s.transactions {
+		if r.Transaction.Transaction != insider.Buy || r.planned || r.Cost <= 0 {
+			continue
+		}
+
+		at := day(r.TransactionDate)
+
+		// the trading calendar of transaction_returns
+		days := s.tradingDays(at)
+		b0, ok := s.closeAt(benchmark, at)
+		if !ok {
+			continue
+		}
+
+		fwd := s.forward(r.Ticker, days, horizon, r.Cost)
+		bf := s.forward(benchmark, days, horizon, b0)
+		if fwd == nil || bf == nil {
+			continue
+		}
+
+		rr = append(rr, score.BuyReturn{
+			TransactionID:   r.ID,
+			Owner:           r.Owner,
+			OwnerCIK:        r.OwnerCIK,
+			Relationship:    r.Relationship,
+			Return:          *fwd,
+			BenchmarkReturn: *bf,
+		})
+	}
+
+	return rr, nil
+}
+
+// SaveScores inserts or updates scores.
+func (s *Store) SaveScores(_ context.Context, scores []score.Score) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sc := range scores {
+		s.scores[scoreKey{sc.Kind, sc.Name, sc.Horizon}] = sc
+	}
+
+	return nil
+}
+
+// Scores returns scores of the kind for the horizon, the best go first.
+func (s *Store) Scores(_ context.Context, kind score.Kind, horizon int) ([]score.Score, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sc []score.Score
+	for k, v := range s.scores {
+		if k.kind == kind && k.horizon == horizon {
+			sc = append(sc, v)
+		}
+	}
+
+	sort.Slice(sc, func(i, j int) bool {
+		if sc[i].AvgExcessReturn != sc[j].AvgExcessReturn {
+			return sc[i].AvgExcessReturn > sc[j].AvgExcessReturn
+		}
+		return sc[i].Name < sc[j].Name
+	})
+
+	return sc, nil
+}
+
+// TransactionScores returns buys of the report day annotated with
+// the insider's scores for all horizons.
+func (s *Store) TransactionScores(_ context.Context, f insider.ReportFilter) ([]score.TransactionScore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ts []score.TransactionScore
+	for _, r := range s.transactions {
+		if !r.report(f) || r.Transaction.Transaction != insider.Buy {
+			continue
+		}
+
+		for k, sc := range s.scores {
+			if k.kind != score.KindInsider || k.name != r.OwnerID() {
+				continue
+			}
+
+			ts = append(ts, score.TransactionScore{
+				TransactionID: r.ID,
+				Ticker:        r.Ticker,
+				Owner:         r.Owner,
+				Relationship:  r.Relationship,
+				Value:         r.Value,
+				Score:         sc,
+			})
+		}
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Value != ts[j].Value {
+			return ts[i].Value > ts[j].Value
+		}
+		if ts[i].TransactionID != ts[j].TransactionID {
+			return ts[i].TransactionID < ts[j].TransactionID
+		}
+		return ts[i].Horizon < ts[j].Horizon
+	})
+
+	return ts, nil
+}
+
+// Rules returns all alert rules.
+func (s *Store) Rules(_ context.Context) ([]alert.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := make([]alert.Rule, 0, len(s.rules))
+	for _, v := range s.rules {
+		r = append(r, v)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Name < r[j].Name
+	})
+
+	return r, nil
+}
+
+// SaveRule inserts or updates the alert rule.
+func (s *Store) SaveRule(_ context.Context, r alert.Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.CreatedAt = time.Now()
+	if old, ok := s.rules[r.Name]; ok {
+		r.CreatedAt = old.CreatedAt
+	}
+	s.rules[r.Name] = r
+
+	return nil
+}
+
+// DeleteRule deletes the alert rule with its firing history.
+func (s *Store) DeleteRule(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rules, name)
+	s.firings = slices.DeleteFunc(s.firings, func(f alert.Firing) bool {
+		return f.RuleName == name
+	})
+
+	return nil
+}
+
+// Fire records the firing of the rule, it returns false
+// if the rule has already fired for the transaction.
+func (s *Store) Fire(_ context.Context, rule, transactionID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rules[rule]; !ok {
+		return false, fmt.Errorf("failed insert firing: rule %q not found", rule)
+	}
+
+	if !slices.ContainsFunc(s.transactions, func(r row) bool { return r.ID == transactionID }) {
+		return false, fmt.Errorf("failed insert firing: transaction %q not found", transactionID)
+	}
+
+	if slices.ContainsFunc(s.firings, func(f alert.Firing) bool {
+		return f.RuleName == rule && f.TransactionID == transactionID
+	}) {
+		return false, nil
+	}
+
+	s.firings = append(s.firings, alert.Firing{
+		RuleName:      rule,
+		TransactionID: transactionID,
+		FiredAt:       time.Now(),
+	})
+
+	return true, nil
+}
+
+// Deliver marks the firing of the rule delivered.
+func (s *Store) Deliver(_ context.Context, rule, transactionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.firings {
+		if f.RuleName == rule && f.TransactionID == transactionID {
+			now := time.Now()
+			s.firings[i].DeliveredAt = &now
+		}
+	}
+
+	return nil
+}
+
+// Undelivered returns transactions of pending firings of the rule,
+// the oldest firing first.
+func (s *Store) Undelivered(_ context.Context, rule string) (insider.Transactions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tr insider.Transactions
+	for _, f := range s.firings {
+		if f.RuleName != rule || f.DeliveredAt != nil {
+			continue
+		}
+
+		i := slices.IndexFunc(s.transactions, func(r row) bool { return r.ID == f.TransactionID })
+		if i >= 0 {
+			tr = append(tr, s.transactions[i].Transaction)
+		}
+	}
+
+	return tr, nil
+}
+
+// Firings returns the firing history of the rule, the latest go first.
+func (s *Store) Firings(_ context.Context, rule string, limit int) ([]alert.Firing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var f []alert.Firing
+	for i := len(s.firings) - 1; i >= 0 && len(f) < limit; i-- {
+		if s.firings[i].RuleName == rule {
+			f = append(f, s.firings[i])
+		}
+	}
+
+	return f, nil
+}
+
+// StartRun inserts the run and sets its ID.
+func (s *Store) StartRun(_ context.Context, r *run.Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ID = int64(len(s.runs) + 1)
+	s.runs = append(s.runs, cloneRun(*r))
+
+	return nil
+}
+
+func (s *Store) SaveRun(_ context.Context, r run.Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.ID < 1 || r.ID > int64(len(s.runs)) {
+		return nil
+	}
+
+	// the start and the day are set once by StartRun
+	stored := &s.runs[r.ID-1]
+	r.StartedAt, r.Day = stored.StartedAt, stored.Day
+	*stored = cloneRun(r)
+
+	return nil
+}
+
+func cloneRun(r run.Run) run.Run {
+	r.SourceURLs = slices.Clone(r.SourceURLs)
+	if r.SourceURLs == nil {
+		r.SourceURLs = []string{}
+	}
+
+	statuses := make(map[string]run.Status, len(r.Publish))
+	for k, v := range r.Publish {
+		statuses[k] = v
+	}
+	r.Publish = statuses
+
+	health := make(map[string]run.SourceHealth, len(r.Sources))
+	for k, v := range r.Sources {
+		health[k] = v
+	}
+	r.Sources = health
+
+	return r
+}
+
+func (s *Store) Fetched(_ context.Context, d time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.ContainsFunc(s.runs, func(r run.Run) bool {
+		return day(r.Day) == day(d) && r.Status == run.StatusSucceeded
+	}), nil
+}
+
+func (s *Store) LastFetched(context.Context) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last string
+	for _, r := range s.runs {
+		if r.Status == run.StatusSucceeded && day(r.Day) > last {
+			last = day(r.Day)
+		}
+	}
+
+	if last == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.DateOnly, last)
+}
+
+func (s *Store) Runs(_ context.Context, limit int) ([]run.Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var r []run.Run
+	for i := len(s.runs) - 1; i >= 0 && len(r) < limit; i-- {
+		r = append(r, cloneRun(s.runs[i]))
+	}
+
+	return r, nil
+}
+
+// PendingDigests returns not delivered digests, the oldest first.
+func (s *Store) PendingDigests(_ context.Context) ([]publish.Digest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var d []publish.Digest
+	for _, v := range s.digests {
+		if v.DeliveredAt == nil {
+			d = append(d, v)
+		}
+	}
+
+	sort.Slice(d, func(i, j int) bool {
+		if !d[i].Day.Equal(d[j].Day) {
+			return d[i].Day.Before(d[j].Day)
+		}
+		return d[i].Key < d[j].Key
+	})
+
+	return d, nil
+}
+
+// ClaimDigest leases the pending digest, the lease of the crashed
+// publisher expires, so its digest is retried.
+func (s *Store) ClaimDigest(_ context.Context, key string, lease time.Duration) (publish.Digest, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	d, ok := s.digests[key]
+	if until, locked := s.leases[key]; !ok || d.DeliveredAt != nil || locked && until.After(now) {
+		return publish.Digest{}, false, nil
+	}
+	s.leases[key] = now.Add(lease)
+
+	return d, true, nil
+}
+
+func (s *Store) DigestParts(_ context.Context, key string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var parts []string
+	for part := range s.parts[key] {
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+
+	return parts, nil
+}
+
+func (s *Store) SaveDigestPart(_ context.Context, key, part string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.parts[key] == nil {
+		s.parts[key] = make(map[string]struct{})
+	}
+	s.parts[key][part] = struct{}{}
+
+	return nil
+}
+
+// ReleaseDigest records the attempt of the claimed digest.
+func (s *Store) ReleaseDigest(_ context.Context, key string, derr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.leases, key)
+
+	d, ok := s.digests[key]
+	if !ok {
+		return nil
+	}
+
+	d.Attempts++
+	d.LastError = ""
+	if derr != nil {
+		d.LastError = derr.Error()
+	} else {
+		now := time.Now()
+		d.DeliveredAt = &now
+	}
+	s.digests[key] = d
+
+	return nil
+}
+
+// PendingEvents returns not published events in the order of notification date.
+func (s *Store) PendingEvents(_ context.Context, limit int) ([]event.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var e []event.Event
+	for _, v := range s.events {
+		if _, ok := s.published[v.ID]; !ok {
+			e = append(e, v)
+		}
+	}
+
+	// events are appended in the order of IDs
+	sort.SliceStable(e, func(i, j int) bool {
+		return e[i].NotificationDate.Before(e[j].NotificationDate)
+	})
+
+	if len(e) > limit {
+		e = e[:limit]
+	}
+
+	return e, nil
+}
+
+func (s *Store) MarkPublished(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.published[id] = struct{}{}
+
+	return nil
+}
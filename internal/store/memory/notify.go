@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/migrations"
+)
+
+// NotifyIngest sends the notification to subscribers.
+func (s *Store) NotifyIngest(_ context.Context, in run.Ingest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subs {
+		select {
+		case ch <- in:
+		default:
+			// slow subscribers miss notifications like after
+			// the lost connection in PostgreSQL
+		}
+	}
+
+	return nil
+}
+
+// Subscribe returns notifications until ctx is done,
+// then the channel is closed.
+func (s *Store) Subscribe(ctx context.Context) (<-chan run.Ingest, error) {
+	ch := make(chan run.Ingest, 1)
+
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// Migrator returns the migrator without migrations,
+// the memory has no schema.
+func (s *Store) Migrator() (migrations.Migrator, error) {
+	return migrator{}, nil
+}
+
+type migrator struct{}
+
+func (migrator) Up(context.Context) error                            { return nil }
+func (migrator) Down(context.Context, int) error                     { return nil }
+func (migrator) Force(context.Context, uint) error                   { return nil }
+func (migrator) Version(context.Context) (uint, bool, error)         { return 0, false, nil }
+func (migrator) Status(context.Context) ([]migrations.Status, error) { return nil, nil }
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RyabovNick/finviz_parser/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID is the key of the advisory lock
+// that keeps concurrent instances from migrating at the same time.
+const migrationLockID = 7_234_001
+
+// Migrator applies migrations in the golang-migrate format and keeps
+// the version in its schema_migrations table, so databases migrated
+// by the migrate CLI are supported.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []migrations.Migration
+}
+
+// Migrator reads migrations of the PostgreSQL backend.
+func (s *Store) Migrator() (migrations.Migrator, error) {
+	mm, err := migrations.Load(migrations.FS)
+	if err != nil {
+		return nil, fmt.Errorf("failed load migrations: %w", err)
+	}
+
+	return &Migrator{pool: s.pool, migrations: mm}, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(conn *pgxpool.Conn) error {
+		version, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if mg.Version <= version {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, mg.Version, mg.Up); err != nil {
+				return fmt.Errorf("failed migration %d_%s up: %w", mg.Version, mg.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down rolls back the number of applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(conn *pgxpool.Conn) error {
+		version, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mg := m.migrations[i]
+			if mg.Version > version {
+				continue
+			}
+
+			if mg.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mg.Version, mg.Name)
+			}
+
+			// version of the previous migration, 0 means nothing is applied
+			var prev uint
+			if i > 0 {
+				prev = m.migrations[i-1].Version
+			}
+
+			if err := m.apply(ctx, conn, prev, mg.Down); err != nil {
+				return fmt.Errorf("failed migration %d_%s down: %w", mg.Version, mg.Name, err)
+			}
+
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Force sets the version without running migrations,
+// it's used to clean the dirty state after the manual fix.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	return m.locked(ctx, func(conn *pgxpool.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+// Version returns the current version and whether the database is dirty.
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return 0, false, err
+	}
+
+	return readVersion(ctx, conn)
+}
+
+// Status returns all migrations with their state.
+func (m *Migrator) Status(ctx context.Context) ([]migrations.Status, error) {
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations.Statuses(m.migrations, version), nil
+}
+
+// locked runs fn holding the advisory lock on the dedicated connection.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, migrationLockID); err != nil {
+		return fmt.Errorf("failed lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLockID)
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// version returns the current version, it fails on the dirty database.
+func (m *Migrator) version(ctx context.Context, conn *pgxpool.Conn) (uint, error) {
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w: version %d", migrations.ErrDirty, version)
+	}
+
+	return version, nil
+}
+
+// apply runs the migration SQL, the version is marked dirty until it succeeds.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, version uint, sql string) error {
+	if err := setVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+
+	// migrations contain several statements, so they are sent
+	// without arguments with the simple protocol
+	if _, err := conn.Exec(ctx, sql); err != nil {
+		return err
+	}
+
+	return setVersion(ctx, conn, version, false)
+}
+
+func ensureVersionTable(ctx context.Context, conn *pgxpool.Conn) error {
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		);
+	`); err != nil {
+		return fmt.Errorf("failed create schema_migrations: %w", err)
+	}
+
+	return nil
+}
+
+func readVersion(ctx context.Context, conn *pgxpool.Conn) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := conn.QueryRow(ctx, `
+		SELECT version, dirty
+		FROM schema_migrations
+		LIMIT 1;
+	`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed select version: %w", err)
+	}
+
+	return uint(version), dirty, nil
+}
+
+func setVersion(ctx context.Context, conn *pgxpool.Conn, version uint, dirty bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations;`); err != nil {
+		return fmt.Errorf("failed delete version: %w", err)
+	}
+
+	// golang-migrate keeps no row when nothing is applied
+	if version > 0 || dirty {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO schema_migrations (version, dirty)
+			VALUES ($1, $2);
+		`, int64(version), dirty); err != nil {
+			return fmt.Errorf("failed insert version: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
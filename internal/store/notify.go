@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IngestChannel is the channel notified after transactions are saved.
+const IngestChannel = "finviz_ingest"
+
+// resubscribeDelay is the delay before listening again
+// after the connection is lost.
+const resubscribeDelay = 5 * time.Second
+
+// NotifyIngest sends the JSON payload to IngestChannel.
+func (s *Store) NotifyIngest(ctx context.Context, in run.Ingest) error {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if _, err := s.pool.Exec(ctx, `SELECT pg_notify($1, $2);`, IngestChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed notify: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe listens to IngestChannel on the dedicated connection until ctx
+// is done, then the channel is closed. The connection is re-established
+// if it's lost, notifications sent in the meantime are missed.
+func (s *Store) Subscribe(ctx context.Context) (<-chan run.Ingest, error) {
+	conn, err := s.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan run.Ingest)
+	go func() {
+		defer close(ch)
+
+		for {
+			err := s.wait(ctx, conn, ch)
+			conn.Release()
+			if ctx.Err() != nil {
+				return
+			}
+			slog.WarnContext(ctx, "ingest subscription", "err", err)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(resubscribeDelay):
+				}
+
+				conn, err = s.listen(ctx)
+				if err == nil {
+					break
+				}
+				slog.WarnContext(ctx, "ingest subscription", "err", err)
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (s *Store) listen(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed acquire connection: %w", err)
+	}
+
+	if _, err := conn.Exec(ctx, `LISTEN `+IngestChannel+`;`); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed listen: %w", err)
+	}
+
+	return conn, nil
+}
+
+// wait sends notifications to ch until the error.
+func (s *Store) wait(ctx context.Context, conn *pgxpool.Conn, ch chan<- run.Ingest) error {
+	// the connection is closed instead of being returned to the pool
+	// as it still listens to the channel
+	defer conn.Conn().Close(context.Background())
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var in run.Ingest
+		if err := json.Unmarshal([]byte(n.Payload), &in); err != nil {
+			slog.WarnContext(ctx, "invalid ingest notification", "payload", n.Payload, "err", err)
+			continue
+		}
+
+		select {
+		case ch <- in:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package sqlite
+
+// the pure-Go driver registers itself as "sqlite", the binary
+// doesn't need cgo
+import _ "github.com/glebarez/go-sqlite"
@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/RyabovNick/finviz_parser/migrations"
+)
+
+// Migrator applies SQLite migrations and keeps the version in the
+// schema_migrations table like the PostgreSQL migrator. SQLite runs DDL
+// in transactions, so the migration and its version are applied at once
+// and the database is dirty only after Force.
+type Migrator struct {
+	store      *Store
+	migrations []migrations.Migration
+}
+
+// Migrator reads migrations of the SQLite backend.
+func (s *Store) Migrator() (migrations.Migrator, error) {
+	mm, err := migrations.Load(migrations.SQLite())
+	if err != nil {
+		return nil, fmt.Errorf("failed load migrations: %w", err)
+	}
+
+	return &Migrator{store: s, migrations: mm}, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	for _, mg := range m.migrations {
+		err := m.store.immediate(ctx, func(conn *sql.Conn) error {
+			version, err := m.version(ctx, conn)
+			if err != nil {
+				return err
+			}
+
+			if mg.Version <= version {
+				return nil
+			}
+
+			return apply(ctx, conn, mg.Version, mg.Up)
+		})
+		if err != nil {
+			return fmt.Errorf("failed migration %d_%s up: %w", mg.Version, mg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down rolls back the number of applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+		mg := m.migrations[i]
+
+		// version of the previous migration, 0 means nothing is applied
+		var prev uint
+		if i > 0 {
+			prev = m.migrations[i-1].Version
+		}
+
+		var applied bool
+		err := m.store.immediate(ctx, func(conn *sql.Conn) error {
+			version, err := m.version(ctx, conn)
+			if err != nil {
+				return err
+			}
+
+			if mg.Version > version {
+				return nil
+			}
+			applied = true
+
+			if mg.Down == "" {
+				return errors.New("no down file")
+			}
+
+			return apply(ctx, conn, prev, mg.Down)
+		})
+		if err != nil {
+			return fmt.Errorf("failed migration %d_%s down: %w", mg.Version, mg.Name, err)
+		}
+
+		if applied {
+			steps--
+		}
+	}
+
+	return nil
+}
+
+// Force sets the version without running migrations,
+// it's used to clean the dirty state after the manual fix.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	return m.store.immediate(ctx, func(conn *sql.Conn) error {
+		if err := ensureVersionTable(ctx, conn); err != nil {
+			return err
+		}
+
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+// Version returns the current version and whether the database is dirty.
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	if err := ensureVersionTable(ctx, m.store.db); err != nil {
+		return 0, false, err
+	}
+
+	return readVersion(ctx, m.store.db)
+}
+
+// Status returns all migrations with their state.
+func (m *Migrator) Status(ctx context.Context) ([]migrations.Status, error) {
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations.Statuses(m.migrations, version), nil
+}
+
+// version returns the current version, it fails on the dirty database.
+func (m *Migrator) version(ctx context.Context, q querier) (uint, error) {
+	if err := ensureVersionTable(ctx, q); err != nil {
+		return 0, err
+	}
+
+	version, dirty, err := readVersion(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w: version %d", migrations.ErrDirty, version)
+	}
+
+	return version, nil
+}
+
+// apply runs the migration SQL and sets the version in the same transaction.
+func apply(ctx context.Context, q querier, version uint, sql string) error {
+	if _, err := q.ExecContext(ctx, sql); err != nil {
+		return err
+	}
+
+	return setVersion(ctx, q, version, false)
+}
+
+func ensureVersionTable(ctx context.Context, q querier) error {
+	if _, err := q.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL PRIMARY KEY,
+			dirty INTEGER NOT NULL
+		);
+	`); err != nil {
+		return fmt.Errorf("failed create schema_migrations: %w", err)
+	}
+
+	return nil
+}
+
+func readVersion(ctx context.Context, q querier) (uint, bool, error) {
+	type row struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+
+	r, err := collectOne[row](query(ctx, q, `
+		SELECT version, dirty
+		FROM schema_migrations
+		LIMIT 1;
+	`))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed select version: %w", err)
+	}
+
+	return uint(r.Version), r.Dirty, nil
+}
+
+func setVersion(ctx context.Context, q querier, version uint, dirty bool) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM schema_migrations;`); err != nil {
+		return fmt.Errorf("failed delete version: %w", err)
+	}
+
+	// golang-migrate keeps no row when nothing is applied
+	if version > 0 || dirty {
+		if _, err := q.ExecContext(ctx, `
+			INSERT INTO schema_migrations (version, dirty)
+			VALUES (?1, ?2);
+		`, int64(version), dirty); err != nil {
+			return fmt.Errorf("failed insert version: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/RyabovNick/finviz_parser/internal/run"
+)
+
+// NotifyIngest sends the notification to subscribers of this process,
+// SQLite has no way to notify other processes.
+func (s *Store) NotifyIngest(ctx context.Context, in run.Ingest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subs {
+		select {
+		case ch <- in:
+		default:
+			// slow subscribers miss notifications like after
+			// the lost connection in PostgreSQL
+		}
+	}
+
+	return nil
+}
+
+// Subscribe returns notifications sent by this process until ctx is done,
+// then the channel is closed.
+func (s *Store) Subscribe(ctx context.Context) (<-chan run.Ingest, error) {
+	ch := make(chan run.Ingest, 1)
+
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,259 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// timeLayout keeps timestamps sortable as text.
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// timeLayouts are parsed in the order, SQLite functions
+// return timestamps without fractional seconds.
+var timeLayouts = []string{timeLayout, time.DateTime, time.DateOnly, time.RFC3339Nano}
+
+// ts formats the timestamp in UTC.
+func ts(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// nts formats the optional timestamp, nil is stored as NULL.
+func nts(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+
+	return ts(*t)
+}
+
+// date formats the date of t.
+func date(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+// jsonText encodes arrays and objects, nil is stored as NULL.
+func jsonText(v any) (any, error) {
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// collect scans rows into structs by db tags like pgx.RowToStructByName,
+// every column has to match the field.
+func collect[T any](rows *sql.Rows, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []T
+	for rows.Next() {
+		var v T
+		dest, err := fields(reflect.ValueOf(&v).Elem(), cols)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, rows.Err()
+}
+
+// collectOne returns the first row, sql.ErrNoRows if there is no rows.
+func collectOne[T any](rows *sql.Rows, err error) (T, error) {
+	var zero T
+
+	out, err := collect[T](rows, err)
+	if err != nil {
+		return zero, err
+	}
+
+	if len(out) == 0 {
+		return zero, sql.ErrNoRows
+	}
+
+	return out[0], nil
+}
+
+// collectValues scans the single column.
+func collectValues[T any](rows *sql.Rows, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		var v T
+		if err := rows.Scan(scanner(&v)); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, rows.Err()
+}
+
+// fields returns scan destinations of struct fields in the order of cols,
+// embedded structs without the tag are flattened.
+func fields(v reflect.Value, cols []string) ([]any, error) {
+	byName := make(map[string]reflect.Value)
+
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("db")
+			if f.Anonymous && f.Type.Kind() == reflect.Struct && tag == "" {
+				walk(v.Field(i))
+				continue
+			}
+
+			if tag == "" || tag == "-" || !f.IsExported() {
+				continue
+			}
+			byName[tag] = v.Field(i)
+		}
+	}
+	walk(v)
+
+	dest := make([]any, len(cols))
+	for i, c := range cols {
+		f, ok := byName[c]
+		if !ok {
+			return nil, fmt.Errorf("no field for column %q of %s", c, v.Type())
+		}
+		dest[i] = scanner(f.Addr().Interface())
+	}
+
+	return dest, nil
+}
+
+// scanner wraps destinations the driver can't convert from text.
+func scanner(p any) any {
+	switch p := p.(type) {
+	case *time.Time:
+		return timeScanner{p}
+	case **time.Time:
+		return nullTimeScanner{p}
+	case *json.RawMessage:
+		return rawScanner{p}
+	}
+
+	switch reflect.TypeOf(p).Elem().Kind() {
+	case reflect.Slice, reflect.Map:
+		return jsonScanner{p}
+	}
+
+	return p
+}
+
+type timeScanner struct {
+	t *time.Time
+}
+
+func (s timeScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*s.t = v.UTC()
+		return nil
+	case string:
+		return s.parse(v)
+	case []byte:
+		return s.parse(string(v))
+	case nil:
+		return errors.New("time is NULL")
+	default:
+		return fmt.Errorf("time from %T", src)
+	}
+}
+
+func (s timeScanner) parse(v string) error {
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, v, time.UTC)
+		if err == nil {
+			*s.t = t
+			return nil
+		}
+	}
+
+	return fmt.Errorf("parse time %q", v)
+}
+
+type nullTimeScanner struct {
+	t **time.Time
+}
+
+func (s nullTimeScanner) Scan(src any) error {
+	if src == nil {
+		*s.t = nil
+		return nil
+	}
+
+	var t time.Time
+	if err := (timeScanner{&t}).Scan(src); err != nil {
+		return err
+	}
+	*s.t = &t
+
+	return nil
+}
+
+type rawScanner struct {
+	m *json.RawMessage
+}
+
+func (s rawScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		*s.m = json.RawMessage(v)
+	case []byte:
+		*s.m = append(json.RawMessage(nil), v...)
+	case nil:
+		*s.m = nil
+	default:
+		return fmt.Errorf("json from %T", src)
+	}
+
+	return nil
+}
+
+// jsonScanner decodes arrays and objects, NULL keeps the zero value.
+type jsonScanner struct {
+	v any
+}
+
+func (s jsonScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), s.v)
+	case []byte:
+		return json.Unmarshal(v, s.v)
+	case nil:
+		return nil
+	default:
+		return fmt.Errorf("json from %T", src)
+	}
+}
@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   *float64
+	}{
+		{name: "empty"},
+		{name: "odd", values: []float64{1, 2, 10}, want: ptr(2.0)},
+		{name: "even", values: []float64{1, 2, 4, 10}, want: ptr(3.0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, median(tt.values))
+		})
+	}
+}
+
+func TestTimeScanner(t *testing.T) {
+	want := time.Date(2024, 6, 24, 21, 30, 5, 123_000_000, time.UTC)
+
+	tests := []struct {
+		name string
+		src  any
+		want time.Time
+	}{
+		{name: "stored", src: ts(want), want: want},
+		{name: "bytes", src: []byte(ts(want)), want: want},
+		{name: "function", src: "2024-06-24 21:30:05", want: want.Truncate(time.Second)},
+		{name: "date", src: "2024-06-24", want: time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)},
+		{name: "driver", src: want.In(time.FixedZone("EST", -5*3600)), want: want},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got time.Time
+			require.NoError(t, scanner(&got).(sql.Scanner).Scan(tt.src))
+			assert.True(t, tt.want.Equal(got), got)
+		})
+	}
+
+	t.Run("null", func(t *testing.T) {
+		var got time.Time
+		assert.Error(t, scanner(&got).(sql.Scanner).Scan(nil))
+
+		got2 := &want
+		require.NoError(t, scanner(&got2).(sql.Scanner).Scan(nil))
+		assert.Nil(t, got2)
+	})
+}
+
+func TestJSON(t *testing.T) {
+	v, err := jsonText([]string(nil))
+	require.NoError(t, err)
+	assert.Nil(t, v, "nil slice is NULL")
+
+	v, err = jsonText([]string{"first_buy"})
+	require.NoError(t, err)
+	assert.Equal(t, `["first_buy"]`, v)
+
+	var flags []string
+	require.NoError(t, scanner(&flags).(sql.Scanner).Scan(v))
+	assert.Equal(t, []string{"first_buy"}, flags)
+
+	var raw json.RawMessage
+	require.NoError(t, scanner(&raw).(sql.Scanner).Scan(`{"a":1}`))
+	assert.JSONEq(t, `{"a":1}`, string(raw))
+}
+
+func TestFields(t *testing.T) {
+	var tr insider.Transaction
+	dest, err := fields(reflect.ValueOf(&tr).Elem(), []string{"id", "notification_date", "anomaly_flags"})
+	require.NoError(t, err)
+	assert.Len(t, dest, 3, "embedded structs are flattened")
+
+	_, err = fields(reflect.ValueOf(&tr).Elem(), []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestNewID(t *testing.T) {
+	id, err := newID()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+}
+
+func TestNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "finviz.db")
+
+	s, err := New(context.Background(), path)
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+
+	assert.FileExists(t, path)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,1341 @@
+// Package sqlite is the embedded storage backend for single-user
+// deployments, it keeps the same data as the PostgreSQL store in one file.
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/event"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+)
+
+// driverName is registered by the imported driver.
+const driverName = "sqlite"
+
+// pragmas are set on every connection: WAL lets reports read while
+// the digest is delivered, busy_timeout waits for other processes.
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+// transactionColumns are columns of insider.Transaction.
+const transactionColumns = `id, ticker, owner, relationship, transaction_date,
+	transaction_type, cost, shares, value, shares_total, notification_date, url,
+	COALESCE(issuer_cik, '') as issuer_cik, COALESCE(owner_cik, '') as owner_cik,
+	holdings_change, value_to_median, first_buy, anomaly_flags`
+
+// reportFilter is the condition of insider.ReportFilter,
+// ?1 is ExcludePlanned and ?2 is Day.
+const reportFilter = `date(notification_date) = ?2
+			AND NOT (?1 AND planned)`
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// query runs the query, the driver fails with "not an error (21)"
+// if whitespace follows the last semicolon, so it's trimmed.
+func query(ctx context.Context, q querier, stmt string, args ...any) (*sql.Rows, error) {
+	return q.QueryContext(ctx, strings.TrimSpace(stmt), args...)
+}
+
+type Store struct {
+	db *sql.DB
+
+	mu   sync.Mutex
+	subs map[chan run.Ingest]struct{}
+}
+
+// New opens the database file, it's created if it doesn't exist.
+func New(ctx context.Context, path string) (*Store, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	db, err := sql.Open(driverName, path+sep+pragmas)
+	if err != nil {
+		return nil, fmt.Errorf("failed open: %w", err)
+	}
+
+	// ping
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed ping: %w", err)
+	}
+
+	return &Store{
+		db:   db,
+		subs: make(map[chan run.Ingest]struct{}),
+	}, nil
+}
+
+func (s *Store) Close() {
+	s.db.Close()
+}
+
+// immediate runs fn in the transaction that takes the write lock at once,
+// so rows read before writes can't be changed by concurrent writers.
+func (s *Store) immediate(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE;`); err != nil {
+		return fmt.Errorf("failed begin: %w", err)
+	}
+
+	if err := fn(conn); err != nil {
+		conn.ExecContext(context.Background(), `ROLLBACK;`)
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, `COMMIT;`); err != nil {
+		conn.ExecContext(context.Background(), `ROLLBACK;`)
+		return fmt.Errorf("failed commit: %w", err)
+	}
+
+	return nil
+}
+
+// newID returns the random UUID.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("rand: %w", err)
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// InsertTransactions inserts transactions which are not stored yet,
+// their TransactionCreated events and pending digests in one database
+// transaction, it returns inserted transactions with IDs.
+func (s *Store) InsertTransactions(ctx context.Context, tr insider.Transactions, digests ...publish.Digest) (insider.Transactions, error) {
+	// the digest is enqueued on the day without new transactions too
+	if len(tr) == 0 && len(digests) == 0 {
+		return nil, nil
+	}
+
+	var fresh insider.Transactions
+	err := s.immediate(ctx, func(conn *sql.Conn) error {
+		var err error
+		fresh, err = insertTransactions(ctx, conn, tr)
+		if err != nil {
+			return err
+		}
+
+		if err := insertEvents(ctx, conn, fresh); err != nil {
+			return err
+		}
+
+		for _, d := range digests {
+			// the digest of the day is enqueued once, reruns keep its state
+			if _, err := conn.ExecContext(ctx, `
+				INSERT INTO digests (key, day, sink)
+				VALUES (?1, ?2, ?3)
+				ON CONFLICT (key) DO NOTHING;
+			`, d.Key, date(d.Day), d.Sink); err != nil {
+				return fmt.Errorf("failed insert digest %s: %w", d.Key, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fresh, nil
+}
+
+func insertTransactions(ctx context.Context, q querier, tr insider.Transactions) (insider.Transactions, error) {
+	if len(tr) == 0 {
+		return nil, nil
+	}
+
+	stored, err := storedTransactions(ctx, q, tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed get stored transactions: %w", err)
+	}
+
+	query := sq.Insert("transactions").Columns("id", "ticker", "owner", "relationship",
+		"transaction_date", "transaction_type", "cost", "shares", "value",
+		"shares_total", "notification_date", "url", "issuer_cik", "owner_cik",
+		"holdings_change", "value_to_median", "first_buy", "anomaly_flags")
+
+	var fresh insider.Transactions
+	for _, t := range tr {
+		// skip duplicates of stored rows, of the same page and of other sources
+		if !stored.Add(t) {
+			continue
+		}
+
+		t.ID, err = newID()
+		if err != nil {
+			return nil, err
+		}
+
+		flags, err := jsonText(t.Flags)
+		if err != nil {
+			return nil, err
+		}
+
+		fresh = append(fresh, t)
+		query = query.Values(t.ID, t.Ticker, t.Owner, t.Relationship, ts(t.TransactionDate),
+			string(t.Transaction), t.Cost, t.Shares, t.Value, t.SharesTotal, ts(t.SEC.NotificationDate), t.SEC.URL,
+			sq.Expr("NULLIF(?, '')", t.IssuerCIK), sq.Expr("NULLIF(?, '')", t.OwnerCIK),
+			t.HoldingsChange, t.ValueToMedian, t.FirstBuy, flags)
+	}
+
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("transactions insert to sql: %w", err)
+	}
+
+	if _, err := q.ExecContext(ctx, sql, args...); err != nil {
+		return nil, fmt.Errorf("transactions insert exec: %w", err)
+	}
+
+	return fresh, nil
+}
+
+// insertEvents writes TransactionCreated events to the outbox.
+func insertEvents(ctx context.Context, q querier, tr insider.Transactions) error {
+	if len(tr) == 0 {
+		return nil
+	}
+
+	query := sq.Insert("outbox").Columns("event_type", "event_key", "notification_date", "payload")
+	for _, t := range tr {
+		e, err := event.TransactionCreated(t)
+		if err != nil {
+			return err
+		}
+
+		query = query.Values(e.Type, e.Key, ts(e.NotificationDate), string(e.Data))
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("outbox insert to sql: %w", err)
+	}
+
+	if _, err := q.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed insert events: %w", err)
+	}
+
+	return nil
+}
+
+// storedTransactions returns Dedup of stored transactions notified
+// in the same period as tr, sources may differ in notification time by hours.
+func storedTransactions(ctx context.Context, q querier, tr insider.Transactions) (*insider.Dedup, error) {
+	from, to := tr[0].NotificationDate, tr[0].NotificationDate
+	tickers := make([]string, 0, len(tr))
+	for _, t := range tr {
+		if t.NotificationDate.Before(from) {
+			from = t.NotificationDate
+		}
+		if t.NotificationDate.After(to) {
+			to = t.NotificationDate
+		}
+		tickers = append(tickers, t.Ticker)
+	}
+
+	tickersJSON, err := jsonText(tickers)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := collect[insider.Transaction](query(ctx, q, `
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE notification_date BETWEEN ?1 AND ?2
+			AND ticker IN (SELECT value FROM json_each(?3));
+	`, ts(from.AddDate(0, 0, -1)), ts(to.AddDate(0, 0, 1)), tickersJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	return insider.NewDedup(stored), nil
+}
+
+// History returns the insiders' history for the transactions.
+// SQLite has no percentile_cont, so the median is computed here.
+func (s *Store) History(ctx context.Context, tr insider.Transactions) ([]insider.History, error) {
+	type key struct {
+		owner, ticker string
+		transaction   insider.TransactionType
+	}
+
+	var history []insider.History
+	seen := make(map[key]struct{}, len(tr))
+	for _, t := range tr {
+		k := key{t.Owner, t.Ticker, t.Transaction}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+
+		// insiders and companies are identified by CIKs when known,
+		// so name variations and ticker changes share the history.
+		// The owner's CIK is looked up by the name at the same issuer,
+		// namesakes at other companies are different insiders.
+		issuerCIK := t.IssuerCIK
+		if issuerCIK == "" {
+			ciks, err := collectValues[string](query(ctx, s.db, `
+				SELECT issuer_cik
+				FROM transactions
+				WHERE ticker = ?1 AND issuer_cik IS NOT NULL
+				LIMIT 1;
+			`, t.Ticker))
+			if err != nil {
+				return nil, fmt.Errorf("failed select issuer cik: %w", err)
+			}
+			if len(ciks) > 0 {
+				issuerCIK = ciks[0]
+			}
+		}
+
+		ownerCIK := t.OwnerCIK
+		if ownerCIK == "" {
+			ciks, err := collectValues[string](query(ctx, s.db, `
+				SELECT owner_cik
+				FROM transactions
+				WHERE owner = ?1
+					AND (ticker = ?2 OR issuer_cik = NULLIF(?3, ''))
+					AND owner_cik IS NOT NULL
+				LIMIT 1;
+			`, t.Owner, t.Ticker, issuerCIK))
+			if err != nil {
+				return nil, fmt.Errorf("failed select owner cik: %w", err)
+			}
+			if len(ciks) > 0 {
+				ownerCIK = ciks[0]
+			}
+		}
+
+		values, err := collectValues[float64](query(ctx, s.db, `
+			SELECT value
+			FROM transactions
+			WHERE `+sameOwner+`
+				AND transaction_type = ?3
+			ORDER BY value;
+		`, t.Owner, ownerCIK, string(t.Transaction)))
+		if err != nil {
+			return nil, fmt.Errorf("failed select history values: %w", err)
+		}
+
+		lastBuy, err := collectValues[*time.Time](query(ctx, s.db, `
+			SELECT max(transaction_date)
+			FROM transactions
+			WHERE `+sameOwner+`
+				AND (ticker = ?3 OR issuer_cik = NULLIF(?4, ''))
+				AND transaction_type = 'Buy';
+		`, t.Owner, ownerCIK, t.Ticker, issuerCIK))
+		if err != nil {
+			return nil, fmt.Errorf("failed select last buy: %w", err)
+		}
+
+		history = append(history, insider.History{
+			Owner:        t.Owner,
+			Ticker:       t.Ticker,
+			Transaction:  t.Transaction,
+			Transactions: len(values),
+			MedianValue:  median(values),
+			LastBuy:      lastBuy[0],
+		})
+	}
+
+	return history, nil
+}
+
+// sameOwner matches transactions of the owner ?1 with the CIK ?2,
+// only by the name if the CIK is unknown.
+const sameOwner = `(owner_cik = NULLIF(?2, '') OR (?2 = '' AND owner = ?1))`
+
+// median is percentile_cont(0.5) of sorted values, nil if there are none.
+func median(sorted []float64) *float64 {
+	n := len(sorted)
+	if n == 0 {
+		return nil
+	}
+
+	m := sorted[n/2]
+	if n%2 == 0 {
+		m = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	return &m
+}
+
+// UnenrichedTransactions returns transactions for the last week
+// without details from the Form 4 filing.
+func (s *Store) UnenrichedTransactions(ctx context.Context) (insider.Transactions, error) {
+	tr, err := collect[insider.Transaction](query(ctx, s.db, `
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE accession_number IS NULL
+			AND notification_date > date('now', '-7 days')
+		ORDER BY notification_date;
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed select unenriched transactions: %w", err)
+	}
+
+	return tr, nil
+}
+
+// NewestNotification returns the notification date of the newest transaction.
+func (s *Store) NewestNotification(ctx context.Context) (time.Time, error) {
+	newest, err := collectValues[*time.Time](query(ctx, s.db, `
+		SELECT max(notification_date)
+		FROM transactions;
+	`))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed select newest notification: %w", err)
+	}
+
+	if len(newest) == 0 || newest[0] == nil {
+		return time.Time{}, nil
+	}
+
+	return *newest[0], nil
+}
+
+// SaveFiling stores details from the Form 4 filing against the transaction.
+// The issuer's CIK is also set to transactions of the same ticker without it,
+// the owner's CIK to transactions of the same name at the same issuer.
+func (s *Store) SaveFiling(ctx context.Context, transactionID string, f insider.Filing) error {
+	footnotes, err := jsonText(f.Footnotes)
+	if err != nil {
+		return err
+	}
+
+	return s.immediate(ctx, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, `
+			UPDATE transactions
+			SET accession_number = ?2,
+				transaction_code = ?3,
+				plan_10b5_1 = ?4,
+				direct_ownership = ?5,
+				footnotes = ?6,
+				planned = ?7,
+				issuer_cik = NULLIF(?8, ''),
+				owner_cik = NULLIF(?9, '')
+			WHERE id = ?1;
+		`, transactionID, f.AccessionNumber, string(f.TransactionCode), f.Plan10b51, f.DirectOwnership, footnotes, f.Planned(),
+			f.IssuerCIK, f.OwnerCIK); err != nil {
+			return fmt.Errorf("failed update filing: %w", err)
+		}
+
+		if _, err := conn.ExecContext(ctx, `
+			UPDATE transactions
+			SET issuer_cik = (SELECT issuer_cik FROM transactions WHERE id = ?1)
+			WHERE issuer_cik IS NULL
+				AND ticker = (SELECT ticker FROM transactions WHERE id = ?1);
+		`, transactionID); err != nil {
+			return fmt.Errorf("failed update issuer cik: %w", err)
+		}
+
+		// issuer CIKs are set above, so the ticker change matches too
+		if _, err := conn.ExecContext(ctx, `
+			UPDATE transactions
+			SET owner_cik = (SELECT owner_cik FROM transactions WHERE id = ?1)
+			WHERE owner_cik IS NULL
+				AND owner = (SELECT owner FROM transactions WHERE id = ?1)
+				AND (ticker = (SELECT ticker FROM transactions WHERE id = ?1)
+					OR issuer_cik = (SELECT issuer_cik FROM transactions WHERE id = ?1));
+		`, transactionID); err != nil {
+			return fmt.Errorf("failed update owner cik: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) TransactionTypeCount(ctx context.Context, f insider.ReportFilter) ([]insider.TransactionTypeCount, error) {
+	tc, err := collect[insider.TransactionTypeCount](query(ctx, s.db, `
+		SELECT transaction_type, count(*) as transaction_count, sum(value) as total_value
+		FROM transactions
+		WHERE `+reportFilter+`
+		GROUP BY transaction_type
+		ORDER BY transaction_type;
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select transaction type count: %w", err)
+	}
+
+	return tc, nil
+}
+
+// DailyTotals returns totals by notification day and type
+// from since to the day of the filter.
+func (s *Store) DailyTotals(ctx context.Context, since time.Time, f insider.ReportFilter) ([]insider.DailyTotal, error) {
+	dt, err := collect[insider.DailyTotal](query(ctx, s.db, `
+		SELECT date(notification_date) AS day, transaction_type,
+			count(*) as transaction_count, sum(value) as total_value
+		FROM transactions
+		WHERE date(notification_date) BETWEEN ?2 AND ?3
+			AND NOT (?1 AND planned)
+		GROUP BY day, transaction_type
+		ORDER BY day, transaction_type;
+	`, f.ExcludePlanned, date(since), date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select daily totals: %w", err)
+	}
+
+	return dt, nil
+}
+
+func (s *Store) RelationshipCount(ctx context.Context, f insider.ReportFilter) ([]insider.RelationshipCount, error) {
+	rc, err := collect[insider.RelationshipCount](query(ctx, s.db, `
+		SELECT relationship, transaction_type, count(*) as transaction_count, sum(value) as total_value
+		FROM transactions
+		WHERE `+reportFilter+`
+		GROUP BY relationship, transaction_type
+		ORDER BY total_value DESC;
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select relationship count: %w", err)
+	}
+
+	return rc, nil
+}
+
+// TopBuy returns tickers with the largest net buy: buys minus sales.
+func (s *Store) TopBuy(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
+	tt, err := s.netValue(ctx, f, "DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed select top buy: %w", err)
+	}
+
+	return tt, nil
+}
+
+// TopSell returns tickers with the largest net sale: buys minus sales.
+func (s *Store) TopSell(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
+	tt, err := s.netValue(ctx, f, "ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed select top sell: %w", err)
+	}
+
+	return tt, nil
+}
+
+func (s *Store) netValue(ctx context.Context, f insider.ReportFilter, order string) ([]insider.TotalTransaction, error) {
+	return collect[insider.TotalTransaction](query(ctx, s.db, `
+		SELECT ticker,
+			sum(CASE WHEN transaction_type = 'Buy' THEN value ELSE -value END) as total_value
+		FROM transactions
+		WHERE `+reportFilter+`
+			AND transaction_type IN ('Buy', 'Sale')
+		GROUP BY ticker
+		ORDER BY total_value `+order+`
+		LIMIT 20;
+	`, f.ExcludePlanned, date(f.Day)))
+}
+
+// UnusualTransactions returns transactions of the report day flagged at ingest.
+func (s *Store) UnusualTransactions(ctx context.Context, f insider.ReportFilter) (insider.Transactions, error) {
+	tr, err := collect[insider.Transaction](query(ctx, s.db, `
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE `+reportFilter+`
+			AND json_array_length(anomaly_flags) > 0
+		ORDER BY value DESC;
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select unusual transactions: %w", err)
+	}
+
+	return tr, nil
+}
+
+// TopPlannedSell returns tickers with the largest sales
+// made under Rule 10b5-1 plans on the report day.
+func (s *Store) TopPlannedSell(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
+	tt, err := collect[insider.TotalTransaction](query(ctx, s.db, `
+		SELECT ticker, sum(value) as total_value
+		FROM transactions
+		WHERE date(notification_date) = ?1
+			AND planned
+			AND transaction_type = 'Sale'
+		GROUP BY ticker
+		ORDER BY total_value DESC
+		LIMIT 20;
+	`, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select top planned sell: %w", err)
+	}
+
+	return tt, nil
+}
+
+func (s *Store) SaleTicker(ctx context.Context, f insider.ReportFilter) (insider.Tickers, error) {
+	t, err := collectValues[string](query(ctx, s.db, `
+		SELECT DISTINCT ticker
+		FROM transactions
+		WHERE `+reportFilter+`
+			AND transaction_type = 'Sale';
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select sale ticker: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *Store) BuyTicker(ctx context.Context, f insider.ReportFilter) (insider.Tickers, error) {
+	t, err := collectValues[string](query(ctx, s.db, `
+		SELECT DISTINCT ticker
+		FROM transactions
+		WHERE `+reportFilter+`
+			AND transaction_type = 'Buy';
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select buy ticker: %w", err)
+	}
+
+	return t, nil
+}
+
+// TradedTickers returns tickers with transactions since the date.
+func (s *Store) TradedTickers(ctx context.Context, since time.Time) (insider.Tickers, error) {
+	t, err := collectValues[string](query(ctx, s.db, `
+		SELECT DISTINCT ticker
+		FROM transactions
+		WHERE transaction_date >= ?1
+		ORDER BY ticker;
+	`, ts(since)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select traded tickers: %w", err)
+	}
+
+	return t, nil
+}
+
+// Companies returns stored companies of the tickers.
+func (s *Store) Companies(ctx context.Context, tickers []string) ([]company.Company, error) {
+	tickersJSON, err := jsonText(tickers)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := collect[company.Company](query(ctx, s.db, `
+		SELECT ticker, name, sector, industry, country, market_cap, shares_outstanding, shares_float, updated_at
+		FROM companies
+		WHERE ticker IN (SELECT value FROM json_each(?1))
+		ORDER BY ticker;
+	`, tickersJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed select companies: %w", err)
+	}
+
+	return c, nil
+}
+
+// SaveCompany inserts or updates the company.
+func (s *Store) SaveCompany(ctx context.Context, c company.Company) error {
+	sql, args, err := sq.Insert("companies").
+		Columns("ticker", "name", "sector", "industry", "country", "market_cap", "shares_outstanding", "shares_float", "updated_at").
+		Values(c.Ticker, c.Name, c.Sector, c.Industry, c.Country, c.MarketCap, c.SharesOutstanding, c.SharesFloat, ts(c.UpdatedAt)).
+		Suffix(`ON CONFLICT (ticker) DO UPDATE SET
+		name = excluded.name,
+		sector = excluded.sector,
+		industry = excluded.industry,
+		country = excluded.country,
+		market_cap = excluded.market_cap,
+		shares_outstanding = excluded.shares_outstanding,
+		shares_float = excluded.shares_float,
+		updated_at = excluded.updated_at`).ToSql()
+	if err != nil {
+		return fmt.Errorf("company insert to sql: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("company insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// SectorTotals returns totals by sector and type of the report day,
+// tickers without the company are not counted.
+func (s *Store) SectorTotals(ctx context.Context, f insider.ReportFilter) ([]company.SectorTotal, error) {
+	st, err := collect[company.SectorTotal](query(ctx, s.db, `
+		SELECT c.sector, t.transaction_type, count(*) as transaction_count, sum(t.value) as total_value
+		FROM transactions t
+		JOIN companies c ON c.ticker = t.ticker
+		WHERE date(t.notification_date) = ?2
+			AND NOT (?1 AND t.planned)
+			AND c.sector <> ''
+		GROUP BY c.sector, t.transaction_type
+		ORDER BY c.sector, t.transaction_type;
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select sector totals: %w", err)
+	}
+
+	return st, nil
+}
+
+// SignificantBuy returns top 20 tickers of the report day with the net buy
+// by the value in basis points of the market cap, tickers without the market cap are skipped.
+func (s *Store) SignificantBuy(ctx context.Context, f insider.ReportFilter) ([]company.Significance, error) {
+	return s.significance(ctx, f, "n.total_value > 0", "DESC")
+}
+
+// SignificantSell returns top 20 tickers of the report day with the net sale
+// by the value in basis points of the market cap, tickers without the market cap are skipped.
+func (s *Store) SignificantSell(ctx context.Context, f insider.ReportFilter) ([]company.Significance, error) {
+	return s.significance(ctx, f, "n.total_value < 0", "ASC")
+}
+
+// significance returns tickers with the net transaction matching where,
+// the net buy is never reported as the sale and vice versa.
+func (s *Store) significance(ctx context.Context, f insider.ReportFilter, where, order string) ([]company.Significance, error) {
+	sig, err := collect[company.Significance](query(ctx, s.db, `
+		WITH net AS (
+			SELECT ticker,
+				sum(CASE WHEN transaction_type = 'Buy' THEN value ELSE -value END) AS total_value,
+				sum(CASE WHEN transaction_type = 'Buy' THEN shares ELSE -shares END) AS total_shares
+			FROM transactions
+			WHERE date(notification_date) = ?2
+				AND NOT (?1 AND planned)
+				AND transaction_type IN ('Buy', 'Sale')
+			GROUP BY ticker
+		)
+		SELECT n.ticker, n.total_value, n.total_shares, c.market_cap,
+			n.total_value * 10000.0 / c.market_cap AS value_bps,
+			n.total_shares * 1.0 / NULLIF(COALESCE(c.shares_float, c.shares_outstanding), 0) AS float_share
+		FROM net n
+		JOIN companies c ON c.ticker = n.ticker
+		WHERE c.market_cap > 0
+			AND `+where+`
+		ORDER BY value_bps `+order+`, n.ticker
+		LIMIT 20;
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select significance: %w", err)
+	}
+
+	return sig, nil
+}
+
+// SaveSymbols inserts or updates symbols of sources.
+func (s *Store) SaveSymbols(ctx context.Context, symbols []symbol.Symbol) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	query := sq.Insert("symbols").Columns("source", "symbol", "ticker", "updated_at")
+	for _, sym := range symbols {
+		query = query.Values(sym.Source, sym.Symbol, sym.Ticker, ts(sym.UpdatedAt))
+	}
+
+	sql, args, err := query.Suffix(`ON CONFLICT (source, symbol) DO UPDATE SET
+		ticker = excluded.ticker,
+		updated_at = excluded.updated_at`).ToSql()
+	if err != nil {
+		return fmt.Errorf("symbols insert to sql: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("symbols insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// Symbols returns symbols of the canonical ticker.
+func (s *Store) Symbols(ctx context.Context, ticker string) ([]symbol.Symbol, error) {
+	sym, err := collect[symbol.Symbol](query(ctx, s.db, `
+		SELECT source, symbol, ticker, updated_at
+		FROM symbols
+		WHERE ticker = ?1
+		ORDER BY source, symbol;
+	`, ticker))
+	if err != nil {
+		return nil, fmt.Errorf("failed select symbols: %w", err)
+	}
+
+	return sym, nil
+}
+
+// SymbolTicker returns the canonical ticker of the symbol of the source,
+// it's empty if the symbol isn't mapped.
+func (s *Store) SymbolTicker(ctx context.Context, source symbol.Source, sym string) (string, error) {
+	tickers, err := collectValues[string](query(ctx, s.db, `
+		SELECT ticker
+		FROM symbols
+		WHERE source = ?1
+			AND symbol = ?2;
+	`, source, sym))
+	if err != nil {
+		return "", fmt.Errorf("failed select symbol ticker: %w", err)
+	}
+
+	if len(tickers) == 0 {
+		return "", nil
+	}
+
+	return tickers[0], nil
+}
+
+// SaveCloses inserts or updates daily close prices.
+func (s *Store) SaveCloses(ctx context.Context, closes []price.Close) error {
+	if len(closes) == 0 {
+		return nil
+	}
+
+	query := sq.Insert("daily_closes").Columns("ticker", "date", "close")
+	for _, c := range closes {
+		query = query.Values(c.Ticker, date(c.Date), c.Close)
+	}
+
+	sql, args, err := query.Suffix("ON CONFLICT (ticker, date) DO UPDATE SET close = excluded.close").ToSql()
+	if err != nil {
+		return fmt.Errorf("closes insert to sql: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("closes insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// TransactionReturns returns forward returns of transactions
+// notified since the date.
+func (s *Store) TransactionReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.TransactionReturn, error) {
+	tr, err := collect[price.TransactionReturn](query(ctx, s.db, `
+		SELECT id, ticker, owner, relationship, transaction_type, transaction_date, cost,
+			return_1d, return_5d, return_20d, return_60d
+		FROM transaction_returns
+		WHERE notification_date >= ?1
+			AND NOT (?2 AND planned)
+		ORDER BY notification_date;
+	`, ts(since), f.ExcludePlanned))
+	if err != nil {
+		return nil, fmt.Errorf("failed select transaction returns: %w", err)
+	}
+
+	return tr, nil
+}
+
+// TypeReturns returns average forward returns by transaction type
+// of transactions notified since the date.
+func (s *Store) TypeReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.TypeReturn, error) {
+	tr, err := collect[price.TypeReturn](query(ctx, s.db, `
+		SELECT transaction_type, count(*) as transaction_count,
+			avg(return_1d) as return_1d, avg(return_5d) as return_5d,
+			avg(return_20d) as return_20d, avg(return_60d) as return_60d,
+			avg(CASE WHEN transaction_type = 'Buy' THEN return_20d > 0 ELSE return_20d < 0 END) as hit_rate_20d
+		FROM transaction_returns
+		WHERE notification_date >= ?1
+			AND NOT (?2 AND planned)
+		GROUP BY transaction_type
+		ORDER BY transaction_type;
+	`, ts(since), f.ExcludePlanned))
+	if err != nil {
+		return nil, fmt.Errorf("failed select type returns: %w", err)
+	}
+
+	return tr, nil
+}
+
+// InsiderReturns returns average forward returns by insider and transaction type,
+// insiders with the best 20 days return go first.
+func (s *Store) InsiderReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.InsiderReturn, error) {
+	ir, err := collect[price.InsiderReturn](query(ctx, s.db, `
+		SELECT owner, transaction_type, count(*) as transaction_count,
+			avg(return_1d) as return_1d, avg(return_5d) as return_5d,
+			avg(return_20d) as return_20d, avg(return_60d) as return_60d,
+			avg(CASE WHEN transaction_type = 'Buy' THEN return_20d > 0 ELSE return_20d < 0 END) as hit_rate_20d
+		FROM transaction_returns
+		WHERE notification_date >= ?1
+			AND NOT (?2 AND planned)
+		GROUP BY owner, transaction_type
+		ORDER BY return_20d DESC NULLS LAST;
+	`, ts(since), f.ExcludePlanned))
+	if err != nil {
+		return nil, fmt.Errorf("failed select insider returns: %w", err)
+	}
+
+	return ir, nil
+}
+
+// BuyReturns returns discretionary buys with the return after horizon trading days
+// and the benchmark return for the same period.
+func (s *Store) BuyReturns(ctx context.Context, horizon int, benchmark string) ([]score.BuyReturn, error) {
+	rr, err := collect[score.BuyReturn](query(ctx, s.db, `
+		SELECT id, owner, owner_cik, relationship,
+			fwd / cost - 1 as return,
+			bf / b0 - 1 as benchmark_return
+		FROM (
+			SELECT c.*,
+				(SELECT close FROM daily_closes WHERE ticker = c.ticker AND date = c.day) as fwd,
+				(SELECT close FROM daily_closes WHERE ticker = ?2 AND date = c.day) as bf
+			FROM (
+				SELECT t.id, t.ticker, t.owner, COALESCE(t.owner_cik, '') as owner_cik, t.relationship, t.cost,
+					-- the trading calendar of transaction_returns
+					(
+						SELECT DISTINCT date
+						FROM daily_closes
+						WHERE date > date(t.transaction_date)
+						ORDER BY date
+						LIMIT 1 OFFSET ?1 - 1
+					) as day,
+					(
+						SELECT close
+						FROM daily_closes
+						WHERE ticker = ?2
+							AND date <= date(t.transaction_date)
+						ORDER BY date DESC
+						LIMIT 1
+					) as b0
+				FROM transactions t
+				WHERE t.transaction_type = 'Buy'
+					AND NOT t.planned
+					AND t.cost > 0
+			) c
+		)
+		WHERE fwd IS NOT NULL
+			AND b0 IS NOT NULL
+			AND bf IS NOT NULL;
+	`, horizon, benchmark))
+	if err != nil {
+		return nil, fmt.Errorf("failed select buy returns: %w", err)
+	}
+
+	return rr, nil
+}
+
+// SaveScores inserts or updates scores.
+func (s *Store) SaveScores(ctx context.Context, scores []score.Score) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	query := sq.Insert("insider_scores").Columns("kind", "name", "horizon",
+		"transactions", "hit_rate", "avg_excess_return", "updated_at")
+	for _, sc := range scores {
+		query = query.Values(string(sc.Kind), sc.Name, sc.Horizon, sc.Transactions,
+			sc.HitRate, sc.AvgExcessReturn, ts(sc.UpdatedAt))
+	}
+
+	sql, args, err := query.Suffix(`ON CONFLICT (kind, name, horizon) DO UPDATE SET
+		transactions = excluded.transactions,
+		hit_rate = excluded.hit_rate,
+		avg_excess_return = excluded.avg_excess_return,
+		updated_at = excluded.updated_at`).ToSql()
+	if err != nil {
+		return fmt.Errorf("scores insert to sql: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("scores insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// Scores returns scores of the kind for the horizon, the best go first.
+func (s *Store) Scores(ctx context.Context, kind score.Kind, horizon int) ([]score.Score, error) {
+	sc, err := collect[score.Score](query(ctx, s.db, `
+		SELECT kind, name, horizon, transactions, hit_rate, avg_excess_return, updated_at
+		FROM insider_scores
+		WHERE kind = ?1
+			AND horizon = ?2
+		ORDER BY avg_excess_return DESC;
+	`, string(kind), horizon))
+	if err != nil {
+		return nil, fmt.Errorf("failed select scores: %w", err)
+	}
+
+	return sc, nil
+}
+
+// TransactionScores returns buys of the report day annotated with
+// the insider's scores for all horizons.
+func (s *Store) TransactionScores(ctx context.Context, f insider.ReportFilter) ([]score.TransactionScore, error) {
+	sc, err := collect[score.TransactionScore](query(ctx, s.db, `
+		SELECT t.id, t.ticker, t.owner, t.relationship, t.value,
+			sc.kind, sc.name, sc.horizon, sc.transactions, sc.hit_rate, sc.avg_excess_return, sc.updated_at
+		FROM transactions t
+		JOIN insider_scores sc ON sc.kind = 'insider' AND sc.name = COALESCE(t.owner_cik, t.owner)
+		WHERE date(t.notification_date) = ?2
+			AND NOT (?1 AND t.planned)
+			AND t.transaction_type = 'Buy'
+		ORDER BY t.value DESC, t.id, sc.horizon;
+	`, f.ExcludePlanned, date(f.Day)))
+	if err != nil {
+		return nil, fmt.Errorf("failed select transaction scores: %w", err)
+	}
+
+	return sc, nil
+}
+
+// Rules returns all alert rules.
+func (s *Store) Rules(ctx context.Context) ([]alert.Rule, error) {
+	r, err := collect[alert.Rule](query(ctx, s.db, `
+		SELECT name, expr, enabled, created_at
+		FROM alert_rules
+		ORDER BY name;
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed select rules: %w", err)
+	}
+
+	return r, nil
+}
+
+// SaveRule inserts or updates the alert rule.
+func (s *Store) SaveRule(ctx context.Context, r alert.Rule) error {
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO alert_rules (name, expr, enabled)
+		VALUES (?1, ?2, ?3)
+		ON CONFLICT (name) DO UPDATE SET
+			expr = excluded.expr,
+			enabled = excluded.enabled;
+	`, r.Name, r.Expr, r.Enabled); err != nil {
+		return fmt.Errorf("failed save rule: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRule deletes the alert rule with its firing history.
+func (s *Store) DeleteRule(ctx context.Context, name string) error {
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM alert_rules
+		WHERE name = ?1;
+	`, name); err != nil {
+		return fmt.Errorf("failed delete rule: %w", err)
+	}
+
+	return nil
+}
+
+// Fire records the firing of the rule, it returns false
+// if the rule has already fired for the transaction.
+func (s *Store) Fire(ctx context.Context, rule, transactionID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO alert_firings (rule_name, transaction_id)
+		VALUES (?1, ?2)
+		ON CONFLICT DO NOTHING;
+	`, rule, transactionID)
+	if err != nil {
+		return false, fmt.Errorf("failed insert firing: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed insert firing: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// Deliver marks the firing of the rule delivered.
+func (s *Store) Deliver(ctx context.Context, rule, transactionID string) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE alert_firings
+		SET delivered_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		WHERE rule_name = ?1 AND transaction_id = ?2;
+	`, rule, transactionID); err != nil {
+		return fmt.Errorf("failed update firing: %w", err)
+	}
+
+	return nil
+}
+
+// Undelivered returns transactions of pending firings of the rule,
+// the oldest firing first.
+func (s *Store) Undelivered(ctx context.Context, rule string) (insider.Transactions, error) {
+	tr, err := collect[insider.Transaction](query(ctx, s.db, `
+		SELECT `+transactionColumns+`
+		FROM transactions t
+		JOIN alert_firings f ON f.transaction_id = t.id
+		WHERE f.rule_name = ?1 AND f.delivered_at IS NULL
+		ORDER BY f.fired_at;
+	`, rule))
+	if err != nil {
+		return nil, fmt.Errorf("failed select undelivered firings: %w", err)
+	}
+
+	return tr, nil
+}
+
+// Firings returns the firing history of the rule, the latest go first.
+func (s *Store) Firings(ctx context.Context, rule string, limit int) ([]alert.Firing, error) {
+	f, err := collect[alert.Firing](query(ctx, s.db, `
+		SELECT rule_name, transaction_id, fired_at, delivered_at
+		FROM alert_firings
+		WHERE rule_name = ?1
+		ORDER BY fired_at DESC
+		LIMIT ?2;
+	`, rule, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed select firings: %w", err)
+	}
+
+	return f, nil
+}
+
+// StartRun inserts the run and sets its ID.
+func (s *Store) StartRun(ctx context.Context, r *run.Run) error {
+	sources, err := jsonText(r.SourceURLs)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := jsonText(r.Publish)
+	if err != nil {
+		return err
+	}
+
+	health, err := jsonText(r.Sources)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO runs (started_at, day, source_urls, status, publish, sources)
+		VALUES (?1, ?2, coalesce(?3, '[]'), ?4, coalesce(?5, '{}'), coalesce(?6, '{}'));
+	`, ts(r.StartedAt), date(r.Day), sources, string(r.Status), statuses, health)
+	if err != nil {
+		return fmt.Errorf("failed insert run: %w", err)
+	}
+
+	r.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed insert run: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) SaveRun(ctx context.Context, r run.Run) error {
+	sources, err := jsonText(r.SourceURLs)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := jsonText(r.Publish)
+	if err != nil {
+		return err
+	}
+
+	health, err := jsonText(r.Sources)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE runs
+		SET finished_at = ?2,
+			source_urls = coalesce(?3, '[]'),
+			rows_scraped = ?4,
+			rows_kept = ?5,
+			rows_inserted = ?6,
+			rows_duplicate = ?7,
+			status = ?8,
+			error = ?9,
+			publish = coalesce(?10, '{}'),
+			sources = coalesce(?11, '{}')
+		WHERE id = ?1;
+	`, r.ID, nts(r.FinishedAt), sources, r.Scraped, r.Kept, r.Inserted, r.Duplicates,
+		string(r.Status), r.Error, statuses, health); err != nil {
+		return fmt.Errorf("failed update run: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Fetched(ctx context.Context, day time.Time) (bool, error) {
+	ok, err := collectValues[bool](query(ctx, s.db, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM runs
+			WHERE day = ?1
+				AND status = ?2
+		);
+	`, date(day), string(run.StatusSucceeded)))
+	if err != nil {
+		return false, fmt.Errorf("failed select fetched: %w", err)
+	}
+
+	return ok[0], nil
+}
+
+func (s *Store) LastFetched(ctx context.Context) (time.Time, error) {
+	last, err := collectValues[*time.Time](query(ctx, s.db, `
+		SELECT max(day)
+		FROM runs
+		WHERE status = ?1;
+	`, string(run.StatusSucceeded)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed select last fetched: %w", err)
+	}
+
+	if len(last) == 0 || last[0] == nil {
+		return time.Time{}, nil
+	}
+
+	return *last[0], nil
+}
+
+func (s *Store) Runs(ctx context.Context, limit int) ([]run.Run, error) {
+	r, err := collect[run.Run](query(ctx, s.db, `
+		SELECT id, started_at, finished_at, day, source_urls, rows_scraped,
+			rows_kept, rows_inserted, rows_duplicate, status, error, publish, sources
+		FROM runs
+		ORDER BY id DESC
+		LIMIT ?1;
+	`, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed select runs: %w", err)
+	}
+
+	return r, nil
+}
+
+// PendingDigests returns not delivered digests, the oldest first.
+func (s *Store) PendingDigests(ctx context.Context) ([]publish.Digest, error) {
+	d, err := collect[publish.Digest](query(ctx, s.db, `
+		SELECT key, day, sink, attempts, last_error, created_at, delivered_at
+		FROM digests
+		WHERE delivered_at IS NULL
+		ORDER BY day, key;
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed select pending digests: %w", err)
+	}
+
+	return d, nil
+}
+
+// ClaimDigest leases the pending digest, the lease of the crashed
+// publisher expires, so its digest is retried.
+func (s *Store) ClaimDigest(ctx context.Context, key string, lease time.Duration) (publish.Digest, bool, error) {
+	now := time.Now()
+	d, err := collectOne[publish.Digest](query(ctx, s.db, `
+		UPDATE digests
+		SET locked_until = ?2
+		WHERE key = ?1
+			AND delivered_at IS NULL
+			AND (locked_until IS NULL OR locked_until <= ?3)
+		RETURNING key, day, sink, attempts, last_error, created_at, delivered_at;
+	`, key, ts(now.Add(lease)), ts(now)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return publish.Digest{}, false, nil
+	}
+	if err != nil {
+		return publish.Digest{}, false, fmt.Errorf("failed claim digest: %w", err)
+	}
+
+	return d, true, nil
+}
+
+func (s *Store) DigestParts(ctx context.Context, key string) ([]string, error) {
+	parts, err := collectValues[string](query(ctx, s.db, `
+		SELECT part
+		FROM digest_parts
+		WHERE key = ?1
+		ORDER BY part;
+	`, key))
+	if err != nil {
+		return nil, fmt.Errorf("failed select digest parts: %w", err)
+	}
+
+	return parts, nil
+}
+
+func (s *Store) SaveDigestPart(ctx context.Context, key, part string) error {
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO digest_parts (key, part)
+		VALUES (?1, ?2)
+		ON CONFLICT (key, part) DO NOTHING;
+	`, key, part); err != nil {
+		return fmt.Errorf("failed insert digest part: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseDigest records the attempt of the claimed digest.
+func (s *Store) ReleaseDigest(ctx context.Context, key string, derr error) error {
+	lastError, deliveredAt := "", any(ts(time.Now()))
+	if derr != nil {
+		lastError, deliveredAt = derr.Error(), nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE digests
+		SET attempts = attempts + 1,
+			last_error = ?2,
+			delivered_at = ?3,
+			locked_until = NULL
+		WHERE key = ?1;
+	`, key, lastError, deliveredAt); err != nil {
+		return fmt.Errorf("failed update digest: %w", err)
+	}
+
+	return nil
+}
+
+// PendingEvents returns not published events in the order of notification date.
+func (s *Store) PendingEvents(ctx context.Context, limit int) ([]event.Event, error) {
+	e, err := collect[event.Event](query(ctx, s.db, `
+		SELECT id, event_type, event_key, notification_date, created_at, payload
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY notification_date, id
+		LIMIT ?1;
+	`, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed select pending events: %w", err)
+	}
+
+	return e, nil
+}
+
+func (s *Store) MarkPublished(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET published_at = ?2
+		WHERE id = ?1;
+	`, id, ts(time.Now())); err != nil {
+		return fmt.Errorf("failed update event: %w", err)
+	}
+
+	return nil
+}
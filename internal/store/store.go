@@ -3,20 +3,36 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/event"
 	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/run"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/RyabovNick/finviz_parser/internal/tracing"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var (
-	pgsq                  = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-	ErrAlreadyParsedToday = fmt.Errorf("already parsed today")
-)
+var pgsq = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// transactionColumns are columns of insider.Transaction.
+const transactionColumns = `id::text as id, ticker, owner, relationship, transaction_date,
+	transaction_type, cost, shares, value, shares_total, notification_date, url,
+	COALESCE(issuer_cik, '') as issuer_cik, COALESCE(owner_cik, '') as owner_cik,
+	holdings_change, value_to_median, first_buy, anomaly_flags`
+
+const redacted = "[REDACTED]"
 
 type Options struct {
 	Host     string
@@ -27,7 +43,23 @@ type Options struct {
 	MinPool  int
 }
 
+// String returns the connection string without the password,
+// it's safe to log.
 func (o Options) String() string {
+	if o.Password != "" {
+		o.Password = redacted
+	}
+
+	return o.ConnString()
+}
+
+// LogValue keeps the password out of logs.
+func (o Options) LogValue() slog.Value {
+	return slog.StringValue(o.String())
+}
+
+// ConnString returns the connection string with the password.
+func (o Options) ConnString() string {
 	port := "5432"
 
 	hp := strings.Split(o.Host, ":")
@@ -45,7 +77,21 @@ type Store struct {
 
 // New creates connection.
 func New(ctx context.Context, o Options) (*Store, error) {
-	pool, err := pgxpool.New(ctx, o.String())
+	slog.DebugContext(ctx, "connect postgres", "options", o)
+	return Connect(ctx, o.ConnString())
+}
+
+// Connect creates connection by the connection string,
+// it's either the postgres:// URL or keyword/value pairs.
+func Connect(ctx context.Context, connString string) (*Store, error) {
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed parse connection string: %w", err)
+	}
+	// every query is the span
+	cfg.ConnConfig.Tracer = tracing.QueryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed connection: %w", err)
 	}
@@ -63,80 +109,328 @@ func (s *Store) Close() {
 	s.pool.Close()
 }
 
-func (s *Store) lastParse(ctx context.Context) (time.Time, error) {
-	var t time.Time
-	if err := s.pool.QueryRow(ctx, `
-		SELECT updated_at
-		FROM last_parse
-		WHERE id = 1;
-	`).Scan(&t); err != nil {
-		return time.Time{}, fmt.Errorf("failed select last parse: %w", err)
+// InsertTransactions inserts transactions which are not stored yet,
+// their TransactionCreated events and pending digests in one database
+// transaction, it returns inserted transactions with IDs.
+func (s *Store) InsertTransactions(ctx context.Context, tr insider.Transactions, digests ...publish.Digest) (insider.Transactions, error) {
+	// the digest is enqueued on the day without new transactions too
+	if len(tr) == 0 && len(digests) == 0 {
+		return nil, nil
 	}
 
-	return t, nil
-}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-// updateLastParse sets last parse as yesterday.
-func (s *Store) updateLastParse(ctx context.Context) error {
-	if _, err := s.pool.Exec(ctx, `
-		UPDATE last_parse
-		SET updated_at = current_date - 1
-		WHERE id = 1;
-	`); err != nil {
-		return fmt.Errorf("failed update last parse: %w", err)
+	fresh, err := insertTransactions(ctx, tx, tr)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	if err := insertEvents(ctx, tx, fresh); err != nil {
+		return nil, err
+	}
+
+	for _, d := range digests {
+		// the digest of the day is enqueued once, reruns keep its state
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO digests (key, day, sink)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (key) DO NOTHING;
+		`, d.Key, d.Day, d.Sink); err != nil {
+			return nil, fmt.Errorf("failed insert digest %s: %w", d.Key, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed commit: %w", err)
+	}
+
+	return fresh, nil
 }
 
-func (s *Store) InsertTransactions(ctx context.Context, tr insider.Transactions) error {
+func insertTransactions(ctx context.Context, tx pgx.Tx, tr insider.Transactions) (insider.Transactions, error) {
 	if len(tr) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	lp, err := s.lastParse(ctx)
+	stored, err := storedTransactions(ctx, tx, tr)
 	if err != nil {
-		return fmt.Errorf("failed get last parse: %w", err)
+		return nil, fmt.Errorf("failed get stored transactions: %w", err)
 	}
 
 	query := pgsq.Insert("transactions").Columns("ticker", "owner", "relationship",
 		"transaction_date", "transaction_type", "cost", "shares", "value",
-		"shares_total", "notification_date", "url")
+		"shares_total", "notification_date", "url", "issuer_cik", "owner_cik",
+		"holdings_change", "value_to_median", "first_buy", "anomaly_flags")
 
+	var fresh insider.Transactions
 	for _, t := range tr {
-		// skip if already parsed today
-		if t.NotificationDate.Format("2006-01-02") == lp.Format("2006-01-02") {
-			return ErrAlreadyParsedToday
+		// skip duplicates of stored rows, of the same page and of other sources
+		if !stored.Add(t) {
+			continue
 		}
 
+		fresh = append(fresh, t)
 		query = query.Values(t.Ticker, t.Owner, t.Relationship, t.TransactionDate,
-			t.Transaction, t.Cost, t.Shares, t.Value, t.SharesTotal, t.SEC.NotificationDate, t.SEC.URL)
+			t.Transaction, t.Cost, t.Shares, t.Value, t.SharesTotal, t.SEC.NotificationDate, t.SEC.URL,
+			sq.Expr("NULLIF(?, '')", t.IssuerCIK), sq.Expr("NULLIF(?, '')", t.OwnerCIK),
+			t.HoldingsChange, t.ValueToMedian, t.FirstBuy, t.Flags)
+	}
+
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+
+	// the order of returned rows isn't guaranteed,
+	// so IDs are matched by the natural key
+	sql, args, err := query.Suffix(`RETURNING id::text, ticker, owner, transaction_date,
+		transaction_type, shares, value, notification_date`).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("transactions insert to sql: %w", err)
+	}
+
+	rows, _ := tx.Query(ctx, sql, args...)
+	inserted, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[insider.Transaction])
+	if err != nil {
+		return nil, fmt.Errorf("transactions insert exec: %w", err)
+	}
+
+	ids := make(map[string]string, len(inserted))
+	for _, t := range inserted {
+		ids[t.Key()] = t.ID
+	}
+
+	for i := range fresh {
+		id, ok := ids[fresh[i].Key()]
+		if !ok {
+			return nil, fmt.Errorf("failed find id of inserted transaction %s", fresh[i].Key())
+		}
+		fresh[i].ID = id
+	}
+
+	return fresh, nil
+}
+
+// insertEvents writes TransactionCreated events to the outbox.
+func insertEvents(ctx context.Context, tx pgx.Tx, tr insider.Transactions) error {
+	if len(tr) == 0 {
+		return nil
+	}
+
+	query := pgsq.Insert("outbox").Columns("event_type", "event_key", "notification_date", "payload")
+	for _, t := range tr {
+		e, err := event.TransactionCreated(t)
+		if err != nil {
+			return err
+		}
+
+		query = query.Values(e.Type, e.Key, e.NotificationDate, e.Data)
 	}
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf("candles insert to sql: %w", err)
+		return fmt.Errorf("outbox insert to sql: %w", err)
 	}
 
-	if _, err := s.pool.Exec(ctx, sql, args...); err != nil {
-		return fmt.Errorf("candles insert exec: %w", err)
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed insert events: %w", err)
+	}
+
+	return nil
+}
+
+// storedTransactions returns Dedup of stored transactions notified
+// in the same period as tr, sources may differ in notification time by hours.
+func storedTransactions(ctx context.Context, tx pgx.Tx, tr insider.Transactions) (*insider.Dedup, error) {
+	from, to := tr[0].NotificationDate, tr[0].NotificationDate
+	tickers := make([]string, 0, len(tr))
+	for _, t := range tr {
+		if t.NotificationDate.Before(from) {
+			from = t.NotificationDate
+		}
+		if t.NotificationDate.After(to) {
+			to = t.NotificationDate
+		}
+		tickers = append(tickers, t.Ticker)
+	}
+
+	rows, _ := tx.Query(ctx, `
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE notification_date BETWEEN $1 AND $2
+			AND ticker = ANY($3);
+	`, from.AddDate(0, 0, -1), to.AddDate(0, 0, 1), tickers)
+	stored, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.Transaction])
+	if err != nil {
+		return nil, err
+	}
+
+	return insider.NewDedup(stored), nil
+}
+
+// History returns the insiders' history for the transactions.
+func (s *Store) History(ctx context.Context, tr insider.Transactions) ([]insider.History, error) {
+	if len(tr) == 0 {
+		return nil, nil
+	}
+
+	owners := make([]string, 0, len(tr))
+	tickers := make([]string, 0, len(tr))
+	types := make([]string, 0, len(tr))
+	ownerCIKs := make([]string, 0, len(tr))
+	issuerCIKs := make([]string, 0, len(tr))
+	for _, t := range tr {
+		owners = append(owners, t.Owner)
+		tickers = append(tickers, t.Ticker)
+		types = append(types, string(t.Transaction))
+		ownerCIKs = append(ownerCIKs, t.OwnerCIK)
+		issuerCIKs = append(issuerCIKs, t.IssuerCIK)
+	}
+
+	// insiders and companies are identified by CIKs when known,
+	// so name variations and ticker changes share the history.
+	// The owner's CIK is looked up by the name at the same issuer,
+	// namesakes at other companies are different insiders.
+	rows, _ := s.pool.Query(ctx, `
+		WITH i AS (
+			SELECT DISTINCT ON (u.owner, u.ticker, u.transaction_type)
+				u.owner, u.ticker, u.transaction_type, NULLIF(u.owner_cik, '') AS owner_cik,
+				COALESCE(NULLIF(u.issuer_cik, ''), (
+					SELECT issuer_cik
+					FROM transactions t
+					WHERE t.ticker = u.ticker
+						AND t.issuer_cik IS NOT NULL
+					LIMIT 1
+				)) as issuer_cik
+			FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[])
+				AS u(owner, ticker, transaction_type, owner_cik, issuer_cik)
+			ORDER BY u.owner, u.ticker, u.transaction_type, u.owner_cik DESC
+		), k AS (
+			SELECT i.owner, i.ticker, i.transaction_type, i.issuer_cik,
+				COALESCE(i.owner_cik, (
+					SELECT owner_cik
+					FROM transactions t
+					WHERE t.owner = i.owner
+						AND (t.ticker = i.ticker OR t.issuer_cik = i.issuer_cik)
+						AND t.owner_cik IS NOT NULL
+					LIMIT 1
+				)) as owner_cik
+			FROM i
+		)
+		SELECT k.owner, k.ticker, k.transaction_type,
+			(
+				SELECT count(*)
+				FROM transactions t
+				WHERE (t.owner_cik = k.owner_cik OR (k.owner_cik IS NULL AND t.owner = k.owner))
+					AND t.transaction_type = k.transaction_type
+			) as transactions,
+			(
+				SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY t.value)
+				FROM transactions t
+				WHERE (t.owner_cik = k.owner_cik OR (k.owner_cik IS NULL AND t.owner = k.owner))
+					AND t.transaction_type = k.transaction_type
+			) as median_value,
+			(
+				SELECT max(t.transaction_date)
+				FROM transactions t
+				WHERE (t.owner_cik = k.owner_cik OR (k.owner_cik IS NULL AND t.owner = k.owner))
+					AND (t.ticker = k.ticker OR t.issuer_cik = k.issuer_cik)
+					AND t.transaction_type = 'Buy'
+			) as last_buy
+		FROM k;
+	`, owners, tickers, types, ownerCIKs, issuerCIKs)
+	h, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.History])
+	if err != nil {
+		return nil, fmt.Errorf("failed select history: %w", err)
+	}
+
+	return h, nil
+}
+
+// UnenrichedTransactions returns transactions for the last week
+// without details from the Form 4 filing.
+func (s *Store) UnenrichedTransactions(ctx context.Context) (insider.Transactions, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE accession_number IS NULL
+			AND notification_date > current_date - 7
+		ORDER BY notification_date;
+	`)
+	tr, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.Transaction])
+	if err != nil {
+		return nil, fmt.Errorf("failed select unenriched transactions: %w", err)
+	}
+
+	return tr, nil
+}
+
+// NewestNotification returns the notification date of the newest transaction.
+func (s *Store) NewestNotification(ctx context.Context) (time.Time, error) {
+	var newest *time.Time
+	if err := s.pool.QueryRow(ctx, `
+		SELECT max(notification_date)
+		FROM transactions;
+	`).Scan(&newest); err != nil {
+		return time.Time{}, fmt.Errorf("failed select newest notification: %w", err)
 	}
 
-	if err := s.updateLastParse(ctx); err != nil {
-		return fmt.Errorf("failed update last parse: %w", err)
+	if newest == nil {
+		return time.Time{}, nil
+	}
+
+	return *newest, nil
+}
+
+// SaveFiling stores details from the Form 4 filing against the transaction.
+// The issuer's CIK is also set to transactions of the same ticker without it,
+// the owner's CIK to transactions of the same name at the same issuer.
+func (s *Store) SaveFiling(ctx context.Context, transactionID string, f insider.Filing) error {
+	if _, err := s.pool.Exec(ctx, `
+		WITH f AS (
+			UPDATE transactions
+			SET accession_number = $2,
+				transaction_code = $3,
+				plan_10b5_1 = $4,
+				direct_ownership = $5,
+				footnotes = $6,
+				planned = $7,
+				issuer_cik = NULLIF($8, ''),
+				owner_cik = NULLIF($9, '')
+			WHERE id = $1
+			RETURNING id, owner, ticker, issuer_cik, owner_cik
+		)
+		UPDATE transactions t
+		SET owner_cik = CASE
+				WHEN t.owner = f.owner AND (t.ticker = f.ticker OR t.issuer_cik = f.issuer_cik)
+				THEN COALESCE(t.owner_cik, f.owner_cik)
+				ELSE t.owner_cik
+			END,
+			issuer_cik = CASE WHEN t.ticker = f.ticker THEN COALESCE(t.issuer_cik, f.issuer_cik) ELSE t.issuer_cik END
+		FROM f
+		WHERE t.id <> f.id
+			AND ((t.owner = f.owner AND (t.ticker = f.ticker OR t.issuer_cik = f.issuer_cik) AND t.owner_cik IS NULL)
+				OR (t.ticker = f.ticker AND t.issuer_cik IS NULL));
+	`, transactionID, f.AccessionNumber, f.TransactionCode, f.Plan10b51, f.DirectOwnership, f.Footnotes, f.Planned(),
+		f.IssuerCIK, f.OwnerCIK); err != nil {
+		return fmt.Errorf("failed update filing: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Store) TransactionTypeCount(ctx context.Context) ([]insider.TransactionTypeCount, error) {
+func (s *Store) TransactionTypeCount(ctx context.Context, f insider.ReportFilter) ([]insider.TransactionTypeCount, error) {
 	rows, _ := s.pool.Query(ctx, `
 		SELECT transaction_type, count(*) as transaction_count, sum(value) as total_value
 		FROM transactions
-		WHERE notification_date::date = current_date - 1
+		WHERE notification_date::date = $2
+			AND NOT ($1 AND planned)
 		GROUP BY transaction_type
 		ORDER BY transaction_type;
-	`)
+	`, f.ExcludePlanned, f.Day)
 	tc, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.TransactionTypeCount])
 	if err != nil {
 		return nil, fmt.Errorf("failed select transaction type count: %w", err)
@@ -145,14 +439,35 @@ func (s *Store) TransactionTypeCount(ctx context.Context) ([]insider.Transaction
 	return tc, nil
 }
 
-func (s *Store) RelationshipCount(ctx context.Context) ([]insider.RelationshipCount, error) {
+// DailyTotals returns totals by notification day and type
+// from since to the day of the filter.
+func (s *Store) DailyTotals(ctx context.Context, since time.Time, f insider.ReportFilter) ([]insider.DailyTotal, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT notification_date::date AS day, transaction_type,
+			count(*) as transaction_count, sum(value) as total_value
+		FROM transactions
+		WHERE notification_date::date BETWEEN $2 AND $3
+			AND NOT ($1 AND planned)
+		GROUP BY day, transaction_type
+		ORDER BY day, transaction_type;
+	`, f.ExcludePlanned, since, f.Day)
+	dt, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.DailyTotal])
+	if err != nil {
+		return nil, fmt.Errorf("failed select daily totals: %w", err)
+	}
+
+	return dt, nil
+}
+
+func (s *Store) RelationshipCount(ctx context.Context, f insider.ReportFilter) ([]insider.RelationshipCount, error) {
 	rows, _ := s.pool.Query(ctx, `
 		SELECT relationship, transaction_type, count(*) as transaction_count, sum(value) as total_value
 		FROM transactions
-		WHERE notification_date::date = current_date - 1
+		WHERE notification_date::date = $2
+			AND NOT ($1 AND planned)
 		GROUP BY relationship, transaction_type
 		ORDER BY total_value DESC;
-	`)
+	`, f.ExcludePlanned, f.Day)
 	rc, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.RelationshipCount])
 	if err != nil {
 		return nil, fmt.Errorf("failed select relationship count: %w", err)
@@ -161,18 +476,20 @@ func (s *Store) RelationshipCount(ctx context.Context) ([]insider.RelationshipCo
 	return rc, nil
 }
 
-func (s *Store) TopBuy(ctx context.Context) ([]insider.TotalTransaction, error) {
+func (s *Store) TopBuy(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
 	rows, _ := s.pool.Query(ctx, `
 		WITH sale AS (
 			SELECT ticker, sum(value) as total_value
 			FROM transactions
-			WHERE notification_date::date = current_date - 1
+			WHERE notification_date::date = $2
+				AND NOT ($1 AND planned)
 				AND transaction_type = 'Sale'
 			GROUP BY ticker
 		), buy AS (
 				SELECT ticker, sum(value) as total_value
 				FROM transactions
-				WHERE notification_date::date = current_date - 1
+				WHERE notification_date::date = $2
+					AND NOT ($1 AND planned)
 					AND transaction_type = 'Buy'
 				GROUP BY ticker
 		)
@@ -184,7 +501,7 @@ func (s *Store) TopBuy(ctx context.Context) ([]insider.TotalTransaction, error)
 		FULL OUTER JOIN buy ON sale.ticker = buy.ticker
 		ORDER BY total_value DESC
 		LIMIT 20;
-	`)
+	`, f.ExcludePlanned, f.Day)
 	tt, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.TotalTransaction])
 	if err != nil {
 		return nil, fmt.Errorf("failed select top sell: %w", err)
@@ -193,18 +510,20 @@ func (s *Store) TopBuy(ctx context.Context) ([]insider.TotalTransaction, error)
 	return tt, nil
 }
 
-func (s *Store) TopSell(ctx context.Context) ([]insider.TotalTransaction, error) {
+func (s *Store) TopSell(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
 	rows, _ := s.pool.Query(ctx, `
 		WITH sale AS (
 			SELECT ticker, sum(value) as total_value
 			FROM transactions
-			WHERE notification_date::date = current_date - 1
+			WHERE notification_date::date = $2
+				AND NOT ($1 AND planned)
 				AND transaction_type = 'Sale'
 			GROUP BY ticker
 		), buy AS (
 				SELECT ticker, sum(value) as total_value
 				FROM transactions
-				WHERE notification_date::date = current_date - 1
+				WHERE notification_date::date = $2
+					AND NOT ($1 AND planned)
 					AND transaction_type = 'Buy'
 				GROUP BY ticker
 		)
@@ -216,7 +535,7 @@ func (s *Store) TopSell(ctx context.Context) ([]insider.TotalTransaction, error)
 		FULL OUTER JOIN buy ON sale.ticker = buy.ticker
 		ORDER BY total_value ASC
 		LIMIT 20;
-	`)
+	`, f.ExcludePlanned, f.Day)
 	tc, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.TotalTransaction])
 	if err != nil {
 		return nil, fmt.Errorf("failed select top sell: %w", err)
@@ -225,13 +544,53 @@ func (s *Store) TopSell(ctx context.Context) ([]insider.TotalTransaction, error)
 	return tc, nil
 }
 
-func (s *Store) SaleTicker(ctx context.Context) (insider.Tickers, error) {
+// UnusualTransactions returns transactions of the report day flagged at ingest.
+func (s *Store) UnusualTransactions(ctx context.Context, f insider.ReportFilter) (insider.Transactions, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE notification_date::date = $2
+			AND NOT ($1 AND planned)
+			AND cardinality(anomaly_flags) > 0
+		ORDER BY value DESC;
+	`, f.ExcludePlanned, f.Day)
+	tr, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.Transaction])
+	if err != nil {
+		return nil, fmt.Errorf("failed select unusual transactions: %w", err)
+	}
+
+	return tr, nil
+}
+
+// TopPlannedSell returns tickers with the largest sales
+// made under Rule 10b5-1 plans on the report day.
+func (s *Store) TopPlannedSell(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT ticker, sum(value) as total_value
+		FROM transactions
+		WHERE notification_date::date = $1
+			AND planned
+			AND transaction_type = 'Sale'
+		GROUP BY ticker
+		ORDER BY total_value DESC
+		LIMIT 20;
+	`, f.Day)
+	tt, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.TotalTransaction])
+	if err != nil {
+		return nil, fmt.Errorf("failed select top planned sell: %w", err)
+	}
+
+	return tt, nil
+}
+
+func (s *Store) SaleTicker(ctx context.Context, f insider.ReportFilter) (insider.Tickers, error) {
 	rows, _ := s.pool.Query(ctx, `
 		SELECT DISTINCT ticker
 		FROM transactions
-		WHERE notification_date::date = current_date - 1
+		WHERE notification_date::date = $2
+			AND NOT ($1 AND planned)
 			AND transaction_type = 'Sale';
-	`)
+	`, f.ExcludePlanned, f.Day)
 	t, err := pgx.CollectRows(rows, pgx.RowTo[string])
 	if err != nil {
 		return nil, fmt.Errorf("failed select sale ticker: %w", err)
@@ -240,13 +599,14 @@ func (s *Store) SaleTicker(ctx context.Context) (insider.Tickers, error) {
 	return t, nil
 }
 
-func (s *Store) BuyTicker(ctx context.Context) (insider.Tickers, error) {
+func (s *Store) BuyTicker(ctx context.Context, f insider.ReportFilter) (insider.Tickers, error) {
 	rows, _ := s.pool.Query(ctx, `
 		SELECT DISTINCT ticker
 		FROM transactions
-		WHERE notification_date::date = current_date - 1
+		WHERE notification_date::date = $2
+			AND NOT ($1 AND planned)
 			AND transaction_type = 'Buy';
-	`)
+	`, f.ExcludePlanned, f.Day)
 	t, err := pgx.CollectRows(rows, pgx.RowTo[string])
 	if err != nil {
 		return nil, fmt.Errorf("failed select buy ticker: %w", err)
@@ -254,3 +614,683 @@ func (s *Store) BuyTicker(ctx context.Context) (insider.Tickers, error) {
 
 	return t, nil
 }
+
+// TradedTickers returns tickers with transactions since the date.
+func (s *Store) TradedTickers(ctx context.Context, since time.Time) (insider.Tickers, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT DISTINCT ticker
+		FROM transactions
+		WHERE transaction_date >= $1
+		ORDER BY ticker;
+	`, since)
+	t, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed select traded tickers: %w", err)
+	}
+
+	return t, nil
+}
+
+// Companies returns stored companies of the tickers.
+func (s *Store) Companies(ctx context.Context, tickers []string) ([]company.Company, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT ticker, name, sector, industry, country, market_cap, shares_outstanding, shares_float, updated_at
+		FROM companies
+		WHERE ticker = ANY($1)
+		ORDER BY ticker;
+	`, tickers)
+	c, err := pgx.CollectRows(rows, pgx.RowToStructByName[company.Company])
+	if err != nil {
+		return nil, fmt.Errorf("failed select companies: %w", err)
+	}
+
+	return c, nil
+}
+
+// SaveCompany inserts or updates the company.
+func (s *Store) SaveCompany(ctx context.Context, c company.Company) error {
+	sql, args, err := pgsq.Insert("companies").
+		Columns("ticker", "name", "sector", "industry", "country", "market_cap", "shares_outstanding", "shares_float", "updated_at").
+		Values(c.Ticker, c.Name, c.Sector, c.Industry, c.Country, c.MarketCap, c.SharesOutstanding, c.SharesFloat, c.UpdatedAt).
+		Suffix(`ON CONFLICT (ticker) DO UPDATE SET
+		name = EXCLUDED.name,
+		sector = EXCLUDED.sector,
+		industry = EXCLUDED.industry,
+		country = EXCLUDED.country,
+		market_cap = EXCLUDED.market_cap,
+		shares_outstanding = EXCLUDED.shares_outstanding,
+		shares_float = EXCLUDED.shares_float,
+		updated_at = EXCLUDED.updated_at`).ToSql()
+	if err != nil {
+		return fmt.Errorf("company insert to sql: %w", err)
+	}
+
+	if _, err := s.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("company insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// SectorTotals returns totals by sector and type of the report day,
+// tickers without the company are not counted.
+func (s *Store) SectorTotals(ctx context.Context, f insider.ReportFilter) ([]company.SectorTotal, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT c.sector, t.transaction_type, count(*) as transaction_count, sum(t.value) as total_value
+		FROM transactions t
+		JOIN companies c ON c.ticker = t.ticker
+		WHERE t.notification_date::date = $2
+			AND NOT ($1 AND t.planned)
+			AND c.sector <> ''
+		GROUP BY c.sector, t.transaction_type
+		ORDER BY c.sector, t.transaction_type;
+	`, f.ExcludePlanned, f.Day)
+	st, err := pgx.CollectRows(rows, pgx.RowToStructByName[company.SectorTotal])
+	if err != nil {
+		return nil, fmt.Errorf("failed select sector totals: %w", err)
+	}
+
+	return st, nil
+}
+
+// SignificantBuy returns top 20 tickers of the report day with the net buy
+// by the value in basis points of the market cap, tickers without the market cap are skipped.
+func (s *Store) SignificantBuy(ctx context.Context, f insider.ReportFilter) ([]company.Significance, error) {
+	return s.significance(ctx, f, "n.total_value > 0", "DESC")
+}
+
+// SignificantSell returns top 20 tickers of the report day with the net sale
+// by the value in basis points of the market cap, tickers without the market cap are skipped.
+func (s *Store) SignificantSell(ctx context.Context, f insider.ReportFilter) ([]company.Significance, error) {
+	return s.significance(ctx, f, "n.total_value < 0", "ASC")
+}
+
+// significance returns tickers with the net transaction matching where,
+// the net buy is never reported as the sale and vice versa.
+func (s *Store) significance(ctx context.Context, f insider.ReportFilter, where, order string) ([]company.Significance, error) {
+	rows, _ := s.pool.Query(ctx, `
+		WITH net AS (
+			SELECT ticker,
+				sum(CASE WHEN transaction_type = 'Buy' THEN value ELSE -value END)::double precision AS total_value,
+				sum(CASE WHEN transaction_type = 'Buy' THEN shares ELSE -shares END)::bigint AS total_shares
+			FROM transactions
+			WHERE notification_date::date = $2
+				AND NOT ($1 AND planned)
+				AND transaction_type IN ('Buy', 'Sale')
+			GROUP BY ticker
+		)
+		SELECT n.ticker, n.total_value, n.total_shares, c.market_cap,
+			n.total_value / c.market_cap * 10000 AS value_bps,
+			n.total_shares / NULLIF(COALESCE(c.shares_float, c.shares_outstanding), 0) AS float_share
+		FROM net n
+		JOIN companies c ON c.ticker = n.ticker
+		WHERE c.market_cap > 0
+			AND `+where+`
+		ORDER BY value_bps `+order+`, n.ticker
+		LIMIT 20;
+	`, f.ExcludePlanned, f.Day)
+	sig, err := pgx.CollectRows(rows, pgx.RowToStructByName[company.Significance])
+	if err != nil {
+		return nil, fmt.Errorf("failed select significance: %w", err)
+	}
+
+	return sig, nil
+}
+
+// SaveSymbols inserts or updates symbols of sources.
+func (s *Store) SaveSymbols(ctx context.Context, symbols []symbol.Symbol) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	query := pgsq.Insert("symbols").Columns("source", "symbol", "ticker", "updated_at")
+	for _, sym := range symbols {
+		query = query.Values(sym.Source, sym.Symbol, sym.Ticker, sym.UpdatedAt)
+	}
+
+	sql, args, err := query.Suffix(`ON CONFLICT (source, symbol) DO UPDATE SET
+		ticker = EXCLUDED.ticker,
+		updated_at = EXCLUDED.updated_at`).ToSql()
+	if err != nil {
+		return fmt.Errorf("symbols insert to sql: %w", err)
+	}
+
+	if _, err := s.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("symbols insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// Symbols returns symbols of the canonical ticker.
+func (s *Store) Symbols(ctx context.Context, ticker string) ([]symbol.Symbol, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT source, symbol, ticker, updated_at
+		FROM symbols
+		WHERE ticker = $1
+		ORDER BY source, symbol;
+	`, ticker)
+	sym, err := pgx.CollectRows(rows, pgx.RowToStructByName[symbol.Symbol])
+	if err != nil {
+		return nil, fmt.Errorf("failed select symbols: %w", err)
+	}
+
+	return sym, nil
+}
+
+// SymbolTicker returns the canonical ticker of the symbol of the source,
+// it's empty if the symbol isn't mapped.
+func (s *Store) SymbolTicker(ctx context.Context, source symbol.Source, sym string) (string, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT ticker
+		FROM symbols
+		WHERE source = $1
+			AND symbol = $2;
+	`, source, sym)
+	ticker, err := pgx.CollectOneRow(rows, pgx.RowTo[string])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed select symbol ticker: %w", err)
+	}
+
+	return ticker, nil
+}
+
+// SaveCloses inserts or updates daily close prices.
+func (s *Store) SaveCloses(ctx context.Context, closes []price.Close) error {
+	if len(closes) == 0 {
+		return nil
+	}
+
+	query := pgsq.Insert("daily_closes").Columns("ticker", "date", "close")
+	for _, c := range closes {
+		query = query.Values(c.Ticker, c.Date, c.Close)
+	}
+
+	sql, args, err := query.Suffix("ON CONFLICT (ticker, date) DO UPDATE SET close = EXCLUDED.close").ToSql()
+	if err != nil {
+		return fmt.Errorf("closes insert to sql: %w", err)
+	}
+
+	if _, err := s.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("closes insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// TransactionReturns returns forward returns of transactions
+// notified since the date.
+func (s *Store) TransactionReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.TransactionReturn, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT id::text as id, ticker, owner, relationship, transaction_type, transaction_date, cost,
+			return_1d, return_5d, return_20d, return_60d
+		FROM transaction_returns
+		WHERE notification_date >= $1
+			AND NOT ($2 AND planned)
+		ORDER BY notification_date;
+	`, since, f.ExcludePlanned)
+	tr, err := pgx.CollectRows(rows, pgx.RowToStructByName[price.TransactionReturn])
+	if err != nil {
+		return nil, fmt.Errorf("failed select transaction returns: %w", err)
+	}
+
+	return tr, nil
+}
+
+// TypeReturns returns average forward returns by transaction type
+// of transactions notified since the date.
+func (s *Store) TypeReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.TypeReturn, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT transaction_type, count(*) as transaction_count,
+			avg(return_1d) as return_1d, avg(return_5d) as return_5d,
+			avg(return_20d) as return_20d, avg(return_60d) as return_60d,
+			avg(CASE WHEN transaction_type = 'Buy' THEN (return_20d > 0)::int ELSE (return_20d < 0)::int END) as hit_rate_20d
+		FROM transaction_returns
+		WHERE notification_date >= $1
+			AND NOT ($2 AND planned)
+		GROUP BY transaction_type
+		ORDER BY transaction_type;
+	`, since, f.ExcludePlanned)
+	tr, err := pgx.CollectRows(rows, pgx.RowToStructByName[price.TypeReturn])
+	if err != nil {
+		return nil, fmt.Errorf("failed select type returns: %w", err)
+	}
+
+	return tr, nil
+}
+
+// InsiderReturns returns average forward returns by insider and transaction type,
+// insiders with the best 20 days return go first.
+func (s *Store) InsiderReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.InsiderReturn, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT owner, transaction_type, count(*) as transaction_count,
+			avg(return_1d) as return_1d, avg(return_5d) as return_5d,
+			avg(return_20d) as return_20d, avg(return_60d) as return_60d,
+			avg(CASE WHEN transaction_type = 'Buy' THEN (return_20d > 0)::int ELSE (return_20d < 0)::int END) as hit_rate_20d
+		FROM transaction_returns
+		WHERE notification_date >= $1
+			AND NOT ($2 AND planned)
+		GROUP BY owner, transaction_type
+		ORDER BY return_20d DESC NULLS LAST;
+	`, since, f.ExcludePlanned)
+	ir, err := pgx.CollectRows(rows, pgx.RowToStructByName[price.InsiderReturn])
+	if err != nil {
+		return nil, fmt.Errorf("failed select insider returns: %w", err)
+	}
+
+	return ir, nil
+}
+
+// BuyReturns returns discretionary buys with the return after horizon trading days
+// and the benchmark return for the same period.
+func (s *Store) BuyReturns(ctx context.Context, horizon int, benchmark string) ([]score.BuyReturn, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT t.id::text as id, t.owner, COALESCE(t.owner_cik, '') as owner_cik, t.relationship,
+			fwd.close / t.cost - 1 as return,
+			bf.close / b0.close - 1 as benchmark_return
+		FROM transactions t
+		JOIN LATERAL (
+			-- the trading calendar of transaction_returns
+			SELECT DISTINCT date
+			FROM daily_closes
+			WHERE date > t.transaction_date::date
+			ORDER BY date
+			OFFSET $1 - 1
+			LIMIT 1
+		) cal ON true
+		JOIN daily_closes fwd ON fwd.ticker = t.ticker AND fwd.date = cal.date
+		JOIN daily_closes bf ON bf.ticker = $2 AND bf.date = cal.date
+		JOIN LATERAL (
+			SELECT close
+			FROM daily_closes
+			WHERE ticker = $2
+				AND date <= t.transaction_date::date
+			ORDER BY date DESC
+			LIMIT 1
+		) b0 ON true
+		WHERE t.transaction_type = 'Buy'
+			AND NOT t.planned
+			AND t.cost > 0;
+	`, horizon, benchmark)
+	rr, err := pgx.CollectRows(rows, pgx.RowToStructByName[score.BuyReturn])
+	if err != nil {
+		return nil, fmt.Errorf("failed select buy returns: %w", err)
+	}
+
+	return rr, nil
+}
+
+// SaveScores inserts or updates scores.
+func (s *Store) SaveScores(ctx context.Context, scores []score.Score) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	query := pgsq.Insert("insider_scores").Columns("kind", "name", "horizon",
+		"transactions", "hit_rate", "avg_excess_return", "updated_at")
+	for _, sc := range scores {
+		query = query.Values(sc.Kind, sc.Name, sc.Horizon, sc.Transactions,
+			sc.HitRate, sc.AvgExcessReturn, sc.UpdatedAt)
+	}
+
+	sql, args, err := query.Suffix(`ON CONFLICT (kind, name, horizon) DO UPDATE SET
+		transactions = EXCLUDED.transactions,
+		hit_rate = EXCLUDED.hit_rate,
+		avg_excess_return = EXCLUDED.avg_excess_return,
+		updated_at = EXCLUDED.updated_at`).ToSql()
+	if err != nil {
+		return fmt.Errorf("scores insert to sql: %w", err)
+	}
+
+	if _, err := s.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("scores insert exec: %w", err)
+	}
+
+	return nil
+}
+
+// Scores returns scores of the kind for the horizon, the best go first.
+func (s *Store) Scores(ctx context.Context, kind score.Kind, horizon int) ([]score.Score, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT kind, name, horizon, transactions, hit_rate, avg_excess_return, updated_at
+		FROM insider_scores
+		WHERE kind = $1
+			AND horizon = $2
+		ORDER BY avg_excess_return DESC;
+	`, kind, horizon)
+	sc, err := pgx.CollectRows(rows, pgx.RowToStructByName[score.Score])
+	if err != nil {
+		return nil, fmt.Errorf("failed select scores: %w", err)
+	}
+
+	return sc, nil
+}
+
+// TransactionScores returns buys of the report day annotated with
+// the insider's scores for all horizons.
+func (s *Store) TransactionScores(ctx context.Context, f insider.ReportFilter) ([]score.TransactionScore, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT t.id::text as id, t.ticker, t.owner, t.relationship, t.value,
+			sc.kind, sc.name, sc.horizon, sc.transactions, sc.hit_rate, sc.avg_excess_return, sc.updated_at
+		FROM transactions t
+		JOIN insider_scores sc ON sc.kind = 'insider' AND sc.name = COALESCE(t.owner_cik, t.owner)
+		WHERE t.notification_date::date = $2
+			AND NOT ($1 AND t.planned)
+			AND t.transaction_type = 'Buy'
+		ORDER BY t.value DESC, t.id, sc.horizon;
+	`, f.ExcludePlanned, f.Day)
+	ts, err := pgx.CollectRows(rows, pgx.RowToStructByName[score.TransactionScore])
+	if err != nil {
+		return nil, fmt.Errorf("failed select transaction scores: %w", err)
+	}
+
+	return ts, nil
+}
+
+// Rules returns all alert rules.
+func (s *Store) Rules(ctx context.Context) ([]alert.Rule, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT name, expr, enabled, created_at
+		FROM alert_rules
+		ORDER BY name;
+	`)
+	r, err := pgx.CollectRows(rows, pgx.RowToStructByName[alert.Rule])
+	if err != nil {
+		return nil, fmt.Errorf("failed select rules: %w", err)
+	}
+
+	return r, nil
+}
+
+// SaveRule inserts or updates the alert rule.
+func (s *Store) SaveRule(ctx context.Context, r alert.Rule) error {
+	if _, err := s.pool.Exec(ctx, `
+		INSERT INTO alert_rules (name, expr, enabled)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			expr = EXCLUDED.expr,
+			enabled = EXCLUDED.enabled;
+	`, r.Name, r.Expr, r.Enabled); err != nil {
+		return fmt.Errorf("failed save rule: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRule deletes the alert rule with its firing history.
+func (s *Store) DeleteRule(ctx context.Context, name string) error {
+	if _, err := s.pool.Exec(ctx, `
+		DELETE FROM alert_rules
+		WHERE name = $1;
+	`, name); err != nil {
+		return fmt.Errorf("failed delete rule: %w", err)
+	}
+
+	return nil
+}
+
+// Fire records the firing of the rule, it returns false
+// if the rule has already fired for the transaction.
+func (s *Store) Fire(ctx context.Context, rule, transactionID string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		INSERT INTO alert_firings (rule_name, transaction_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;
+	`, rule, transactionID)
+	if err != nil {
+		return false, fmt.Errorf("failed insert firing: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// Deliver marks the firing of the rule delivered.
+func (s *Store) Deliver(ctx context.Context, rule, transactionID string) error {
+	if _, err := s.pool.Exec(ctx, `
+		UPDATE alert_firings
+		SET delivered_at = now()
+		WHERE rule_name = $1 AND transaction_id = $2;
+	`, rule, transactionID); err != nil {
+		return fmt.Errorf("failed update firing: %w", err)
+	}
+
+	return nil
+}
+
+// Undelivered returns transactions of pending firings of the rule,
+// the oldest firing first.
+func (s *Store) Undelivered(ctx context.Context, rule string) (insider.Transactions, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT `+transactionColumns+`
+		FROM transactions t
+		JOIN alert_firings f ON f.transaction_id = t.id
+		WHERE f.rule_name = $1 AND f.delivered_at IS NULL
+		ORDER BY f.fired_at;
+	`, rule)
+	tr, err := pgx.CollectRows(rows, pgx.RowToStructByName[insider.Transaction])
+	if err != nil {
+		return nil, fmt.Errorf("failed select undelivered firings: %w", err)
+	}
+
+	return tr, nil
+}
+
+// Firings returns the firing history of the rule, the latest go first.
+func (s *Store) Firings(ctx context.Context, rule string, limit int) ([]alert.Firing, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT rule_name, transaction_id::text as transaction_id, fired_at, delivered_at
+		FROM alert_firings
+		WHERE rule_name = $1
+		ORDER BY fired_at DESC
+		LIMIT $2;
+	`, rule, limit)
+	f, err := pgx.CollectRows(rows, pgx.RowToStructByName[alert.Firing])
+	if err != nil {
+		return nil, fmt.Errorf("failed select firings: %w", err)
+	}
+
+	return f, nil
+}
+
+// StartRun inserts the run and sets its ID.
+func (s *Store) StartRun(ctx context.Context, r *run.Run) error {
+	if err := s.pool.QueryRow(ctx, `
+		INSERT INTO runs (started_at, day, source_urls, status, publish, sources)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id;
+	`, r.StartedAt, r.Day, r.SourceURLs, r.Status, r.Publish, r.Sources).Scan(&r.ID); err != nil {
+		return fmt.Errorf("failed insert run: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) SaveRun(ctx context.Context, r run.Run) error {
+	if _, err := s.pool.Exec(ctx, `
+		UPDATE runs
+		SET finished_at = $2,
+			source_urls = $3,
+			rows_scraped = $4,
+			rows_kept = $5,
+			rows_inserted = $6,
+			rows_duplicate = $7,
+			status = $8,
+			error = $9,
+			publish = $10,
+			sources = $11
+		WHERE id = $1;
+	`, r.ID, r.FinishedAt, r.SourceURLs, r.Scraped, r.Kept, r.Inserted, r.Duplicates,
+		r.Status, r.Error, r.Publish, r.Sources); err != nil {
+		return fmt.Errorf("failed update run: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Fetched(ctx context.Context, day time.Time) (bool, error) {
+	var ok bool
+	if err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM runs
+			WHERE day = $1
+				AND status = $2
+		);
+	`, day, run.StatusSucceeded).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed select fetched: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (s *Store) LastFetched(ctx context.Context) (time.Time, error) {
+	var last *time.Time
+	if err := s.pool.QueryRow(ctx, `
+		SELECT max(day)
+		FROM runs
+		WHERE status = $1;
+	`, run.StatusSucceeded).Scan(&last); err != nil {
+		return time.Time{}, fmt.Errorf("failed select last fetched: %w", err)
+	}
+
+	if last == nil {
+		return time.Time{}, nil
+	}
+
+	return *last, nil
+}
+
+func (s *Store) Runs(ctx context.Context, limit int) ([]run.Run, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT id, started_at, finished_at, day, source_urls, rows_scraped,
+			rows_kept, rows_inserted, rows_duplicate, status, error, publish, sources
+		FROM runs
+		ORDER BY id DESC
+		LIMIT $1;
+	`, limit)
+	r, err := pgx.CollectRows(rows, pgx.RowToStructByName[run.Run])
+	if err != nil {
+		return nil, fmt.Errorf("failed select runs: %w", err)
+	}
+
+	return r, nil
+}
+
+// PendingDigests returns not delivered digests, the oldest first.
+func (s *Store) PendingDigests(ctx context.Context) ([]publish.Digest, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT key, day, sink, attempts, last_error, created_at, delivered_at
+		FROM digests
+		WHERE delivered_at IS NULL
+		ORDER BY day, key;
+	`)
+	d, err := pgx.CollectRows(rows, pgx.RowToStructByName[publish.Digest])
+	if err != nil {
+		return nil, fmt.Errorf("failed select pending digests: %w", err)
+	}
+
+	return d, nil
+}
+
+// ClaimDigest leases the pending digest, the lease of the crashed
+// publisher expires, so its digest is retried.
+func (s *Store) ClaimDigest(ctx context.Context, key string, lease time.Duration) (publish.Digest, bool, error) {
+	now := time.Now()
+	rows, _ := s.pool.Query(ctx, `
+		UPDATE digests
+		SET locked_until = $2
+		WHERE key = $1
+			AND delivered_at IS NULL
+			AND (locked_until IS NULL OR locked_until <= $3)
+		RETURNING key, day, sink, attempts, last_error, created_at, delivered_at;
+	`, key, now.Add(lease), now)
+	d, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[publish.Digest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return publish.Digest{}, false, nil
+	}
+	if err != nil {
+		return publish.Digest{}, false, fmt.Errorf("failed claim digest: %w", err)
+	}
+
+	return d, true, nil
+}
+
+func (s *Store) DigestParts(ctx context.Context, key string) ([]string, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT part
+		FROM digest_parts
+		WHERE key = $1
+		ORDER BY part;
+	`, key)
+	parts, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed select digest parts: %w", err)
+	}
+
+	return parts, nil
+}
+
+func (s *Store) SaveDigestPart(ctx context.Context, key, part string) error {
+	if _, err := s.pool.Exec(ctx, `
+		INSERT INTO digest_parts (key, part)
+		VALUES ($1, $2)
+		ON CONFLICT (key, part) DO NOTHING;
+	`, key, part); err != nil {
+		return fmt.Errorf("failed insert digest part: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseDigest records the attempt of the claimed digest.
+func (s *Store) ReleaseDigest(ctx context.Context, key string, derr error) error {
+	lastError, deliveredAt := "", any(time.Now())
+	if derr != nil {
+		lastError, deliveredAt = derr.Error(), nil
+	}
+
+	if _, err := s.pool.Exec(ctx, `
+		UPDATE digests
+		SET attempts = attempts + 1,
+			last_error = $2,
+			delivered_at = $3,
+			locked_until = NULL
+		WHERE key = $1;
+	`, key, lastError, deliveredAt); err != nil {
+		return fmt.Errorf("failed update digest: %w", err)
+	}
+
+	return nil
+}
+
+// PendingEvents returns not published events in the order of notification date.
+func (s *Store) PendingEvents(ctx context.Context, limit int) ([]event.Event, error) {
+	rows, _ := s.pool.Query(ctx, `
+		SELECT id, event_type, event_key, notification_date, created_at, payload
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY notification_date, id
+		LIMIT $1;
+	`, limit)
+	e, err := pgx.CollectRows(rows, pgx.RowToStructByName[event.Event])
+	if err != nil {
+		return nil, fmt.Errorf("failed select pending events: %w", err)
+	}
+
+	return e, nil
+}
+
+func (s *Store) MarkPublished(ctx context.Context, id int64) error {
+	if _, err := s.pool.Exec(ctx, `
+		UPDATE outbox
+		SET published_at = now()
+		WHERE id = $1;
+	`, id); err != nil {
+		return fmt.Errorf("failed update event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package store
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions(t *testing.T) {
+	o := Options{Host: "db:6432", Database: "finviz", Username: "finviz", Password: "s3cret", MinPool: 2, MaxPool: 10}
+
+	assert.Equal(t, "host=db port=6432 dbname=finviz user=finviz password=s3cret sslmode=disable pool_min_conns=2 pool_max_conns=10", o.ConnString())
+	assert.Equal(t, "host=db port=6432 dbname=finviz user=finviz password=[REDACTED] sslmode=disable pool_min_conns=2 pool_max_conns=10", o.String())
+	assert.NotContains(t, o.LogValue().String(), "s3cret")
+	assert.Equal(t, slog.KindString, o.LogValue().Kind())
+}
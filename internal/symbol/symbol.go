@@ -0,0 +1,126 @@
+// Package symbol normalizes tickers and maps them to symbols of data sources,
+// so transactions, prices and filings of the same security line up.
+package symbol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Source is the data source or the vendor with its own symbol format.
+type Source string
+
+const (
+	// Finviz and Yahoo separate the share class with a dash: BRK-B.
+	Finviz Source = "finviz"
+	Yahoo  Source = "yahoo"
+	// Bloomberg separates the share class with a slash: BRK/B.
+	Bloomberg Source = "bloomberg"
+	// EDGAR symbols are CIKs of issuers, they are known only from the mapping.
+	EDGAR Source = "edgar"
+)
+
+// Sources are all known sources.
+var Sources = []Source{Finviz, Yahoo, Bloomberg, EDGAR}
+
+// Symbol maps the symbol of the source to the canonical ticker.
+type Symbol struct {
+	Source    Source    `json:"source" db:"source"`
+	Symbol    string    `json:"symbol" db:"symbol"`
+	Ticker    string    `json:"ticker" db:"ticker"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Storer interface {
+	// SaveSymbols inserts or updates symbols by the source and the symbol.
+	SaveSymbols(ctx context.Context, s []Symbol) error
+	// Symbols returns all symbols of the canonical ticker.
+	Symbols(ctx context.Context, ticker string) ([]Symbol, error)
+	// SymbolTicker returns the canonical ticker of the symbol,
+	// it's empty if the symbol isn't mapped.
+	SymbolTicker(ctx context.Context, source Source, symbol string) (string, error)
+}
+
+// Normalize returns the canonical ticker: upper case
+// with the share class after the dot, BRK-B and brk/b are BRK.B.
+func Normalize(ticker string) string {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+
+	return strings.NewReplacer("-", ".", "/", ".", " ", ".").Replace(ticker)
+}
+
+// Format returns the symbol of the canonical ticker in the format of the source.
+// EDGAR CIKs can't be derived, the ticker is returned as is.
+func Format(ticker string, source Source) string {
+	ticker = Normalize(ticker)
+
+	switch source {
+	case Finviz, Yahoo:
+		return strings.ReplaceAll(ticker, ".", "-")
+	case Bloomberg:
+		return strings.ReplaceAll(ticker, ".", "/")
+	default:
+		return ticker
+	}
+}
+
+// ParseSource returns the known source by the name.
+func ParseSource(s string) (Source, error) {
+	for _, src := range Sources {
+		if string(src) == strings.ToLower(s) {
+			return src, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown symbol source %q", s)
+}
+
+// Mapper resolves symbols with the stored mapping,
+// symbols without the mapping are converted by the format of the source.
+type Mapper struct {
+	store Storer
+}
+
+func NewMapper(store Storer) *Mapper {
+	return &Mapper{store: store}
+}
+
+// Ticker returns the canonical ticker of the symbol of the source,
+// it's empty for EDGAR without the mapping.
+func (m *Mapper) Ticker(ctx context.Context, source Source, symbol string) (string, error) {
+	t, err := m.store.SymbolTicker(ctx, source, symbol)
+	if err != nil {
+		return "", fmt.Errorf("failed get symbol ticker: %w", err)
+	}
+
+	if t != "" || source == EDGAR {
+		return t, nil
+	}
+
+	return Normalize(symbol), nil
+}
+
+// Symbol returns the symbol of the ticker in the source,
+// it's empty for EDGAR without the mapping.
+func (m *Mapper) Symbol(ctx context.Context, ticker string, source Source) (string, error) {
+	ticker = Normalize(ticker)
+
+	symbols, err := m.store.Symbols(ctx, ticker)
+	if err != nil {
+		return "", fmt.Errorf("failed get symbols: %w", err)
+	}
+
+	for _, s := range symbols {
+		if s.Source == source {
+			return s.Symbol, nil
+		}
+	}
+
+	if source == EDGAR {
+		return "", nil
+	}
+
+	return Format(ticker, source), nil
+}
@@ -0,0 +1,94 @@
+package symbol_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	"github.com/RyabovNick/finviz_parser/internal/symbol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		ticker string
+		want   string
+	}{
+		{ticker: "AAPL", want: "AAPL"},
+		{ticker: " aapl ", want: "AAPL"},
+		{ticker: "BRK-B", want: "BRK.B"},
+		{ticker: "BRK.B", want: "BRK.B"},
+		{ticker: "BF/A", want: "BF.A"},
+		{ticker: "brk b", want: "BRK.B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ticker, func(t *testing.T) {
+			assert.Equal(t, tt.want, symbol.Normalize(tt.ticker))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		source symbol.Source
+		want   string
+	}{
+		{source: symbol.Finviz, want: "BRK-B"},
+		{source: symbol.Yahoo, want: "BRK-B"},
+		{source: symbol.Bloomberg, want: "BRK/B"},
+		{source: symbol.EDGAR, want: "BRK.B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.source), func(t *testing.T) {
+			assert.Equal(t, tt.want, symbol.Format("brk-b", tt.source))
+		})
+	}
+}
+
+func TestMapper(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	require.NoError(t, store.SaveSymbols(ctx, []symbol.Symbol{
+		{Source: symbol.EDGAR, Symbol: "1067983", Ticker: "BRK.B", UpdatedAt: time.Now()},
+		// the vendor lists the ticker under the old name
+		{Source: symbol.Yahoo, Symbol: "FB", Ticker: "META", UpdatedAt: time.Now()},
+	}))
+
+	m := symbol.NewMapper(store)
+
+	ticker, err := m.Ticker(ctx, symbol.EDGAR, "1067983")
+	require.NoError(t, err)
+	assert.Equal(t, "BRK.B", ticker)
+
+	ticker, err = m.Ticker(ctx, symbol.Yahoo, "FB")
+	require.NoError(t, err)
+	assert.Equal(t, "META", ticker, "mapped")
+
+	ticker, err = m.Ticker(ctx, symbol.Finviz, "BF-A")
+	require.NoError(t, err)
+	assert.Equal(t, "BF.A", ticker, "normalized")
+
+	ticker, err = m.Ticker(ctx, symbol.EDGAR, "320193")
+	require.NoError(t, err)
+	assert.Empty(t, ticker, "CIK isn't mapped")
+
+	sym, err := m.Symbol(ctx, "BRK-B", symbol.EDGAR)
+	require.NoError(t, err)
+	assert.Equal(t, "1067983", sym)
+
+	sym, err = m.Symbol(ctx, "META", symbol.Yahoo)
+	require.NoError(t, err)
+	assert.Equal(t, "FB", sym, "mapped")
+
+	sym, err = m.Symbol(ctx, "BRK.B", symbol.Finviz)
+	require.NoError(t, err)
+	assert.Equal(t, "BRK-B", sym, "formatted")
+
+	sym, err = m.Symbol(ctx, "AAPL", symbol.EDGAR)
+	require.NoError(t, err)
+	assert.Empty(t, sym, "CIK is unknown")
+}
@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/chart"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+)
+
+// trendDays is the period of the trend chart.
+const trendDays = 30
+
+// photo is the chart sent after the digest messages,
+// the caption is rendered by the template.
+type photo struct {
+	name     string
+	template string
+	png      []byte
+}
+
+// charts renders charts of the report, charts without data are skipped.
+func charts(r Report) ([]photo, error) {
+	var photos []photo
+
+	net, err := chart.Bars("Net buy/sell, $", netBars(r))
+	switch {
+	case errors.Is(err, chart.ErrNoData):
+	case err != nil:
+		return nil, fmt.Errorf("net chart: %w", err)
+	default:
+		photos = append(photos, photo{name: "net.png", template: "net_chart", png: net})
+	}
+
+	days, buys, sales := trend(r)
+	tr, err := chart.Trend(fmt.Sprintf("Buy vs sale value, %d days", trendDays), days,
+		chart.Series{Label: "Buy", Color: chart.BuyColor, Values: buys},
+		chart.Series{Label: "Sale", Color: chart.SaleColor, Values: sales},
+	)
+	switch {
+	case errors.Is(err, chart.ErrNoData):
+	case err != nil:
+		return nil, fmt.Errorf("trend chart: %w", err)
+	default:
+		photos = append(photos, photo{name: "trend.png", template: "trend_chart", png: tr})
+	}
+
+	return photos, nil
+}
+
+// netBars returns tickers of top buys and sells by net value.
+func netBars(r Report) []chart.Bar {
+	seen := make(map[string]struct{})
+
+	var bars []chart.Bar
+	for _, t := range append(append([]insider.TotalTransaction(nil), r.TopBuy...), r.TopSell...) {
+		if _, ok := seen[t.Ticker]; ok {
+			continue
+		}
+		seen[t.Ticker] = struct{}{}
+
+		bars = append(bars, chart.Bar{Label: t.Ticker, Value: t.TotalValue})
+	}
+
+	sort.SliceStable(bars, func(i, j int) bool {
+		return bars[i].Value > bars[j].Value
+	})
+
+	return bars
+}
+
+// trend returns values of buys and sales of every day of the period,
+// days without transactions are zero.
+func trend(r Report) ([]time.Time, []float64, []float64) {
+	since := r.Day.AddDate(0, 0, -(trendDays - 1))
+
+	days := make([]time.Time, trendDays)
+	index := make(map[string]int, trendDays)
+	for i := range days {
+		days[i] = since.AddDate(0, 0, i)
+		index[days[i].Format(time.DateOnly)] = i
+	}
+
+	buys, sales := make([]float64, trendDays), make([]float64, trendDays)
+	for _, t := range r.Trend {
+		i, ok := index[t.Day.Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+
+		switch t.Transaction {
+		case insider.Buy:
+			buys[i] += t.TotalValue
+		case insider.Sale:
+			sales[i] += t.TotalValue
+		}
+	}
+
+	return days, buys, sales
+}
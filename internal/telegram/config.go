@@ -1,14 +1,37 @@
 package telegram
 
 import (
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
 	Token string
-	Chat  int64
+	// Chats receive all messages, the digest is rendered
+	// by the template of the chat.
+	Chats []Chat
+	// ExcludePlanned excludes Rule 10b5-1 planned transactions from
+	// the reports, it's off by default. Planned sales are always
+	// shown in the separate message.
+	ExcludePlanned bool
+}
+
+// LogValue keeps the token out of logs.
+func (c Config) LogValue() slog.Value {
+	token := ""
+	if c.Token != "" {
+		token = "[REDACTED]"
+	}
+
+	return slog.GroupValue(
+		slog.String("token", token),
+		slog.Int("chats", len(c.Chats)),
+		slog.Bool("exclude_planned", c.ExcludePlanned),
+	)
 }
 
 func ParseTelegramConfig() Config {
@@ -18,17 +41,84 @@ func ParseTelegramConfig() Config {
 	}
 
 	chat := os.Getenv("CHAT_ID")
-	if token == "" {
+	if chat == "" {
 		log.Fatal("ENV: CHAT_ID not found")
 	}
 
-	ch, err := strconv.ParseInt(chat, 10, 64)
+	chats, err := parseChats(chat, os.Getenv("TG_TEMPLATE"))
+	if err != nil {
+		log.Fatalf("env: CHAT_ID cannot convert: %s", err)
+	}
+
+	return Config{
+		Token:          token,
+		Chats:          chats,
+		ExcludePlanned: parseExcludePlanned(),
+	}
+}
+
+// Chat is the chat and the name or the file of its digest template.
+type Chat struct {
+	ID       int64
+	Template string
+}
+
+// parseChats parses the comma separated list of chats,
+// every chat is the ID with the optional template after the colon:
+// "-100123:ru,-100456:/etc/finviz/digest.tmpl". Chats without
+// the template get the default one.
+func parseChats(s, defaultTemplate string) ([]Chat, error) {
+	if defaultTemplate == "" {
+		defaultTemplate = DefaultTemplate
+	}
+
+	var chats []Chat
+	for _, v := range strings.Split(s, ",") {
+		id, tmpl, ok := strings.Cut(strings.TrimSpace(v), ":")
+		if !ok || tmpl == "" {
+			tmpl = defaultTemplate
+		}
+
+		ch, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("chat %q: %w", id, err)
+		}
+
+		chats = append(chats, Chat{ID: ch, Template: tmpl})
+	}
+
+	return chats, nil
+}
+
+// ParseDryRunConfig parses the config of dry runs,
+// the bot isn't used so TG_TOKEN and CHAT_ID aren't required.
+func ParseDryRunConfig() Config {
+	chat := os.Getenv("CHAT_ID")
+	if chat == "" {
+		chat = "0"
+	}
+
+	chats, err := parseChats(chat, os.Getenv("TG_TEMPLATE"))
 	if err != nil {
-		log.Fatal("env: cannot convert")
+		log.Fatalf("env: CHAT_ID cannot convert: %s", err)
 	}
 
 	return Config{
-		Token: token,
-		Chat:  ch,
+		Chats:          chats,
+		ExcludePlanned: parseExcludePlanned(),
+	}
+}
+
+func parseExcludePlanned() bool {
+	v := os.Getenv("EXCLUDE_PLANNED")
+	if v == "" {
+		return false
 	}
+
+	excludePlanned, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatal("env: EXCLUDE_PLANNED cannot convert")
+	}
+
+	return excludePlanned
 }
@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	linkRe = regexp.MustCompile(`<a href='([^']*)'>(.*?)</a>`)
+	tagRe  = regexp.MustCompile(`<[^>]+>`)
+)
+
+// Printer prints messages instead of sending them.
+type Printer struct {
+	mu sync.Mutex
+	w  io.Writer
+	n  int
+}
+
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w}
+}
+
+// Send prints the message rendered as text and as HTML,
+// photos are printed with the caption and the size.
+func (p *Printer) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	switch msg := c.(type) {
+	case tgbotapi.MessageConfig:
+		p.n++
+		err = p.print(fmt.Sprintf("message %d to %d", p.n, msg.ChatID), msg.Text, msg.ParseMode)
+	case tgbotapi.PhotoConfig:
+		p.n++
+		file, ok := msg.File.(tgbotapi.FileBytes)
+		if !ok {
+			return tgbotapi.Message{}, fmt.Errorf("unsupported photo file %T", msg.File)
+		}
+		err = p.print(fmt.Sprintf("photo %d to %d (%s, %d bytes)", p.n, msg.ChatID, file.Name, len(file.Bytes)),
+			msg.Caption, msg.ParseMode)
+	default:
+		return tgbotapi.Message{}, fmt.Errorf("unsupported message %T", c)
+	}
+	if err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("print: %w", err)
+	}
+
+	return tgbotapi.Message{MessageID: p.n}, nil
+}
+
+func (p *Printer) print(header, text, mode string) error {
+	plain := text
+	if mode == ParseModeHTML {
+		plain = PlainText(text)
+	}
+
+	_, err := fmt.Fprintf(p.w, "--- %s, text ---\n%s\n--- %s, %s ---\n%s\n\n",
+		header, plain, header, parseMode(mode), text)
+
+	return err
+}
+
+func parseMode(m string) string {
+	if m == "" {
+		return "raw"
+	}
+
+	return m
+}
+
+// PlainText renders the HTML message like Telegram shows it,
+// links are followed by the URL.
+func PlainText(s string) string {
+	s = linkRe.ReplaceAllString(s, "$2 ($1)")
+	s = tagRe.ReplaceAllString(s, "")
+
+	return html.UnescapeString(s)
+}
+
+// NewDryRun returns the connection which prints messages to w.
+func NewDryRun(cfg Config, store Storer, w io.Writer) (*Connection, error) {
+	return newConnection(NewPrinter(w), cfg, store)
+}
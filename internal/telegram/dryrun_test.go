@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"bytes"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "bold",
+			html: "<b>Top 20 buy:</b>",
+			want: "Top 20 buy:",
+		},
+		{
+			name: "link",
+			html: "<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a>: 600",
+			want: "AAA (https://finviz.com/quote.ashx?t=AAA): 600",
+		},
+		{
+			name: "entities",
+			html: "Smith &amp; Sons &lt;CEO&gt;",
+			want: "Smith & Sons <CEO>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PlainText(tt.html))
+		})
+	}
+}
+
+func TestPrinter_Send(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinter(&buf)
+
+	msg := tgbotapi.NewMessage(0, "<b>Alert: large</b>\n<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a> Buy")
+	msg.ParseMode = ParseModeHTML
+
+	sent, err := p.Send(msg)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent.MessageID)
+
+	assert.Equal(t, "--- message 1 to 0, text ---\n"+
+		"Alert: large\nAAA (https://finviz.com/quote.ashx?t=AAA) Buy\n"+
+		"--- message 1 to 0, HTML ---\n"+
+		"<b>Alert: large</b>\n<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a> Buy\n\n", buf.String())
+
+	buf.Reset()
+	photo := tgbotapi.NewPhoto(0, tgbotapi.FileBytes{Name: "chart.png", Bytes: []byte("png")})
+	photo.Caption = "<b>Chart</b>"
+	photo.ParseMode = ParseModeHTML
+
+	_, err = p.Send(photo)
+	require.NoError(t, err)
+	assert.Equal(t, "--- photo 2 to 0 (chart.png, 3 bytes), text ---\nChart\n"+
+		"--- photo 2 to 0 (chart.png, 3 bytes), HTML ---\n<b>Chart</b>\n\n", buf.String())
+
+	_, err = p.Send(tgbotapi.NewDocument(0, tgbotapi.FileBytes{Name: "report.csv"}))
+	assert.Error(t, err)
+}
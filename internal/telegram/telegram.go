@@ -3,30 +3,74 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/RyabovNick/finviz_parser/internal/alert"
+	"github.com/RyabovNick/finviz_parser/internal/company"
 	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/metrics"
+	"github.com/RyabovNick/finviz_parser/internal/price"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+	"github.com/RyabovNick/finviz_parser/internal/tracing"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
 	ParseModeHTML = "HTML"
+
+	// performanceDays is the period of the performance report,
+	// enough to have 60 trading days returns.
+	performanceDays = 90
+	// topInsiders is the number of insiders in the performance report.
+	topInsiders = 10
 )
 
 type Storer interface {
-	TransactionTypeCount(ctx context.Context) ([]insider.TransactionTypeCount, error)
+	TransactionTypeCount(ctx context.Context, f insider.ReportFilter) ([]insider.TransactionTypeCount, error)
+	DailyTotals(ctx context.Context, since time.Time, f insider.ReportFilter) ([]insider.DailyTotal, error)
+
+	TopBuy(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error)
+	TopSell(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error)
+	TopPlannedSell(ctx context.Context, f insider.ReportFilter) ([]insider.TotalTransaction, error)
+
+	BuyTicker(ctx context.Context, f insider.ReportFilter) (insider.Tickers, error)
+	SaleTicker(ctx context.Context, f insider.ReportFilter) (insider.Tickers, error)
 
-	TopBuy(ctx context.Context) ([]insider.TotalTransaction, error)
-	TopSell(ctx context.Context) ([]insider.TotalTransaction, error)
+	TypeReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.TypeReturn, error)
+	InsiderReturns(ctx context.Context, since time.Time, f insider.ReportFilter) ([]price.InsiderReturn, error)
 
-	BuyTicker(ctx context.Context) (insider.Tickers, error)
-	SaleTicker(ctx context.Context) (insider.Tickers, error)
+	TransactionScores(ctx context.Context, f insider.ReportFilter) ([]score.TransactionScore, error)
+
+	UnusualTransactions(ctx context.Context, f insider.ReportFilter) (insider.Transactions, error)
+
+	SectorTotals(ctx context.Context, f insider.ReportFilter) ([]company.SectorTotal, error)
+	SignificantBuy(ctx context.Context, f insider.ReportFilter) ([]company.Significance, error)
+	SignificantSell(ctx context.Context, f insider.ReportFilter) ([]company.Significance, error)
+}
+
+// Sender delivers messages, it's the bot or the printer of dry runs.
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
 }
 
 type Connection struct {
-	Bot   *tgbotapi.BotAPI
-	Chat  int64
-	store Storer
+	Bot    Sender
+	chats  []chat
+	store  Storer
+	filter insider.ReportFilter
+}
+
+// chat receives all messages, the digest is rendered by its template.
+type chat struct {
+	id   int64
+	tmpl *template.Template
 }
 
 func New(cfg Config, store Storer) (*Connection, error) {
@@ -35,120 +79,338 @@ func New(cfg Config, store Storer) (*Connection, error) {
 		return nil, fmt.Errorf("error creating bot: %w", err)
 	}
 
+	return newConnection(bot, cfg, store)
+}
+
+func newConnection(bot Sender, cfg Config, store Storer) (*Connection, error) {
+	chats := make([]chat, 0, len(cfg.Chats))
+	for _, ch := range cfg.Chats {
+		tmpl, err := LoadTemplate(ch.Template)
+		if err != nil {
+			return nil, fmt.Errorf("chat %d: %w", ch.ID, err)
+		}
+
+		chats = append(chats, chat{id: ch.ID, tmpl: tmpl})
+	}
+
 	return &Connection{
 		Bot:   bot,
-		Chat:  cfg.Chat,
+		chats: chats,
 		store: store,
+		filter: insider.ReportFilter{
+			ExcludePlanned: cfg.ExcludePlanned,
+		},
 	}, nil
 }
 
-func (c *Connection) Publish(ctx context.Context) error {
-	if err := c.transactionTypeCount(ctx); err != nil {
-		return fmt.Errorf("error publishing transaction type count: %w", err)
+// Publish sends the digest of transactions notified on the day,
+// every chat gets the digest rendered by its template. Every message
+// is the part of the digest: the chat and the number of the message
+// or the name of the chart, parts sent by earlier attempts are skipped.
+func (c *Connection) Publish(ctx context.Context, day time.Time, progress *publish.Progress) error {
+	f := c.filter
+	f.Day = day
+
+	r, err := c.report(ctx, f)
+	if err != nil {
+		return err
 	}
 
-	if err := c.topBuy(ctx); err != nil {
-		return fmt.Errorf("error publishing top buy: %w", err)
+	photos, err := charts(r)
+	if err != nil {
+		return fmt.Errorf("error rendering charts: %w", err)
 	}
 
-	if err := c.topSell(ctx); err != nil {
-		return fmt.Errorf("error publishing top sell: %w", err)
+	for _, ch := range c.chats {
+		messages, err := render(ch.tmpl, r)
+		if err != nil {
+			return fmt.Errorf("error rendering digest for chat %d: %w", ch.id, err)
+		}
+
+		for i, text := range messages {
+			err := c.sendPart(ctx, progress, fmt.Sprintf("%d/%d", ch.id, i), func() error {
+				return c.sendTo(ctx, ch.id, text)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, p := range photos {
+			caption, err := execute(ch.tmpl, p.template, r)
+			if err != nil {
+				return fmt.Errorf("error rendering caption for chat %d: %w", ch.id, err)
+			}
+
+			// the empty caption turns the chart off
+			if caption == "" {
+				continue
+			}
+
+			err = c.sendPart(ctx, progress, fmt.Sprintf("%d/%s", ch.id, p.name), func() error {
+				return c.sendPhoto(ctx, ch.id, p, caption)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		slog.InfoContext(ctx, "digest sent", "chat", ch.id, "messages", len(messages))
 	}
 
 	return nil
 }
 
-func (c *Connection) transactionTypeCount(ctx context.Context) error {
-	tt, err := c.store.TransactionTypeCount(ctx)
+// report collects the data of the digest, it's empty on the day
+// without transactions: weekends and holidays.
+func (c *Connection) report(ctx context.Context, f insider.ReportFilter) (Report, error) {
+	r := Report{Day: f.Day, ExcludePlanned: f.ExcludePlanned}
+
+	var err error
+	r.Counts, err = c.store.TransactionTypeCount(ctx, f)
 	if err != nil {
-		return fmt.Errorf("error getting transaction type count: %w", err)
+		return r, fmt.Errorf("error getting transaction type count: %w", err)
 	}
 
-	if len(tt) == 0 {
-		return fmt.Errorf("transaction type count is empty")
+	// sectors are known only for scraped companies
+	st, err := c.store.SectorTotals(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting sector totals: %w", err)
 	}
+	r.Sectors = company.Sectors(st)
 
-	text := make([]string, 0, len(tt)+1)
-	text = append(text, "<b>Transaction count and total_value (in $):</b>")
+	r.TopBuy, err = c.store.TopBuy(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting top buy: %w", err)
+	}
 
-	for _, t := range tt {
-		text = append(text, fmt.Sprintf("%s: %d (%.0f)", t.Transaction, t.TransactionCount, t.TotalValue))
+	r.BuyTickers, err = c.store.BuyTicker(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting buy tickers: %w", err)
 	}
 
-	msg := tgbotapi.NewMessage(c.Chat, strings.Join(text, "\n"))
-	msg.ParseMode = ParseModeHTML
+	r.TopSell, err = c.store.TopSell(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting top sell: %w", err)
+	}
 
-	if _, err := c.Bot.Send(msg); err != nil {
-		return fmt.Errorf("error sending message: %w", err)
+	r.SaleTickers, err = c.store.SaleTicker(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting sale tickers: %w", err)
 	}
 
-	return nil
-}
+	// the market cap is known only for scraped companies
+	r.SignificantBuy, err = c.store.SignificantBuy(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting significant buy: %w", err)
+	}
 
-func (c *Connection) topBuy(ctx context.Context) error {
-	tt, err := c.store.TopBuy(ctx)
+	r.SignificantSell, err = c.store.SignificantSell(ctx, f)
 	if err != nil {
-		return fmt.Errorf("error getting transaction type count: %w", err)
+		return r, fmt.Errorf("error getting significant sell: %w", err)
 	}
 
-	if len(tt) == 0 {
-		return fmt.Errorf("top buy is empty")
+	r.Unusual, err = c.store.UnusualTransactions(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting unusual transactions: %w", err)
 	}
 
-	text := make([]string, 0, len(tt)+2)
-	text = append(text, "<b>Top 20 buy:</b>")
+	// there are no scores until prices are collected
+	ts, err := c.store.TransactionScores(ctx, f)
+	if err != nil {
+		return r, fmt.Errorf("error getting transaction scores: %w", err)
+	}
+	r.TrackRecord = trackRecords(ts)
 
-	for _, t := range tt {
-		text = append(text, fmt.Sprintf("%s: %.0f", t.FinvizTicker(), t.TotalValue))
+	r.Trend, err = c.store.DailyTotals(ctx, f.Day.AddDate(0, 0, -(trendDays-1)), f)
+	if err != nil {
+		return r, fmt.Errorf("error getting daily totals: %w", err)
 	}
 
-	tick, err := c.store.BuyTicker(ctx)
+	// planned sales are always shown separately, the reports above
+	// include them unless they are excluded
+	r.TopPlannedSell, err = c.store.TopPlannedSell(ctx, f)
 	if err != nil {
-		return fmt.Errorf("error getting tickers: %w", err)
+		return r, fmt.Errorf("error getting top planned sell: %w", err)
 	}
 
-	text = append(text, tick.Finviz())
+	return r, nil
+}
+
+// trackRecords groups scores by transaction,
+// rows are ordered by transaction, one row per horizon.
+func trackRecords(ts []score.TransactionScore) []TrackRecord {
+	var tr []TrackRecord
+	for _, t := range ts {
+		if n := len(tr); n > 0 && tr[n-1].TransactionID == t.TransactionID {
+			tr[n-1].Scores = append(tr[n-1].Scores, t.Score)
+			continue
+		}
+
+		tr = append(tr, TrackRecord{
+			TransactionID: t.TransactionID,
+			Ticker:        t.Ticker,
+			Owner:         t.Owner,
+			Relationship:  t.Relationship,
+			Value:         t.Value,
+			Scores:        []score.Score{t.Score},
+		})
+	}
 
-	msg := tgbotapi.NewMessage(c.Chat, strings.Join(text, "\n"))
+	return tr
+}
+
+// send sends the HTML message to all chats, the message to the chat
+// is the part of the digest named by the chat.
+func (c *Connection) send(ctx context.Context, progress *publish.Progress, text string) error {
+	for _, ch := range c.chats {
+		err := c.sendPart(ctx, progress, strconv.FormatInt(ch.id, 10), func() error {
+			return c.sendTo(ctx, ch.id, text)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendPart sends the part of the digest unless it has been sent.
+func (c *Connection) sendPart(ctx context.Context, progress *publish.Progress, part string, send func() error) error {
+	if progress.Sent(part) {
+		return nil
+	}
+
+	if err := send(); err != nil {
+		return err
+	}
+
+	return progress.Done(ctx, part)
+}
+
+func (c *Connection) sendPhoto(ctx context.Context, chat int64, p photo, caption string) error {
+	msg := tgbotapi.NewPhoto(chat, tgbotapi.FileBytes{Name: p.name, Bytes: p.png})
+	msg.Caption = caption
+	msg.ParseMode = ParseModeHTML
+
+	if err := c.deliver(ctx, chat, msg); err != nil {
+		return fmt.Errorf("error sending photo: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Connection) sendTo(ctx context.Context, chat int64, text string) error {
+	msg := tgbotapi.NewMessage(chat, text)
 	msg.ParseMode = ParseModeHTML
 
-	if _, err := c.Bot.Send(msg); err != nil {
+	if err := c.deliver(ctx, chat, msg); err != nil {
 		return fmt.Errorf("error sending message: %w", err)
 	}
 
 	return nil
 }
 
-func (c *Connection) topSell(ctx context.Context) error {
-	tt, err := c.store.TopSell(ctx)
+// deliver sends the message and records the latency and failures.
+func (c *Connection) deliver(ctx context.Context, chat int64, msg tgbotapi.Chattable) error {
+	ctx, span := tracing.Start(ctx, "telegram.send", attribute.Int64("chat", chat))
+	start := time.Now()
+	_, err := c.Bot.Send(msg)
+	elapsed := time.Since(start)
+	metrics.TelegramSendDuration.Observe(elapsed.Seconds())
+	tracing.End(span, err)
 	if err != nil {
-		return fmt.Errorf("error getting transaction type count: %w", err)
+		metrics.TelegramSendFailures.Inc()
+		slog.WarnContext(ctx, "telegram send failed", "chat", chat, "duration", elapsed, "err", err)
+		return err
 	}
+	slog.DebugContext(ctx, "telegram sent", "chat", chat, "duration", elapsed)
 
-	if len(tt) == 0 {
-		return fmt.Errorf("top sell is empty")
-	}
+	return nil
+}
 
-	text := make([]string, 0, len(tt)+1)
-	text = append(text, "<b>Top 20 sell:</b>")
+// PublishPerformance sends "did insiders get it right" report:
+// how the stocks did after insiders' transactions.
+func (c *Connection) PublishPerformance(ctx context.Context, day time.Time, progress *publish.Progress) error {
+	since := day.AddDate(0, 0, -performanceDays)
 
-	for _, t := range tt {
-		text = append(text, fmt.Sprintf("%s: %.0f", t.FinvizTicker(), t.TotalValue))
+	tr, err := c.store.TypeReturns(ctx, since, c.filter)
+	if err != nil {
+		return fmt.Errorf("error getting type returns: %w", err)
+	}
+
+	// nothing is sent until closes of the period are stored
+	if len(tr) == 0 {
+		slog.InfoContext(ctx, "skip performance without returns", "day", day.Format(time.DateOnly))
+		return nil
 	}
 
-	tick, err := c.store.SaleTicker(ctx)
+	ir, err := c.store.InsiderReturns(ctx, since, c.filter)
 	if err != nil {
-		return fmt.Errorf("error getting tickers: %w", err)
+		return fmt.Errorf("error getting insider returns: %w", err)
 	}
 
-	text = append(text, tick.Finviz())
+	text := make([]string, 0, len(tr)+topInsiders+2)
+	text = append(text, fmt.Sprintf("<b>Did insiders get it right? Returns after the trade for %d days:</b>", performanceDays))
 
-	msg := tgbotapi.NewMessage(c.Chat, strings.Join(text, "\n"))
-	msg.ParseMode = ParseModeHTML
+	for _, t := range tr {
+		text = append(text, fmt.Sprintf("%s (%d): 1d %s, 5d %s, 20d %s, 60d %s, hit rate 20d %s",
+			t.Transaction, t.TransactionCount, percent(t.Return1d), percent(t.Return5d),
+			percent(t.Return20d), percent(t.Return60d), share(t.HitRate20d)))
+	}
 
-	if _, err := c.Bot.Send(msg); err != nil {
-		return fmt.Errorf("error sending message: %w", err)
+	text = append(text, fmt.Sprintf("<b>Top %d insiders by 20d return after buy:</b>", topInsiders))
+
+	n := 0
+	for _, r := range ir {
+		if r.Transaction != insider.Buy || r.Return20d == nil {
+			continue
+		}
+
+		text = append(text, fmt.Sprintf("%s (%d): %s", html.EscapeString(r.Owner), r.TransactionCount, percent(r.Return20d)))
+
+		n++
+		if n == topInsiders {
+			break
+		}
 	}
 
-	return nil
+	return c.send(ctx, progress, strings.Join(text, "\n"))
+}
+
+func percent(v *float64) string {
+	if v == nil {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%+.1f%%", *v*100)
+}
+
+func share(v *float64) string {
+	if v == nil {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%.0f%%", *v*100)
+}
+
+// fraction formats small shares, like shares of the float.
+func fraction(v *float64) string {
+	if v == nil {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%.2f%%", *v*100)
+}
+
+// Alert sends the notification about the fired rule.
+func (c *Connection) Alert(ctx context.Context, rule alert.Rule, t insider.Transaction) error {
+	text := []string{
+		fmt.Sprintf("<b>Alert: %s</b>", html.EscapeString(rule.Name)),
+		fmt.Sprintf("%s %s by %s (%s)", insider.QuoteLink(t.Ticker), t.Transaction,
+			html.EscapeString(t.Owner), html.EscapeString(t.Relationship)),
+		fmt.Sprintf("%d shares at %.2f, value %d, total %d", t.Shares, t.Cost, t.Value, t.SharesTotal),
+		fmt.Sprintf("<a href='%s'>SEC Form 4</a>", t.URL),
+	}
+
+	return c.send(ctx, nil, strings.Join(text, "\n"))
 }
@@ -0,0 +1,364 @@
+package telegram
+
+import (
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/publish"
+	"github.com/RyabovNick/finviz_parser/internal/store/memory"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// botServer fakes the Bot API and records sent messages
+// and captions of PNG photos.
+type botServer struct {
+	mu       sync.Mutex
+	messages []string
+	photos   []string
+}
+
+func (b *botServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/getMe"):
+		rw.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"test","username":"test_bot"}}`))
+	case strings.HasSuffix(req.URL.Path, "/sendMessage"):
+		if req.FormValue("parse_mode") != ParseModeHTML {
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: parse mode"}`))
+			return
+		}
+
+		b.mu.Lock()
+		b.messages = append(b.messages, req.FormValue("text"))
+		b.mu.Unlock()
+
+		rw.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+	case strings.HasSuffix(req.URL.Path, "/sendPhoto"):
+		f, _, err := req.FormFile("photo")
+		if err == nil {
+			defer f.Close()
+			_, err = png.DecodeConfig(f)
+		}
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: photo"}`))
+			return
+		}
+
+		b.mu.Lock()
+		b.photos = append(b.photos, req.FormValue("caption"))
+		b.mu.Unlock()
+
+		rw.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+	}
+}
+
+func testConnection(t *testing.T, tmpl string, companies []company.Company, tr ...insider.Transaction) (*Connection, *botServer) {
+	t.Helper()
+
+	b := &botServer{}
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint("token", srv.URL+"/bot%s/%s")
+	require.NoError(t, err)
+
+	store := memory.New()
+	_, err = store.InsertTransactions(context.Background(), tr)
+	require.NoError(t, err)
+
+	for _, c := range companies {
+		require.NoError(t, store.SaveCompany(context.Background(), c))
+	}
+
+	c, err := newConnection(bot, Config{
+		Chats:          []Chat{{ID: 1, Template: tmpl}},
+		ExcludePlanned: true,
+	}, store)
+	require.NoError(t, err)
+
+	return c, b
+}
+
+func transaction(day time.Time, ticker, owner string, typ insider.TransactionType, value int) insider.Transaction {
+	return insider.Transaction{
+		Ticker:          ticker,
+		Owner:           owner,
+		Relationship:    "CEO",
+		TransactionDate: day.AddDate(0, 0, -2),
+		Transaction:     typ,
+		Cost:            10,
+		Shares:          value / 10,
+		Value:           value,
+		SEC: insider.SEC{
+			NotificationDate: day.Add(21 * time.Hour),
+		},
+	}
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestConnection_Publish(t *testing.T) {
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	unusual := transaction(day, "CCC", "Smith & Sons", insider.Buy, 200)
+	unusual.Flags = []string{"first buy"}
+
+	tr := []insider.Transaction{
+		transaction(day, "AAA", "Smith John", insider.Buy, 1000),
+		transaction(day, "AAA", "Doe Jane", insider.Sale, 400),
+		transaction(day, "BBB", "Doe Jane", insider.Sale, 700),
+		unusual,
+	}
+
+	tests := []struct {
+		name      string
+		template  string
+		companies []company.Company
+		tr        []insider.Transaction
+		want      []string
+		// wantPhotos are captions of charts
+		wantPhotos []string
+	}{
+		{
+			name:     "english",
+			template: "en",
+			companies: []company.Company{
+				{Ticker: "AAA", MarketCap: ptr(1e6), SharesFloat: ptr(6000)},
+				{Ticker: "BBB", MarketCap: ptr(7e6)},
+			},
+			tr: []insider.Transaction{
+				transaction(day, "AAA", "Smith John", insider.Buy, 1000),
+				transaction(day, "AAA", "Doe Jane", insider.Sale, 400),
+				transaction(day, "BBB", "Doe Jane", insider.Sale, 700),
+				unusual,
+			},
+			want: []string{
+				"<b>Transaction count and total_value (in $):</b>\nBuy: 2 (1200)\nSale: 2 (1100)",
+				"<b>Top 3 buy:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a>: 600\n" +
+					"<a href='https://finviz.com/quote.ashx?t=CCC'>CCC</a>: 200\n" +
+					"<a href='https://finviz.com/quote.ashx?t=BBB'>BBB</a>: -700\n" +
+					"<a href='https://finviz.com/screener.ashx?v=340&t=AAA,CCC&o=ticker'>Open ALL in Finviz Screener</a>",
+				"<b>Top 3 sell:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=BBB'>BBB</a>: -700\n" +
+					"<a href='https://finviz.com/quote.ashx?t=CCC'>CCC</a>: 200\n" +
+					"<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a>: 600\n" +
+					"<a href='https://finviz.com/screener.ashx?v=340&t=AAA,BBB&o=ticker'>Open ALL in Finviz Screener</a>",
+				"<b>Top 1 buy relative to market cap:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a>: +6.0 bps, 1.00% of float (600)",
+				"<b>Top 1 sell relative to market cap:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=BBB'>BBB</a>: -1.0 bps, n/a of float (-700)",
+				"<b>Unusual transactions:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=CCC'>CCC</a> Buy Smith &amp; Sons (CEO) 200: first buy",
+			},
+			wantPhotos: []string{
+				"<b>Net buy/sell of top tickers, $</b>",
+				"<b>Buy vs sale value for 30 days, $</b>",
+			},
+		},
+		{
+			name:     "russian",
+			template: "ru",
+			companies: []company.Company{
+				{Ticker: "AAA", Sector: "Technology"},
+				{Ticker: "BBB", Sector: "Health & Care"},
+			},
+			tr: tr,
+			want: []string{
+				"<b>Количество сделок и сумма (в $):</b>\nПокупки: 2 (1200)\nПродажи: 2 (1100)",
+				"<b>Секторы, покупки / продажи (в $):</b>\n" +
+					"Technology: 1 (1000) / 1 (400)\n" +
+					"Health &amp; Care: 0 (0) / 1 (700)",
+				"<b>Топ 3 покупок:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a>: 600\n" +
+					"<a href='https://finviz.com/quote.ashx?t=CCC'>CCC</a>: 200\n" +
+					"<a href='https://finviz.com/quote.ashx?t=BBB'>BBB</a>: -700\n" +
+					"<a href='https://finviz.com/screener.ashx?v=340&t=AAA,CCC&o=ticker'>Открыть все в скринере Finviz</a>",
+				"<b>Топ 3 продаж:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=BBB'>BBB</a>: -700\n" +
+					"<a href='https://finviz.com/quote.ashx?t=CCC'>CCC</a>: 200\n" +
+					"<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a>: 600\n" +
+					"<a href='https://finviz.com/screener.ashx?v=340&t=AAA,BBB&o=ticker'>Открыть все в скринере Finviz</a>",
+				"<b>Необычные сделки:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=CCC'>CCC</a> покупка Smith &amp; Sons (CEO) 200: first buy",
+			},
+			wantPhotos: []string{
+				"<b>Чистые покупки/продажи топ тикеров, $</b>",
+				"<b>Покупки и продажи за 30 дней, $</b>",
+			},
+		},
+		{
+			name:     "file",
+			template: "testdata/digest.tmpl",
+			tr:       tr,
+			want: []string{
+				"2024-06-24: 2 buys, 2 sales",
+				"<b>Top 3 buy:</b>\n" +
+					"<a href='https://finviz.com/quote.ashx?t=AAA'>AAA</a>: 600\n" +
+					"<a href='https://finviz.com/quote.ashx?t=CCC'>CCC</a>: 200\n" +
+					"<a href='https://finviz.com/quote.ashx?t=BBB'>BBB</a>: -700\n" +
+					"<a href='https://finviz.com/screener.ashx?v=340&t=AAA,CCC&o=ticker'>Open ALL in Finviz Screener</a>",
+			},
+			wantPhotos: []string{"<b>Net buy/sell of top tickers, $</b>"},
+		},
+		{
+			name:     "no transactions",
+			template: "en",
+			want:     []string{"<b>No insider transactions on 2024-06-24.</b>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, b := testConnection(t, tt.template, tt.companies, tt.tr...)
+
+			require.NoError(t, c.Publish(context.Background(), day, nil))
+			assert.Equal(t, tt.want, b.messages)
+			assert.Equal(t, tt.wantPhotos, b.photos)
+		})
+	}
+}
+
+func TestConnection_PublishPlanned(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	c, b := testConnection(t, "en", nil, transaction(day, "AAA", "Smith John", insider.Buy, 1000))
+	c.filter.ExcludePlanned = false
+
+	store := c.store.(*memory.Store)
+	sales, err := store.InsertTransactions(ctx, insider.Transactions{transaction(day, "BBB", "Doe Jane", insider.Sale, 700)})
+	require.NoError(t, err)
+	require.NoError(t, store.SaveFiling(ctx, sales[0].ID, insider.Filing{AccessionNumber: "1", Plan10b51: true}))
+
+	require.NoError(t, c.Publish(ctx, day, nil))
+	assert.Contains(t, b.messages, "<b>Top 1 planned (10b5-1) sell:</b>\n"+
+		"<a href='https://finviz.com/quote.ashx?t=BBB'>BBB</a>: 700",
+		"planned sales are shown without EXCLUDE_PLANNED")
+}
+
+func TestConnection_PublishEmptyDay(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 6, 22, 0, 0, 0, 0, time.UTC)
+
+	c, b := testConnection(t, "en", nil)
+	store := c.store.(*memory.Store)
+	_, err := store.InsertTransactions(ctx, nil, publish.NewDigest(day, "telegram"))
+	require.NoError(t, err)
+
+	p := publish.New(publish.Config{Attempts: 1}, store)
+	p.Register("telegram", c.Publish)
+	results, err := p.Drain(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{"<b>No insider transactions on 2024-06-22.</b>"}, b.messages)
+
+	pending, err := store.PendingDigests(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "the digest of the weekend is delivered")
+}
+
+func TestConnection_PublishPerformance(t *testing.T) {
+	day := time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC)
+
+	c, b := testConnection(t, "en", nil, transaction(day, "AAA", "Smith John", insider.Buy, 1000))
+
+	require.NoError(t, c.PublishPerformance(context.Background(), day, nil))
+	require.Len(t, b.messages, 1)
+	assert.Equal(t, "<b>Did insiders get it right? Returns after the trade for 90 days:</b>\n"+
+		"Buy (1): 1d n/a, 5d n/a, 20d n/a, 60d n/a, hit rate 20d n/a\n"+
+		"<b>Top 10 insiders by 20d return after buy:</b>", b.messages[0])
+
+	c, b = testConnection(t, "en", nil)
+	require.NoError(t, c.PublishPerformance(context.Background(), day, nil), "the day without returns")
+	assert.Empty(t, b.messages)
+}
+
+func TestLoadTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: ""},
+		{name: "en"},
+		{name: "ru"},
+		{name: "testdata/digest.tmpl"},
+		{name: "de", wantErr: true},
+		{name: "testdata/missing.tmpl", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := LoadTemplate(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			for _, name := range digestTemplates {
+				assert.NotNil(t, tmpl.Lookup(name), name)
+			}
+		})
+	}
+}
+
+func TestParseChats(t *testing.T) {
+	tests := []struct {
+		name    string
+		chats   string
+		want    []Chat
+		wantErr bool
+	}{
+		{
+			name:  "single",
+			chats: "-100123",
+			want:  []Chat{{ID: -100123, Template: "en"}},
+		},
+		{
+			name:  "templates",
+			chats: "-100123:ru, 456:/etc/finviz/digest.tmpl,789",
+			want: []Chat{
+				{ID: -100123, Template: "ru"},
+				{ID: 456, Template: "/etc/finviz/digest.tmpl"},
+				{ID: 789, Template: "en"},
+			},
+		},
+		{
+			name:    "invalid",
+			chats:   "chat:ru",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChats(tt.chats, "")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package telegram
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/RyabovNick/finviz_parser/internal/company"
+	"github.com/RyabovNick/finviz_parser/internal/insider"
+	"github.com/RyabovNick/finviz_parser/internal/score"
+)
+
+// DefaultTemplate is the template of chats without the selected one.
+const DefaultTemplate = "en"
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+// digestTemplates are the messages of the digest in the order of sending,
+// empty messages aren't sent.
+var digestTemplates = []string{
+	"transaction_type_count",
+	"sectors",
+	"top_buy",
+	"top_sell",
+	"significant_buy",
+	"significant_sell",
+	"unusual",
+	"track_record",
+	"top_planned_sell",
+}
+
+// Report is the data of digest templates.
+type Report struct {
+	Day time.Time
+	// ExcludePlanned is set when planned transactions are excluded
+	// from the reports, planned sales are listed in TopPlannedSell anyway.
+	ExcludePlanned bool
+
+	Counts         []insider.TransactionTypeCount
+	Sectors        []company.Sector
+	TopBuy         []insider.TotalTransaction
+	TopSell        []insider.TotalTransaction
+	TopPlannedSell []insider.TotalTransaction
+	// SignificantBuy and SignificantSell are ranked by the value
+	// relative to the market cap, only tickers with the known market cap.
+	SignificantBuy  []company.Significance
+	SignificantSell []company.Significance
+	BuyTickers      insider.Tickers
+	SaleTickers     insider.Tickers
+	Unusual         insider.Transactions
+	TrackRecord     []TrackRecord
+	// Trend is daily totals of the last 30 days of the trend chart.
+	Trend []insider.DailyTotal
+}
+
+// TrackRecord is the day's buy with the insider's scores of all horizons.
+type TrackRecord struct {
+	TransactionID string
+	Ticker        string
+	Owner         string
+	Relationship  string
+	Value         int
+	Scores        []score.Score
+}
+
+var funcs = template.FuncMap{
+	"quote":    insider.QuoteLink,
+	"escape":   html.EscapeString,
+	"join":     strings.Join,
+	"money":    func(v float64) string { return fmt.Sprintf("%.0f", v) },
+	"percent":  func(v any) string { return percent(number(v)) },
+	"share":    func(v any) string { return share(number(v)) },
+	"bps":      func(v float64) string { return fmt.Sprintf("%+.1f bps", v) },
+	"fraction": func(v any) string { return fraction(number(v)) },
+	"date":     func(t time.Time) string { return t.Format(time.DateOnly) },
+}
+
+// number converts float64 and *float64 for formatting, nil is n/a.
+func number(v any) *float64 {
+	switch v := v.(type) {
+	case float64:
+		return &v
+	case *float64:
+		return v
+	default:
+		return nil
+	}
+}
+
+// LoadTemplate returns the built-in template by the name (en or ru)
+// or parses the template file. The file may redefine only some messages,
+// the rest are English.
+func LoadTemplate(name string) (*template.Template, error) {
+	if name == "" {
+		name = DefaultTemplate
+	}
+
+	if !strings.ContainsRune(name, filepath.Separator) && filepath.Ext(name) == "" {
+		t, err := builtin(name)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %w", name, err)
+		}
+
+		return t, nil
+	}
+
+	t, err := builtin(DefaultTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read template file: %w", err)
+	}
+
+	if err := parse(t, b); err != nil {
+		return nil, fmt.Errorf("parse template file %s: %w", name, err)
+	}
+
+	return t, nil
+}
+
+func builtin(name string) (*template.Template, error) {
+	b, err := fs.ReadFile(templatesFS, "templates/"+name+".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("read built-in template: %w", err)
+	}
+
+	t := template.New(name).Funcs(funcs)
+	if err := parse(t, b); err != nil {
+		return nil, fmt.Errorf("parse built-in template: %w", err)
+	}
+
+	return t, nil
+}
+
+// parse adds definitions to t, CRLF line endings
+// are not sent in messages.
+func parse(t *template.Template, b []byte) error {
+	_, err := t.Parse(strings.ReplaceAll(string(b), "\r\n", "\n"))
+	return err
+}
+
+// render returns the non-empty messages of the digest.
+func render(t *template.Template, r Report) ([]string, error) {
+	var messages []string
+	for _, name := range digestTemplates {
+		text, err := execute(t, name, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if text != "" {
+			messages = append(messages, text)
+		}
+	}
+
+	return messages, nil
+}
+
+// execute renders the message, it's empty if the template isn't defined.
+func execute(t *template.Template, name string, r Report) (string, error) {
+	if t.Lookup(name) == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, r); err != nil {
+		return "", fmt.Errorf("execute %s: %w", name, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+type Config struct {
+	// Exporter is none, otlp or stdout.
+	Exporter string
+	// Endpoint is the OTLP/HTTP URL of the collector,
+	// the exporter's default is used if it's empty.
+	Endpoint string
+	// Service is the service name of spans.
+	Service string
+}
+
+// Enabled reports whether spans are exported.
+func (c Config) Enabled() bool {
+	return c.Exporter != ExporterNone
+}
+
+func ParseTracingConfig() Config {
+	cfg := Config{
+		Exporter: ExporterNone,
+		Endpoint: os.Getenv("TRACE_ENDPOINT"),
+		Service:  "finviz_parser",
+	}
+
+	if v := os.Getenv("TRACE_EXPORTER"); v != "" {
+		switch strings.ToLower(v) {
+		case ExporterNone, ExporterOTLP, ExporterStdout:
+			cfg.Exporter = strings.ToLower(v)
+		default:
+			log.Fatal("env: TRACE_EXPORTER must be none, otlp or stdout")
+		}
+	}
+
+	if v := os.Getenv("TRACE_SERVICE"); v != "" {
+		cfg.Service = v
+	}
+
+	return cfg
+}
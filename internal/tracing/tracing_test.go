@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record sets the provider recording spans for the test.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	return rec
+}
+
+func TestSetupStdout(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, Service: "test"}, &buf)
+	require.NoError(t, err)
+
+	ctx, run := Start(context.Background(), "run", attribute.Int64("run.id", 42))
+	_, fetch := Start(ctx, "finviz.fetch")
+	End(fetch, errors.New("visit: timeout"))
+	End(run, nil)
+
+	require.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, buf.String(), `"Name":"finviz.fetch"`)
+	assert.Contains(t, buf.String(), `"Name":"run"`)
+	assert.Contains(t, buf.String(), `"Description":"visit: timeout"`)
+	assert.Contains(t, buf.String(), `"Value":"test"`)
+}
+
+func TestSetupNone(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone}, nil)
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), Config{Exporter: "zipkin"}, nil)
+	assert.Error(t, err)
+}
+
+func TestQueryTracer(t *testing.T) {
+	rec := record(t)
+
+	ctx, parent := Start(context.Background(), "ingest.save")
+
+	var tracer QueryTracer
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "insert into transactions (ticker) values ($1)"})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("INSERT 0 3")})
+
+	qctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("conn closed")})
+	parent.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+
+	insert := spans[0]
+	assert.Equal(t, "db INSERT", insert.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), insert.Parent().SpanID())
+	assert.Contains(t, insert.Attributes(), attribute.String("db.system", "postgresql"))
+	assert.Contains(t, insert.Attributes(), attribute.String("db.query.text", "insert into transactions (ticker) values ($1)"))
+	assert.Contains(t, insert.Attributes(), attribute.Int64("db.rows_affected", 3))
+	assert.Equal(t, codes.Unset, insert.Status().Code)
+
+	sel := spans[1]
+	assert.Equal(t, "db SELECT", sel.Name())
+	assert.Equal(t, codes.Error, sel.Status().Code)
+	assert.Equal(t, "conn closed", sel.Status().Description)
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "select * from runs", want: "SELECT"},
+		{sql: "\n\tWITH latest AS (select 1) select * from latest", want: "WITH"},
+		{sql: "LISTEN ingest;", want: "LISTEN"},
+		{sql: "", want: "query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, command(tt.sql))
+		})
+	}
+}
@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE outbox (
+  id BIGSERIAL PRIMARY KEY,
+  event_type VARCHAR(100) NOT NULL,
+  event_key VARCHAR(200) NOT NULL,
+  notification_date TIMESTAMPTZ NOT NULL,
+  payload JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  published_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON outbox (notification_date, id) WHERE published_at IS NULL;
+
+COMMIT;
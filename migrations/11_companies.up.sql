@@ -0,0 +1,16 @@
+BEGIN;
+
+-- companies is the cache of quote pages of traded tickers
+CREATE TABLE companies (
+  ticker VARCHAR(20) PRIMARY KEY,
+  name VARCHAR(2000) NOT NULL,
+  sector VARCHAR(200) NOT NULL,
+  industry VARCHAR(200) NOT NULL,
+  country VARCHAR(200) NOT NULL,
+  market_cap DOUBLE PRECISION,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX ON companies (sector);
+
+COMMIT;
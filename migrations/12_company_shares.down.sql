@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE companies
+  DROP COLUMN shares_outstanding,
+  DROP COLUMN shares_float;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE companies
+  ADD COLUMN shares_outstanding DOUBLE PRECISION,
+  ADD COLUMN shares_float DOUBLE PRECISION;
+
+COMMIT;
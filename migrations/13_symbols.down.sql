@@ -0,0 +1,41 @@
+BEGIN;
+
+DROP TABLE symbols;
+
+DROP VIEW transaction_returns;
+
+ALTER TABLE transactions ALTER COLUMN ticker TYPE VARCHAR(20);
+ALTER TABLE daily_closes ALTER COLUMN ticker TYPE VARCHAR(20);
+ALTER TABLE companies ALTER COLUMN ticker TYPE VARCHAR(20);
+
+-- forward returns from the insider's price to the close
+-- after 1, 5, 20 and 60 trading days
+CREATE VIEW transaction_returns AS
+SELECT
+  t.id,
+  t.ticker,
+  t.owner,
+  t.relationship,
+  t.transaction_type,
+  t.transaction_date,
+  t.notification_date,
+  t.cost,
+  t.planned,
+  fwd.closes[1] / NULLIF(t.cost, 0) - 1 AS return_1d,
+  fwd.closes[5] / NULLIF(t.cost, 0) - 1 AS return_5d,
+  fwd.closes[20] / NULLIF(t.cost, 0) - 1 AS return_20d,
+  fwd.closes[60] / NULLIF(t.cost, 0) - 1 AS return_60d
+FROM transactions t
+LEFT JOIN LATERAL (
+  SELECT array_agg(c.close ORDER BY c.date) AS closes
+  FROM (
+    SELECT close, date
+    FROM daily_closes
+    WHERE ticker = t.ticker
+      AND date > t.transaction_date::date
+    ORDER BY date
+    LIMIT 60
+  ) c
+) fwd ON true;
+
+COMMIT;
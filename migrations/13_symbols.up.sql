@@ -0,0 +1,67 @@
+BEGIN;
+
+-- the view depends on the ticker column
+DROP VIEW transaction_returns;
+
+ALTER TABLE transactions ALTER COLUMN ticker TYPE VARCHAR(50);
+ALTER TABLE daily_closes ALTER COLUMN ticker TYPE VARCHAR(50);
+ALTER TABLE companies ALTER COLUMN ticker TYPE VARCHAR(50);
+
+-- canonical tickers have the share class after the dot: BRK.B
+UPDATE transactions SET ticker = translate(upper(ticker), '-/', '..') WHERE ticker ~ '[-/a-z]';
+
+DELETE FROM daily_closes d
+WHERE d.ticker ~ '[-/a-z]'
+  AND EXISTS (
+    SELECT 1 FROM daily_closes c
+    WHERE c.ticker = translate(upper(d.ticker), '-/', '..') AND c.date = d.date
+  );
+UPDATE daily_closes SET ticker = translate(upper(ticker), '-/', '..') WHERE ticker ~ '[-/a-z]';
+
+DELETE FROM companies d
+WHERE d.ticker ~ '[-/a-z]'
+  AND EXISTS (SELECT 1 FROM companies c WHERE c.ticker = translate(upper(d.ticker), '-/', '..'));
+UPDATE companies SET ticker = translate(upper(ticker), '-/', '..') WHERE ticker ~ '[-/a-z]';
+
+-- forward returns from the insider's price to the close
+-- after 1, 5, 20 and 60 trading days
+CREATE VIEW transaction_returns AS
+SELECT
+  t.id,
+  t.ticker,
+  t.owner,
+  t.relationship,
+  t.transaction_type,
+  t.transaction_date,
+  t.notification_date,
+  t.cost,
+  t.planned,
+  fwd.closes[1] / NULLIF(t.cost, 0) - 1 AS return_1d,
+  fwd.closes[5] / NULLIF(t.cost, 0) - 1 AS return_5d,
+  fwd.closes[20] / NULLIF(t.cost, 0) - 1 AS return_20d,
+  fwd.closes[60] / NULLIF(t.cost, 0) - 1 AS return_60d
+FROM transactions t
+LEFT JOIN LATERAL (
+  SELECT array_agg(c.close ORDER BY c.date) AS closes
+  FROM (
+    SELECT close, date
+    FROM daily_closes
+    WHERE ticker = t.ticker
+      AND date > t.transaction_date::date
+    ORDER BY date
+    LIMIT 60
+  ) c
+) fwd ON true;
+
+-- symbols maps symbols of data sources to canonical tickers
+CREATE TABLE symbols (
+  source VARCHAR(20) NOT NULL,
+  symbol VARCHAR(50) NOT NULL,
+  ticker VARCHAR(50) NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (source, symbol)
+);
+
+CREATE INDEX ON symbols (ticker);
+
+COMMIT;
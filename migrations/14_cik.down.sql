@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE transactions
+  DROP COLUMN issuer_cik,
+  DROP COLUMN owner_cik;
+
+COMMIT;
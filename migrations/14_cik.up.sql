@@ -0,0 +1,12 @@
+BEGIN;
+
+-- CIKs of the issuer and the reporting owner from the Form 4,
+-- without leading zeros
+ALTER TABLE transactions
+  ADD COLUMN issuer_cik VARCHAR(10),
+  ADD COLUMN owner_cik VARCHAR(10);
+
+CREATE INDEX ON transactions (issuer_cik);
+CREATE INDEX ON transactions (owner_cik);
+
+COMMIT;
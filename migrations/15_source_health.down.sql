@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE runs DROP COLUMN sources;
+
+COMMIT;
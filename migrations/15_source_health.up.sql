@@ -0,0 +1,6 @@
+BEGIN;
+
+-- the health of every source of transactions fetched by the run
+ALTER TABLE runs ADD COLUMN sources JSONB NOT NULL DEFAULT '{}';
+
+COMMIT;
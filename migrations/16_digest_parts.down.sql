@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE digest_parts;
+
+ALTER TABLE digests DROP COLUMN locked_until;
+
+COMMIT;
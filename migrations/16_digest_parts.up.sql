@@ -0,0 +1,15 @@
+BEGIN;
+
+-- the publisher leases the digest instead of holding the row lock
+-- while messages are sent
+ALTER TABLE digests ADD COLUMN locked_until TIMESTAMPTZ;
+
+-- sent messages of the digest, retries skip them
+CREATE TABLE digest_parts (
+  key VARCHAR(200) NOT NULL REFERENCES digests (key) ON DELETE CASCADE,
+  part VARCHAR(200) NOT NULL,
+  sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (key, part)
+);
+
+COMMIT;
@@ -0,0 +1,38 @@
+BEGIN;
+
+CREATE INDEX daily_closes_date_idx ON daily_closes (date);
+
+DROP VIEW transaction_returns;
+
+-- forward returns from the insider's price to the close
+-- after 1, 5, 20 and 60 trading days. Dates with any stored close
+-- are the trading calendar (the benchmark is stored every session),
+-- the return is NULL if the close of the day isn't stored.
+CREATE VIEW transaction_returns AS
+SELECT
+  t.id,
+  t.ticker,
+  t.owner,
+  t.relationship,
+  t.transaction_type,
+  t.transaction_date,
+  t.notification_date,
+  t.cost,
+  t.planned,
+  (SELECT close FROM daily_closes WHERE ticker = t.ticker AND date = cal.days[1]) / NULLIF(t.cost, 0) - 1 AS return_1d,
+  (SELECT close FROM daily_closes WHERE ticker = t.ticker AND date = cal.days[5]) / NULLIF(t.cost, 0) - 1 AS return_5d,
+  (SELECT close FROM daily_closes WHERE ticker = t.ticker AND date = cal.days[20]) / NULLIF(t.cost, 0) - 1 AS return_20d,
+  (SELECT close FROM daily_closes WHERE ticker = t.ticker AND date = cal.days[60]) / NULLIF(t.cost, 0) - 1 AS return_60d
+FROM transactions t
+LEFT JOIN LATERAL (
+  SELECT array_agg(d.date ORDER BY d.date) AS days
+  FROM (
+    SELECT DISTINCT date
+    FROM daily_closes
+    WHERE date > t.transaction_date::date
+    ORDER BY date
+    LIMIT 60
+  ) d
+) cal ON true;
+
+COMMIT;
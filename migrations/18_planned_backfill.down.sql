@@ -0,0 +1,5 @@
+BEGIN;
+
+-- the corrected backfill isn't reverted
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+-- the backfill of 3_planned marked "not pursuant to a Rule 10b5-1 plan"
+-- as planned and missed the en dash, it's repeated like Filing.Planned
+UPDATE transactions t
+SET planned = COALESCE(t.plan_10b5_1, false) OR EXISTS (
+    SELECT 1
+    FROM unnest(t.footnotes) AS n(footnote)
+    WHERE n.footnote ~* '10b-?5\s*[-–]\s*1'
+      AND n.footnote !~* 'not\s+(\w+\s+){0,2}pursuant'
+  )
+WHERE t.accession_number IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- pending firings are dropped, so rules fire for their transactions again
+DELETE FROM alert_firings WHERE delivered_at IS NULL;
+
+ALTER TABLE alert_firings DROP COLUMN delivered_at;
+
+COMMIT;
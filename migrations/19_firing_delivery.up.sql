@@ -0,0 +1,11 @@
+BEGIN;
+
+-- the firing stays pending until the notification is sent,
+-- runs retry pending firings
+ALTER TABLE alert_firings ADD COLUMN delivered_at TIMESTAMPTZ;
+
+UPDATE alert_firings SET delivered_at = fired_at;
+
+CREATE INDEX alert_firings_pending_idx ON alert_firings (rule_name) WHERE delivered_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE transactions;
+
+DROP TABLE last_parse;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP INDEX transactions_accession_number_idx;
+
+ALTER TABLE transactions
+  DROP COLUMN accession_number,
+  DROP COLUMN transaction_code,
+  DROP COLUMN plan_10b5_1,
+  DROP COLUMN direct_ownership,
+  DROP COLUMN footnotes;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE transactions
+  ADD COLUMN accession_number VARCHAR(20),
+  ADD COLUMN transaction_code VARCHAR(2),
+  ADD COLUMN plan_10b5_1 BOOLEAN,
+  ADD COLUMN direct_ownership BOOLEAN,
+  ADD COLUMN footnotes TEXT[];
+
+CREATE INDEX ON transactions (accession_number);
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE transactions ADD COLUMN planned BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE transactions
+SET planned = true
+WHERE plan_10b5_1
+  OR array_to_string(footnotes, ' ') ~* '10b-?5\s*-\s*1';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP VIEW transaction_returns;
+
+DROP TABLE daily_closes;
+
+COMMIT;
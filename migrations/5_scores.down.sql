@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE insider_scores;
+
+COMMIT;
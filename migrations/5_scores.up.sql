@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE insider_scores (
+  kind VARCHAR(20) NOT NULL,
+  name VARCHAR(2000) NOT NULL,
+  horizon INT NOT NULL,
+  transactions INT NOT NULL,
+  hit_rate DOUBLE PRECISION NOT NULL,
+  avg_excess_return DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (kind, name, horizon)
+);
+
+COMMIT;
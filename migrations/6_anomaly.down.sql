@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX transactions_owner_transaction_type_idx;
+
+ALTER TABLE transactions
+  DROP COLUMN holdings_change,
+  DROP COLUMN value_to_median,
+  DROP COLUMN first_buy,
+  DROP COLUMN anomaly_flags;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE transactions
+  ADD COLUMN holdings_change DOUBLE PRECISION,
+  ADD COLUMN value_to_median DOUBLE PRECISION,
+  ADD COLUMN first_buy BOOLEAN NOT NULL DEFAULT false,
+  ADD COLUMN anomaly_flags TEXT[];
+
+CREATE INDEX ON transactions (owner, transaction_type);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE alert_firings;
+
+DROP TABLE alert_rules;
+
+COMMIT;
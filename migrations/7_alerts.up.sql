@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE alert_rules (
+  name VARCHAR(200) PRIMARY KEY,
+  expr TEXT NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE alert_firings (
+  rule_name VARCHAR(200) NOT NULL REFERENCES alert_rules (name) ON DELETE CASCADE,
+  transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+  fired_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (rule_name, transaction_id)
+);
+
+COMMIT;
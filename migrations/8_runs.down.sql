@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE last_parse (
+  id INT PRIMARY KEY,
+  updated_at DATE NOT NULL
+);
+
+INSERT INTO last_parse (id, updated_at)
+SELECT 1, COALESCE(max(day), current_date - 100)
+FROM runs
+WHERE status = 'succeeded';
+
+DROP TABLE runs;
+
+COMMIT;
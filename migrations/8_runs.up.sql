@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE runs (
+  id BIGSERIAL PRIMARY KEY,
+  started_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ,
+  day DATE NOT NULL,
+  source_urls TEXT[] NOT NULL DEFAULT '{}',
+  rows_scraped INT NOT NULL DEFAULT 0,
+  rows_kept INT NOT NULL DEFAULT 0,
+  rows_inserted INT NOT NULL DEFAULT 0,
+  rows_duplicate INT NOT NULL DEFAULT 0,
+  status VARCHAR(20) NOT NULL,
+  error TEXT NOT NULL DEFAULT '',
+  publish JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX ON runs (day, status);
+
+DROP TABLE last_parse;
+
+COMMIT;
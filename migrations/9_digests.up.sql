@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE digests (
+  key VARCHAR(200) PRIMARY KEY,
+  day DATE NOT NULL,
+  sink VARCHAR(100) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON digests (day) WHERE delivered_at IS NULL;
+
+COMMIT;
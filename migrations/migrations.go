@@ -0,0 +1,131 @@
+// Package migrations embeds SQL migrations in the golang-migrate format:
+// {version}_{title}.up.sql and {version}_{title}.down.sql
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// FS contains migrations of the PostgreSQL backend.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite returns migrations of the SQLite backend.
+func SQLite() fs.FS {
+	sub, err := fs.Sub(sqliteFS, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
+
+// ErrDirty is returned when the previous migration failed in the middle,
+// the database has to be fixed manually.
+var ErrDirty = errors.New("database is dirty")
+
+// {version}_{title}.{up|down}.sql
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status shows whether the migration is applied.
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Migrator applies migrations of the storage backend.
+type Migrator interface {
+	// Up applies all pending migrations.
+	Up(ctx context.Context) error
+	// Down rolls back the number of applied migrations.
+	Down(ctx context.Context, steps int) error
+	// Force sets the version without running migrations.
+	Force(ctx context.Context, version uint) error
+	// Version returns the current version and whether the database is dirty.
+	Version(ctx context.Context) (uint, bool, error)
+	Status(ctx context.Context) ([]Status, error)
+}
+
+// Load reads migrations from the root of fsys sorted by version,
+// other files are skipped.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, f := range files {
+		m := fileRe.FindStringSubmatch(f.Name())
+		if m == nil {
+			continue
+		}
+
+		v, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("version %s: %w", f.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", f.Name(), err)
+		}
+
+		mg, ok := byVersion[uint(v)]
+		if !ok {
+			mg = &Migration{Version: uint(v), Name: m[2]}
+			byVersion[uint(v)] = mg
+		}
+
+		if m[3] == "up" {
+			mg.Up = string(data)
+		} else {
+			mg.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Statuses marks migrations up to the version as applied.
+func Statuses(migrations []Migration, version uint) []Status {
+	st := make([]Status, 0, len(migrations))
+	for _, mg := range migrations {
+		st = append(st, Status{
+			Migration: mg,
+			Applied:   mg.Version <= version,
+		})
+	}
+
+	return st
+}
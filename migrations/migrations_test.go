@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("embedded", func(t *testing.T) {
+		mm, err := Load(FS)
+		require.NoError(t, err)
+		require.NotEmpty(t, mm)
+
+		for i, m := range mm {
+			assert.Equal(t, uint(i+1), m.Version, "versions have no gaps")
+			assert.NotEmpty(t, m.Up, m.Name)
+			assert.NotEmpty(t, m.Down, m.Name)
+		}
+	})
+
+	t.Run("embedded sqlite", func(t *testing.T) {
+		mm, err := Load(SQLite())
+		require.NoError(t, err)
+		require.NotEmpty(t, mm)
+
+		for i, m := range mm {
+			assert.Equal(t, uint(i+1), m.Version, "versions have no gaps")
+			assert.NotEmpty(t, m.Up, m.Name)
+			assert.NotEmpty(t, m.Down, m.Name)
+		}
+	})
+
+	t.Run("sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"10_ten.up.sql":  {Data: []byte("SELECT 10;")},
+			"2_two.up.sql":   {Data: []byte("SELECT 2;")},
+			"2_two.down.sql": {Data: []byte("SELECT -2;")},
+			"README.md":      {Data: []byte("skipped")},
+		}
+
+		mm, err := Load(fsys)
+		require.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 2, Name: "two", Up: "SELECT 2;", Down: "SELECT -2;"},
+			{Version: 10, Name: "ten", Up: "SELECT 10;"},
+		}, mm)
+	})
+
+	t.Run("no up file", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"1_init.down.sql": {Data: []byte("DROP TABLE t;")},
+		})
+		assert.Error(t, err)
+	})
+}
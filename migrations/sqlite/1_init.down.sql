@@ -0,0 +1,9 @@
+DROP TABLE outbox;
+DROP TABLE digests;
+DROP TABLE runs;
+DROP TABLE alert_firings;
+DROP TABLE alert_rules;
+DROP TABLE insider_scores;
+DROP VIEW transaction_returns;
+DROP TABLE daily_closes;
+DROP TABLE transactions;
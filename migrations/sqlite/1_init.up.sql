@@ -0,0 +1,139 @@
+-- times are stored as 'YYYY-MM-DD HH:MM:SS.SSS' in UTC, dates as 'YYYY-MM-DD',
+-- arrays and objects as JSON text
+
+CREATE TABLE transactions (
+  id TEXT PRIMARY KEY,
+  ticker TEXT NOT NULL,
+  owner TEXT NOT NULL,
+  relationship TEXT NOT NULL,
+  transaction_date TEXT NOT NULL,
+  transaction_type TEXT NOT NULL,
+  cost REAL NOT NULL,
+  shares INTEGER NOT NULL,
+  value INTEGER NOT NULL,
+  shares_total INTEGER NOT NULL,
+  notification_date TEXT NOT NULL,
+  url TEXT NOT NULL,
+  accession_number TEXT,
+  transaction_code TEXT,
+  plan_10b5_1 INTEGER,
+  direct_ownership INTEGER,
+  footnotes TEXT,
+  planned INTEGER NOT NULL DEFAULT 0,
+  holdings_change REAL,
+  value_to_median REAL,
+  first_buy INTEGER NOT NULL DEFAULT 0,
+  anomaly_flags TEXT
+);
+
+CREATE INDEX transactions_notification_date_idx ON transactions (notification_date);
+CREATE INDEX transactions_accession_number_idx ON transactions (accession_number);
+CREATE INDEX transactions_owner_transaction_type_idx ON transactions (owner, transaction_type);
+
+CREATE TABLE daily_closes (
+  ticker TEXT NOT NULL,
+  date TEXT NOT NULL,
+  close REAL NOT NULL,
+  PRIMARY KEY (ticker, date)
+);
+
+-- forward returns from the insider's price to the close
+-- after 1, 5, 20 and 60 trading days
+CREATE VIEW transaction_returns AS
+SELECT
+  t.id,
+  t.ticker,
+  t.owner,
+  t.relationship,
+  t.transaction_type,
+  t.transaction_date,
+  t.notification_date,
+  t.cost,
+  t.planned,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 0
+  ) / NULLIF(t.cost, 0) - 1 AS return_1d,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 4
+  ) / NULLIF(t.cost, 0) - 1 AS return_5d,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 19
+  ) / NULLIF(t.cost, 0) - 1 AS return_20d,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 59
+  ) / NULLIF(t.cost, 0) - 1 AS return_60d
+FROM transactions t;
+
+CREATE TABLE insider_scores (
+  kind TEXT NOT NULL,
+  name TEXT NOT NULL,
+  horizon INTEGER NOT NULL,
+  transactions INTEGER NOT NULL,
+  hit_rate REAL NOT NULL,
+  avg_excess_return REAL NOT NULL,
+  updated_at TEXT NOT NULL,
+  PRIMARY KEY (kind, name, horizon)
+);
+
+CREATE TABLE alert_rules (
+  name TEXT PRIMARY KEY,
+  expr TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TABLE alert_firings (
+  rule_name TEXT NOT NULL REFERENCES alert_rules (name) ON DELETE CASCADE,
+  transaction_id TEXT NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+  fired_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  PRIMARY KEY (rule_name, transaction_id)
+);
+
+CREATE TABLE runs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  started_at TEXT NOT NULL,
+  finished_at TEXT,
+  day TEXT NOT NULL,
+  source_urls TEXT NOT NULL DEFAULT '[]',
+  rows_scraped INTEGER NOT NULL DEFAULT 0,
+  rows_kept INTEGER NOT NULL DEFAULT 0,
+  rows_inserted INTEGER NOT NULL DEFAULT 0,
+  rows_duplicate INTEGER NOT NULL DEFAULT 0,
+  status TEXT NOT NULL,
+  error TEXT NOT NULL DEFAULT '',
+  publish TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX runs_day_status_idx ON runs (day, status);
+
+CREATE TABLE digests (
+  key TEXT PRIMARY KEY,
+  day TEXT NOT NULL,
+  sink TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  delivered_at TEXT
+);
+
+CREATE INDEX digests_pending_idx ON digests (day) WHERE delivered_at IS NULL;
+
+CREATE TABLE outbox (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_type TEXT NOT NULL,
+  event_key TEXT NOT NULL,
+  notification_date TEXT NOT NULL,
+  payload TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  published_at TEXT
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (notification_date, id) WHERE published_at IS NULL;
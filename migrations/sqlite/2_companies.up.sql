@@ -0,0 +1,12 @@
+-- companies is the cache of quote pages of traded tickers
+CREATE TABLE companies (
+  ticker TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  sector TEXT NOT NULL,
+  industry TEXT NOT NULL,
+  country TEXT NOT NULL,
+  market_cap REAL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE INDEX companies_sector_idx ON companies (sector);
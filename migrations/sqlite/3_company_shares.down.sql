@@ -0,0 +1,2 @@
+ALTER TABLE companies DROP COLUMN shares_float;
+ALTER TABLE companies DROP COLUMN shares_outstanding;
@@ -0,0 +1,2 @@
+ALTER TABLE companies ADD COLUMN shares_outstanding REAL;
+ALTER TABLE companies ADD COLUMN shares_float REAL;
@@ -0,0 +1,22 @@
+-- canonical tickers have the share class after the dot: BRK.B
+UPDATE transactions SET ticker = upper(replace(replace(ticker, '-', '.'), '/', '.'))
+WHERE ticker <> upper(replace(replace(ticker, '-', '.'), '/', '.'));
+
+UPDATE OR IGNORE daily_closes SET ticker = upper(replace(replace(ticker, '-', '.'), '/', '.'))
+WHERE ticker <> upper(replace(replace(ticker, '-', '.'), '/', '.'));
+DELETE FROM daily_closes WHERE ticker <> upper(replace(replace(ticker, '-', '.'), '/', '.'));
+
+UPDATE OR IGNORE companies SET ticker = upper(replace(replace(ticker, '-', '.'), '/', '.'))
+WHERE ticker <> upper(replace(replace(ticker, '-', '.'), '/', '.'));
+DELETE FROM companies WHERE ticker <> upper(replace(replace(ticker, '-', '.'), '/', '.'));
+
+-- symbols maps symbols of data sources to canonical tickers
+CREATE TABLE symbols (
+  source TEXT NOT NULL,
+  symbol TEXT NOT NULL,
+  ticker TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  PRIMARY KEY (source, symbol)
+);
+
+CREATE INDEX symbols_ticker_idx ON symbols (ticker);
@@ -0,0 +1,4 @@
+DROP INDEX transactions_owner_cik_idx;
+DROP INDEX transactions_issuer_cik_idx;
+ALTER TABLE transactions DROP COLUMN owner_cik;
+ALTER TABLE transactions DROP COLUMN issuer_cik;
@@ -0,0 +1,7 @@
+-- CIKs of the issuer and the reporting owner from the Form 4,
+-- without leading zeros
+ALTER TABLE transactions ADD COLUMN issuer_cik TEXT;
+ALTER TABLE transactions ADD COLUMN owner_cik TEXT;
+
+CREATE INDEX transactions_issuer_cik_idx ON transactions (issuer_cik);
+CREATE INDEX transactions_owner_cik_idx ON transactions (owner_cik);
@@ -0,0 +1,2 @@
+-- the health of every source of transactions fetched by the run
+ALTER TABLE runs ADD COLUMN sources TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP TABLE digest_parts;
+
+ALTER TABLE digests DROP COLUMN locked_until;
@@ -0,0 +1,11 @@
+-- the publisher leases the digest instead of holding the write lock
+-- while messages are sent
+ALTER TABLE digests ADD COLUMN locked_until TEXT;
+
+-- sent messages of the digest, retries skip them
+CREATE TABLE digest_parts (
+  key TEXT NOT NULL REFERENCES digests (key) ON DELETE CASCADE,
+  part TEXT NOT NULL,
+  sent_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  PRIMARY KEY (key, part)
+);
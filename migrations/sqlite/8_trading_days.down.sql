@@ -0,0 +1,38 @@
+DROP VIEW transaction_returns;
+
+-- forward returns from the insider's price to the close
+-- after 1, 5, 20 and 60 trading days
+CREATE VIEW transaction_returns AS
+SELECT
+  t.id,
+  t.ticker,
+  t.owner,
+  t.relationship,
+  t.transaction_type,
+  t.transaction_date,
+  t.notification_date,
+  t.cost,
+  t.planned,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 0
+  ) / NULLIF(t.cost, 0) - 1 AS return_1d,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 4
+  ) / NULLIF(t.cost, 0) - 1 AS return_5d,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 19
+  ) / NULLIF(t.cost, 0) - 1 AS return_20d,
+  (
+    SELECT close FROM daily_closes
+    WHERE ticker = t.ticker AND date > date(t.transaction_date)
+    ORDER BY date LIMIT 1 OFFSET 59
+  ) / NULLIF(t.cost, 0) - 1 AS return_60d
+FROM transactions t;
+
+DROP INDEX daily_closes_date_idx;
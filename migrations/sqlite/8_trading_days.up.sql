@@ -0,0 +1,52 @@
+CREATE INDEX daily_closes_date_idx ON daily_closes (date);
+
+DROP VIEW transaction_returns;
+
+-- forward returns from the insider's price to the close
+-- after 1, 5, 20 and 60 trading days. Dates with any stored close
+-- are the trading calendar (the benchmark is stored every session),
+-- the return is NULL if the close of the day isn't stored.
+CREATE VIEW transaction_returns AS
+SELECT
+  t.id,
+  t.ticker,
+  t.owner,
+  t.relationship,
+  t.transaction_type,
+  t.transaction_date,
+  t.notification_date,
+  t.cost,
+  t.planned,
+  (
+    SELECT c.close FROM daily_closes c
+    WHERE c.ticker = t.ticker AND c.date = (
+      SELECT DISTINCT date FROM daily_closes
+      WHERE date > date(t.transaction_date)
+      ORDER BY date LIMIT 1 OFFSET 0
+    )
+  ) / NULLIF(t.cost, 0) - 1 AS return_1d,
+  (
+    SELECT c.close FROM daily_closes c
+    WHERE c.ticker = t.ticker AND c.date = (
+      SELECT DISTINCT date FROM daily_closes
+      WHERE date > date(t.transaction_date)
+      ORDER BY date LIMIT 1 OFFSET 4
+    )
+  ) / NULLIF(t.cost, 0) - 1 AS return_5d,
+  (
+    SELECT c.close FROM daily_closes c
+    WHERE c.ticker = t.ticker AND c.date = (
+      SELECT DISTINCT date FROM daily_closes
+      WHERE date > date(t.transaction_date)
+      ORDER BY date LIMIT 1 OFFSET 19
+    )
+  ) / NULLIF(t.cost, 0) - 1 AS return_20d,
+  (
+    SELECT c.close FROM daily_closes c
+    WHERE c.ticker = t.ticker AND c.date = (
+      SELECT DISTINCT date FROM daily_closes
+      WHERE date > date(t.transaction_date)
+      ORDER BY date LIMIT 1 OFFSET 59
+    )
+  ) / NULLIF(t.cost, 0) - 1 AS return_60d
+FROM transactions t;
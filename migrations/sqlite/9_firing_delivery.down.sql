@@ -0,0 +1,3 @@
+DROP INDEX alert_firings_pending_idx;
+DELETE FROM alert_firings WHERE delivered_at IS NULL;
+ALTER TABLE alert_firings DROP COLUMN delivered_at;